@@ -7,7 +7,7 @@ import (
 	"log"
 	"net/http"
 
-	"roleplay/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
 )
 
 func main() {
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/redfoxius/roleplay/services/game-server/internal/config"
@@ -29,6 +33,14 @@ func main() {
 	// Initialize game server
 	server := game.NewGameServer(repo)
 
+	// Start the world tick loop
+	ticker := game.NewTickScheduler(cfg.TickRate, server.Tick)
+	ticker.Start()
+
+	// Start periodic autosave
+	autosaver := game.NewAutoSaver(server, cfg.SaveInterval)
+	autosaver.Start()
+
 	// Initialize game handler
 	handler := game.NewHandler(server)
 
@@ -40,8 +52,44 @@ func main() {
 
 	handler.RegisterRoutes(r)
 
-	log.Printf("Game server starting on port %s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, r); err != nil {
-		log.Fatal(err)
+	httpServer := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+	}
+
+	go func() {
+		log.Printf("Game server starting on port %s", cfg.Port)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for a termination signal
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	log.Printf("Shutting down game server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Stop accepting requests and wait for in-flight ones
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server shutdown: %v", err)
+	}
+
+	ticker.Stop()
+	autosaver.Stop()
+
+	// Drain combat actions and flush state
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Game server shutdown: %v", err)
 	}
+
+	if err := redis.Close(); err != nil {
+		log.Printf("Failed to close Redis: %v", err)
+	}
+
+	log.Printf("Game server stopped")
 }
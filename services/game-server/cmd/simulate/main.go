@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/simulation"
+)
+
+func main() {
+	defaults := simulation.DefaultOptions()
+	builds := flag.String("builds", "Engineer:5,Alchemist:5,Aeronaut:5,ClockworkKnight:5,SteamMage:5", "comma separated builds to test, e.g. Engineer:5+steam_pistol")
+	opponents := flag.String("opponents", "mob:mechanical:5,mob:biological:5,mob:hybrid:5,mob:elemental:5,mob:construct:5", "comma separated builds to fight, e.g. mob:mechanical:5 or SteamMage:5")
+	battles := flag.Int("battles", defaults.Battles, "battles per matchup")
+	seed := flag.Int64("seed", defaults.Seed, "seed of the first battle")
+	terrain := flag.String("terrain", defaults.Terrain, "terrain the battle grid is laid out for")
+	rounds := flag.Int("rounds", defaults.MaxRounds, "rounds after which a battle is a draw")
+	equipment := flag.String("equipment", "", "JSON file with extra equipment builds can wear")
+	format := flag.String("format", "table", "output format: table or csv")
+	flag.Parse()
+
+	if *format != "table" && *format != "csv" {
+		log.Fatalf("Unknown format %q", *format)
+	}
+
+	opts := simulation.Options{
+		Battles:   *battles,
+		Seed:      *seed,
+		Terrain:   *terrain,
+		MaxRounds: *rounds,
+	}
+	if *equipment != "" {
+		file, err := os.Open(*equipment)
+		if err != nil {
+			log.Fatalf("Failed to open equipment: %v", err)
+		}
+		opts.Equipment, err = simulation.LoadEquipment(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	tested, err := simulation.ParseBuilds(*builds)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fought, err := simulation.ParseBuilds(*opponents)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(tested) == 0 || len(fought) == 0 {
+		log.Fatal("Need at least one build and one opponent")
+	}
+
+	results, err := simulation.Run(tested, fought, opts)
+	if err != nil {
+		log.Fatalf("Simulation failed: %v", err)
+	}
+
+	if *format == "csv" {
+		err = simulation.WriteCSV(os.Stdout, results)
+	} else {
+		err = simulation.WriteTable(os.Stdout, results)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write results: %v\n", err)
+		os.Exit(1)
+	}
+}
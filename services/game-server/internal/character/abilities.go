@@ -1,5 +1,7 @@
 package character
 
+import "github.com/redfoxius/roleplay/services/game-server/internal/common"
+
 // Ability represents a mob's special ability
 type Ability struct {
 	Name        string
@@ -7,10 +9,16 @@ type Ability struct {
 	Damage      int
 	Healing     int
 	SteamCost   int
+	Range       int // tiles the ability reaches
+	Area        int // radius around the target it also hits
 	Cooldown    int
 	CurrentCD   int
 	Type        string // "damage", "healing", "buff", "debuff"
 	Effect      string // Additional effect description
+	Reaction    string // condition the ability triggers on out of turn, e.g. "counter"
+	Channel     int    // turns the ability is channelled before it resolves
+	ComboAfter  string // ability an ally used before that this one chains from
+	ComboBonus  int    // percent bonus per link of a combo chain
 }
 
 // GetMobAbilities returns abilities based on mob type and level
@@ -181,20 +189,28 @@ func GetCharacterAbilities(class Class, level int) []Ability {
 		abilities = append(abilities, Ability{
 			Name:        "Steam Blast",
 			Description: "A powerful blast of steam",
-			Type:        "damage",
+			Type:        "mechanical",
 			Damage:      15 + level,
-			SteamCost:   20,
+			SteamCost:   15,
+			Range:       3,
 			Cooldown:    2,
+		}, Ability{
+			Name:        "Repair",
+			Description: "Repair mechanical damage",
+			Type:        "healing",
+			Healing:     15,
+			SteamCost:   10,
+			Range:       2,
+			Cooldown:    3,
 		})
 		if level >= 5 {
 			abilities = append(abilities, Ability{
 				Name:        "Repair Bot",
 				Description: "Deploy a repair bot that heals over time",
-				Type:        "healing",
-				Healing:     10 + level*2,
+				Type:        "summon",
 				SteamCost:   30,
 				Cooldown:    4,
-				Effect:      "heal_over_time",
+				Effect:      "repair_bot",
 			})
 		}
 		if level >= 10 {
@@ -207,36 +223,48 @@ func GetCharacterAbilities(class Class, level int) []Ability {
 				Effect:      "damage_reduction",
 			})
 		}
+		if level >= 15 {
+			abilities = append(abilities, Ability{
+				Name:        "Steam Turret",
+				Description: "Deploy a turret that shoots the nearest enemy",
+				Type:        "summon",
+				SteamCost:   45,
+				Cooldown:    6,
+				Effect:      "steam_turret",
+			})
+		}
 
 	case Alchemist:
 		abilities = append(abilities, Ability{
-			Name:        "Acid Splash",
-			Description: "Throw a vial of corrosive acid",
-			Type:        "damage",
-			Damage:      12 + level,
+			Name:        "Toxic Cloud",
+			Description: "Release a cloud of toxic gas",
+			Type:        "chemical",
+			Damage:      5 + level,
+			SteamCost:   12,
+			Range:       2,
+			Area:        2,
+			Cooldown:    3,
+			Effect:      "poison",
+		}, Ability{
+			Name:        "Healing Vapor",
+			Description: "Release healing steam",
+			Type:        "healing",
+			Healing:     15 + level*2,
 			SteamCost:   15,
-			Cooldown:    2,
-			Effect:      "armor_reduction",
+			Range:       2,
+			Cooldown:    4,
+			Effect:      "heal_over_time",
 		})
 		if level >= 5 {
 			abilities = append(abilities, Ability{
-				Name:        "Healing Vapor",
-				Description: "Release healing steam vapors",
-				Type:        "healing",
-				Healing:     15 + level*2,
-				SteamCost:   25,
-				Cooldown:    3,
-			})
-		}
-		if level >= 10 {
-			abilities = append(abilities, Ability{
-				Name:        "Toxic Cloud",
-				Description: "Create a cloud of toxic steam",
-				Type:        "debuff",
-				Damage:      5 + level,
-				SteamCost:   35,
-				Cooldown:    5,
-				Effect:      "poison",
+				Name:        "Acid Splash",
+				Description: "Throw a vial of corrosive acid",
+				Type:        "chemical",
+				Damage:      12 + level,
+				SteamCost:   15,
+				Range:       3,
+				Cooldown:    2,
+				Effect:      "armor_reduction",
 			})
 		}
 
@@ -247,6 +275,7 @@ func GetCharacterAbilities(class Class, level int) []Ability {
 			Type:        "damage",
 			Damage:      18 + level,
 			SteamCost:   25,
+			Range:       3,
 			Cooldown:    3,
 		})
 		if level >= 5 {
@@ -266,10 +295,23 @@ func GetCharacterAbilities(class Class, level int) []Ability {
 				Type:        "damage",
 				Damage:      25 + level*2,
 				SteamCost:   45,
+				Range:       3,
 				Cooldown:    6,
 				Effect:      "knockback",
 			})
 		}
+		if level >= 15 {
+			abilities = append(abilities, Ability{
+				Name:        "Gear Jammer",
+				Description: "Hurl a gear into an enemy's machinery to stop what it is charging up",
+				Type:        "damage",
+				Damage:      5 + level,
+				SteamCost:   20,
+				Range:       5,
+				Cooldown:    4,
+				Reaction:    "interrupt",
+			})
+		}
 
 	case ClockworkKnight:
 		abilities = append(abilities, Ability{
@@ -278,7 +320,10 @@ func GetCharacterAbilities(class Class, level int) []Ability {
 			Type:        "damage",
 			Damage:      20 + level,
 			SteamCost:   30,
+			Range:       1,
 			Cooldown:    2,
+			ComboAfter:  "Acid Splash",
+			ComboBonus:  25,
 		})
 		if level >= 5 {
 			abilities = append(abilities, Ability{
@@ -297,30 +342,56 @@ func GetCharacterAbilities(class Class, level int) []Ability {
 				Type:        "damage",
 				Damage:      30 + level*2,
 				SteamCost:   50,
+				Range:       3,
 				Cooldown:    6,
 				Effect:      "stun",
 			})
 		}
+		if level >= 15 {
+			abilities = append(abilities, Ability{
+				Name:        "Riposte",
+				Description: "Strike back at an enemy that hit you",
+				Type:        "damage",
+				Damage:      10 + level,
+				SteamCost:   15,
+				Range:       1,
+				Cooldown:    2,
+				Reaction:    "counter",
+			})
+		}
+		if level >= 20 {
+			abilities = append(abilities, Ability{
+				Name:        "Bulwark",
+				Description: "Throw yourself in front of an ally to take the hit",
+				Type:        "buff",
+				SteamCost:   20,
+				Range:       2,
+				Cooldown:    3,
+				Reaction:    "intercept",
+			})
+		}
 
 	case SteamMage:
 		abilities = append(abilities, Ability{
 			Name:        "Steam Bolt",
-			Description: "Launch a bolt of condensed steam",
-			Type:        "damage",
+			Description: "A bolt of condensed steam",
+			Type:        "arcane",
 			Damage:      15 + level,
 			SteamCost:   20,
+			Range:       4,
 			Cooldown:    2,
+			ComboAfter:  "Steam Blast",
+			ComboBonus:  20,
+		}, Ability{
+			Name:        "Steam Shield",
+			Description: "Create a protective steam barrier",
+			Type:        "healing",
+			Healing:     10,
+			SteamCost:   15,
+			Range:       1,
+			Cooldown:    3,
+			Effect:      "damage_reduction",
 		})
-		if level >= 5 {
-			abilities = append(abilities, Ability{
-				Name:        "Steam Shield",
-				Description: "Create a protective barrier of steam",
-				Type:        "buff",
-				SteamCost:   30,
-				Cooldown:    4,
-				Effect:      "damage_reduction",
-			})
-		}
 		if level >= 10 {
 			abilities = append(abilities, Ability{
 				Name:        "Steam Nova",
@@ -328,11 +399,54 @@ func GetCharacterAbilities(class Class, level int) []Ability {
 				Type:        "damage",
 				Damage:      20 + level*2,
 				SteamCost:   45,
+				Range:       3,
 				Cooldown:    6,
 				Effect:      "area_damage",
 			})
 		}
+		if level >= 15 {
+			abilities = append(abilities, Ability{
+				Name:        "Steam Vent",
+				Description: "Open a vent that scalds nearby enemies",
+				Type:        "summon",
+				SteamCost:   40,
+				Cooldown:    6,
+				Effect:      "steam_vent",
+			})
+		}
+		if level >= 20 {
+			abilities = append(abilities, Ability{
+				Name:        "Pressure Cannon",
+				Description: "Build up pressure for a turn, then release a devastating blast",
+				Type:        "damage",
+				Damage:      50 + level*3,
+				SteamCost:   60,
+				Range:       4,
+				Cooldown:    8,
+				Channel:     1,
+			})
+		}
 	}
 
 	return abilities
 }
+
+// Common converts the ability to the form used by characters, mobs and battles
+func (a Ability) Common() common.Ability {
+	return common.Ability{
+		Name:        a.Name,
+		Description: a.Description,
+		Type:        a.Type,
+		Damage:      a.Damage,
+		Healing:     a.Healing,
+		SteamCost:   a.SteamCost,
+		Range:       a.Range,
+		Area:        a.Area,
+		Cooldown:    a.Cooldown,
+		Effect:      a.Effect,
+		Reaction:    a.Reaction,
+		Channel:     a.Channel,
+		ComboAfter:  a.ComboAfter,
+		ComboBonus:  a.ComboBonus,
+	}
+}
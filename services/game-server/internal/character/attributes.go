@@ -63,3 +63,9 @@ func getBaseAttributes(class Class) common.Attributes {
 
 	return base
 }
+
+// MechanicalPrecision returns the character's mechanical precision, which
+// grows by one every other level
+func (c *Character) MechanicalPrecision() int {
+	return getBaseAttributes(c.Class).MechanicalPrecision.Value + c.Level/2
+}
@@ -22,6 +22,7 @@ func generateID() string {
 // Character represents a player character
 type Character struct {
 	ID                string
+	Owner             string
 	Name              string
 	Class             Class
 	Level             int
@@ -37,6 +38,8 @@ type Character struct {
 	Equipment         map[string]common.Item
 	Abilities         []common.Ability
 	Stats             common.Stats
+	Money             common.Currency
+	DeletedAt         *time.Time
 }
 
 // Class represents a character class in the game
@@ -116,67 +119,7 @@ func NewCharacter(name string, class Class) *Character {
 		char.Equipment["body"] = convertEquipmentToItem(SteamVest)
 	}
 
-	// Add class-specific abilities
-	switch class {
-	case Engineer:
-		char.Abilities = append(char.Abilities, common.Ability{
-			Name:        "Steam Blast",
-			Description: "A powerful blast of steam",
-			Type:        "mechanical",
-			Damage:      20,
-			SteamCost:   15,
-			Range:       3,
-			Cooldown:    2,
-		})
-		char.Abilities = append(char.Abilities, common.Ability{
-			Name:        "Repair",
-			Description: "Repair mechanical damage",
-			Type:        "healing",
-			Healing:     15,
-			SteamCost:   10,
-			Range:       2,
-			Cooldown:    3,
-		})
-	case Alchemist:
-		char.Abilities = append(char.Abilities, common.Ability{
-			Name:        "Toxic Cloud",
-			Description: "Release a cloud of toxic gas",
-			Type:        "chemical",
-			Damage:      15,
-			SteamCost:   12,
-			Range:       2,
-			Area:        2,
-			Cooldown:    3,
-		})
-		char.Abilities = append(char.Abilities, common.Ability{
-			Name:        "Healing Vapor",
-			Description: "Release healing steam",
-			Type:        "healing",
-			Healing:     20,
-			SteamCost:   15,
-			Range:       2,
-			Cooldown:    4,
-		})
-	case SteamMage:
-		char.Abilities = append(char.Abilities, common.Ability{
-			Name:        "Steam Bolt",
-			Description: "A bolt of condensed steam",
-			Type:        "arcane",
-			Damage:      25,
-			SteamCost:   20,
-			Range:       4,
-			Cooldown:    2,
-		})
-		char.Abilities = append(char.Abilities, common.Ability{
-			Name:        "Steam Shield",
-			Description: "Create a protective steam barrier",
-			Type:        "healing",
-			Healing:     10,
-			SteamCost:   15,
-			Range:       1,
-			Cooldown:    3,
-		})
-	}
+	char.LearnAbilities()
 
 	return char
 }
@@ -258,6 +201,34 @@ func (c *Character) LevelUp() {
 		c.Stats.Intelligence += 2
 		c.Stats.SteamPower += 1
 	}
+
+	c.LearnAbilities()
+}
+
+// LearnAbilities brings the character's abilities up to date with the
+// abilities of its class and level. Abilities the class table does not know
+// are kept. It reports whether anything changed.
+func (c *Character) LearnAbilities() bool {
+	changed := false
+	for _, learned := range GetCharacterAbilities(c.Class, c.Level) {
+		ability := learned.Common()
+		known := false
+		for i := range c.Abilities {
+			if c.Abilities[i].Name != ability.Name {
+				continue
+			}
+			known = true
+			if c.Abilities[i] != ability {
+				c.Abilities[i] = ability
+				changed = true
+			}
+		}
+		if !known {
+			c.Abilities = append(c.Abilities, ability)
+			changed = true
+		}
+	}
+	return changed
 }
 
 // MoveTo moves the character to a new location
@@ -272,19 +243,17 @@ func (c *Character) GetLocation() common.Coordinates {
 
 // AddMoney adds money to the character's purse
 func (c *Character) AddMoney(money common.Currency) {
-	// Implementation needed
+	c.Money.Add(money)
 }
 
 // RemoveMoney removes money from the character's purse
 func (c *Character) RemoveMoney(money common.Currency) bool {
-	// Implementation needed
-	return false
+	return c.Money.Subtract(money)
 }
 
 // GetMoney returns the character's current money
 func (c *Character) GetMoney() common.Currency {
-	// Implementation needed
-	return common.Currency{}
+	return c.Money
 }
 
 // GetAbilities returns the character's abilities
@@ -398,6 +367,9 @@ func convertEquipmentToItem(eq *Equipment) common.Item {
 			Vitality:     eq.Stats["Constitution"],
 			SteamPower:   eq.SteamPower,
 		},
-		Value: eq.Level * 10,
+		Value:       eq.Level * 10,
+		Resistances: eq.Resistances,
+		Armor:       eq.Armor,
+		Penetration: eq.Penetration,
 	}
 }
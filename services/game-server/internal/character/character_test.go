@@ -94,6 +94,39 @@ func TestAddExperience(t *testing.T) {
 	}
 }
 
+func TestLearnAbilities(t *testing.T) {
+	char := NewCharacter("Test Character", Engineer)
+	if len(char.Abilities) != 2 || char.Abilities[0].Name != "Steam Blast" || char.Abilities[0].Range != 3 {
+		t.Errorf("Expected the level 1 engineer abilities, got %+v", char.Abilities)
+	}
+
+	// Abilities unlock as the character levels up
+	for char.Level < 5 {
+		char.LevelUp()
+	}
+	if char.Abilities[len(char.Abilities)-1].Name != "Repair Bot" {
+		t.Errorf("Expected Repair Bot at level 5, got %+v", char.Abilities)
+	}
+
+	// Outdated abilities are replaced and custom ones kept
+	char.Abilities[0].Damage = 1
+	char.Abilities = append(char.Abilities, common.Ability{Name: "Wrench Strike"})
+	if !char.LearnAbilities() {
+		t.Error("Expected the outdated Steam Blast to be replaced")
+	}
+	if char.Abilities[0].Damage != 15+char.Level || char.Abilities[len(char.Abilities)-1].Name != "Wrench Strike" {
+		t.Errorf("Expected Steam Blast restored and Wrench Strike kept, got %+v", char.Abilities)
+	}
+	if char.LearnAbilities() {
+		t.Error("Expected no change for up to date abilities")
+	}
+
+	alchemist := NewCharacter("Test Alchemist", Alchemist)
+	if cloud := alchemist.Abilities[0]; cloud.Name != "Toxic Cloud" || cloud.Effect != "poison" || cloud.Damage != 5+alchemist.Level {
+		t.Errorf("Expected a poisonous Toxic Cloud scaling with level, got %+v", cloud)
+	}
+}
+
 func TestInventoryManagement(t *testing.T) {
 	char := NewCharacter("Test Char", Engineer)
 
@@ -202,3 +235,40 @@ func TestUpdateStats(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"Test Character", true},
+		{"Ada O'Brass", true},
+		{"Cog-Smith", true},
+		{"Al", false},
+		{"A Very Long Character Name", false},
+		{" Padded", false},
+		{"Double  Space", false},
+		{"Gear-", false},
+		{"R2D2", false},
+		{"Admin", false},
+		{"SYSTEM", false},
+		{"The Gamemaster", false},
+		{"Ad-min Bob", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateName(tt.name)
+		if tt.valid && err != nil {
+			t.Errorf("Expected %q to be valid, got %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("Expected %q to be invalid", tt.name)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	if NormalizeName("  Cog-Smith ") != "cog-smith" {
+		t.Errorf("Expected 'cog-smith', got '%s'", NormalizeName("  Cog-Smith "))
+	}
+}
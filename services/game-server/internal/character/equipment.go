@@ -1,6 +1,10 @@
 package character
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
 
 // EquipmentSlot represents a slot where equipment can be worn
 type EquipmentSlot string
@@ -24,6 +28,9 @@ type Equipment struct {
 	Level         int
 	Rarity        string
 	Stats         map[string]int
+	Resistances   map[string]int // damage type -> percent resisted
+	Armor         int
+	Penetration   int
 	SteamPower    int
 	Durability    int
 	MaxDurability int
@@ -101,6 +108,7 @@ var (
 		Stats: map[string]int{
 			"TechnicalAptitude": 2,
 		},
+		Resistances:   map[string]int{"chemical": 10},
 		SteamPower:    5,
 		Durability:    100,
 		MaxDurability: 100,
@@ -117,6 +125,7 @@ var (
 		Stats: map[string]int{
 			"Constitution": 3,
 		},
+		Armor:         5,
 		SteamPower:    10,
 		Durability:    100,
 		MaxDurability: 100,
@@ -134,9 +143,28 @@ var (
 			"MechanicalPrecision": 2,
 			"SteamPower":          5,
 		},
+		Penetration:   5,
 		SteamPower:    15,
 		Durability:    100,
 		MaxDurability: 100,
 		Description:   "A basic steam-powered pistol",
 	}
 )
+
+// equipmentCatalog lists the common equipment by ID
+var equipmentCatalog = map[string]*Equipment{
+	SteamGoggles.ID: SteamGoggles,
+	SteamVest.ID:    SteamVest,
+	SteamPistol.ID:  SteamPistol,
+}
+
+// GetEquipment returns a piece of common equipment by ID
+func GetEquipment(id string) (*Equipment, bool) {
+	eq, exists := equipmentCatalog[id]
+	return eq, exists
+}
+
+// Item converts the equipment to an item that can be carried and equipped
+func (e *Equipment) Item() common.Item {
+	return convertEquipmentToItem(e)
+}
@@ -0,0 +1,88 @@
+package character
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MinNameLength is the shortest allowed character name
+	MinNameLength = 3
+	// MaxNameLength is the longest allowed character name
+	MaxNameLength = 20
+)
+
+// ErrInvalidName is returned for character names that break the naming rules
+var ErrInvalidName = errors.New("invalid character name")
+
+// reservedNames cannot be used as character names
+var reservedNames = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"moderator":     true,
+	"gamemaster":    true,
+	"gm":            true,
+	"system":        true,
+	"server":        true,
+	"support":       true,
+	"staff":         true,
+	"null":          true,
+	"undefined":     true,
+}
+
+// blockedWords may not appear anywhere in a character name
+var blockedWords = []string{
+	"admin",
+	"moderator",
+	"gamemaster",
+	"official",
+}
+
+// NormalizeName returns the case-insensitive form of a name used for uniqueness checks
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidateName checks a character name against the naming rules. Names are
+// 3-20 characters of letters, single spaces, hyphens and apostrophes, must
+// start and end with a letter and may not be reserved or contain blocked words.
+func ValidateName(name string) error {
+	if name != strings.TrimSpace(name) {
+		return fmt.Errorf("%w: leading or trailing spaces", ErrInvalidName)
+	}
+
+	runes := []rune(name)
+	if len(runes) < MinNameLength || len(runes) > MaxNameLength {
+		return fmt.Errorf("%w: must be %d-%d characters", ErrInvalidName, MinNameLength, MaxNameLength)
+	}
+	if !unicode.IsLetter(runes[0]) || !unicode.IsLetter(runes[len(runes)-1]) {
+		return fmt.Errorf("%w: must start and end with a letter", ErrInvalidName)
+	}
+
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r):
+		case r == ' ' || r == '-' || r == '\'':
+			if !unicode.IsLetter(runes[i-1]) {
+				return fmt.Errorf("%w: separators must be between letters", ErrInvalidName)
+			}
+		default:
+			return fmt.Errorf("%w: %q is not allowed", ErrInvalidName, r)
+		}
+	}
+
+	normalized := NormalizeName(name)
+	if reservedNames[normalized] {
+		return fmt.Errorf("%w: %s is reserved", ErrInvalidName, name)
+	}
+	compact := strings.NewReplacer(" ", "", "-", "", "'", "").Replace(normalized)
+	for _, word := range blockedWords {
+		if strings.Contains(compact, word) {
+			return fmt.Errorf("%w: contains a blocked word", ErrInvalidName)
+		}
+	}
+
+	return nil
+}
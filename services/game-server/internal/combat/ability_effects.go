@@ -0,0 +1,114 @@
+package combat
+
+import "github.com/redfoxius/roleplay/services/game-server/internal/common"
+
+const (
+	// knockbackDistance is how far a knockback pushes the target away
+	knockbackDistance = 2
+	// defaultEffectArea is the splash radius of area_damage abilities without an Area
+	defaultEffectArea = 2
+)
+
+// AbilityEffect describes the mechanic behind an ability's Effect identifier.
+// Status is put on the ability's target and Apply runs any extra behaviour,
+// given the damage the ability rolled before the target's mitigation.
+type AbilityEffect struct {
+	Status *Effect
+	Apply  func(b *Battle, ability *common.Ability, attacker, target *Participant, damage int)
+}
+
+// abilityEffects maps ability Effect identifiers to their mechanics
+var abilityEffects = map[string]AbilityEffect{
+	"stun":             {Status: StunEffect},
+	"knockback":        {Apply: knockback},
+	"armor_reduction":  {Status: ArmorBreakEffect},
+	"dodge_chance":     {Status: EvasionEffect},
+	"damage_reduction": {Status: SteamBarrierEffect},
+	"heal_over_time":   {Status: HealingOverTimeEffect},
+	"area_damage":      {Apply: areaDamage},
+	"poison":           {Status: PoisonEffect},
+	"armor_increase":   {Status: FortifiedEffect, Apply: taunt},
+	"taunt":            {Apply: taunt},
+}
+
+// RegisterAbilityEffect adds or replaces the mechanic for an ability Effect identifier
+func RegisterAbilityEffect(id string, effect AbilityEffect) {
+	abilityEffects[id] = effect
+}
+
+// GetAbilityEffect returns the mechanic for an ability Effect identifier
+func GetAbilityEffect(id string) (AbilityEffect, bool) {
+	effect, exists := abilityEffects[id]
+	return effect, exists
+}
+
+// applyAbilityEffect resolves the ability's Effect identifier against the
+// registry. Unknown identifiers are descriptive only and ignored.
+func (b *Battle) applyAbilityEffect(ability *common.Ability, attacker, target *Participant, damage int) {
+	effect, exists := GetAbilityEffect(ability.Effect)
+	if !exists {
+		return
+	}
+	if effect.Status != nil {
+		b.ApplyEffect(target.ID, effect.Status, attacker.ID)
+	}
+	if effect.Apply != nil {
+		effect.Apply(b, ability, attacker, target, damage)
+	}
+}
+
+// absorb lets the target's shields soak up damage and returns the damage left
+func (b *Battle) absorb(target *Participant, damage int) int {
+	effects := b.StatusEffects[target.ID]
+	remaining := effects[:0]
+	for _, effect := range effects {
+		if effect.Type == EffectShield && damage > 0 {
+			absorbed := min(effect.Value, damage)
+			effect.Value -= absorbed
+			damage -= absorbed
+			if effect.Value == 0 {
+				continue
+			}
+		}
+		remaining = append(remaining, effect)
+	}
+	if len(effects) > 0 {
+		b.StatusEffects[target.ID] = remaining
+	}
+	return damage
+}
+
+// knockback pushes the target away from the attacker until it hits an
+// obstacle, another participant or the edge of the grid
+func knockback(b *Battle, ability *common.Ability, attacker, target *Participant, damage int) {
+	dx, dy := sign(target.Position.X-attacker.Position.X), sign(target.Position.Y-attacker.Position.Y)
+	if dx == 0 && dy == 0 {
+		dx = 1
+	}
+	for i := 0; i < knockbackDistance; i++ {
+		next := common.Coordinates{X: target.Position.X + dx, Y: target.Position.Y + dy}
+		if !b.passable(next) {
+			return
+		}
+		target.Position = next
+	}
+}
+
+// areaDamage splashes the damage around the target for abilities without an Area
+// of their own; abilities with an Area already hit everything in it
+func areaDamage(b *Battle, ability *common.Ability, attacker, target *Participant, damage int) {
+	if ability.Area == 0 {
+		b.applyAreaEffect(ability, attacker, target, damage, 0, defaultEffectArea)
+	}
+}
+
+// sign returns -1, 0 or 1 depending on the sign of v
+func sign(v int) int {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
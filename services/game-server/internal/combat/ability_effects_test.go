@@ -0,0 +1,212 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+func TestStunSkipsTurn(t *testing.T) {
+	battle, _, second := newDuel()
+
+	bash := &common.Ability{Name: "Steam-powered Charge", Damage: 10, Effect: "stun"}
+	if _, err := battle.ExecuteAction(bash, second.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The stunned participant loses its turn and the attacker acts again
+	if battle.ActiveParticipant().ID != "first" {
+		t.Errorf("Expected first to act again, got %s", battle.ActiveParticipant().ID)
+	}
+	last := battle.CombatLog[len(battle.CombatLog)-1]
+	if last.Action != "Skip Turn" || last.Character != "Second" {
+		t.Errorf("Expected skipped turn in combat log, got %+v", last)
+	}
+	if len(battle.GetStatusEffects("second")) != 0 {
+		t.Errorf("Expected stun to expire, got %+v", battle.GetStatusEffects("second"))
+	}
+
+	battle.SkipTurn()
+	if battle.ActiveParticipant().ID != "second" {
+		t.Errorf("Expected second to act after the stun, got %s", battle.ActiveParticipant().ID)
+	}
+}
+
+func TestKnockback(t *testing.T) {
+	battle, _, second := newDuel()
+	target := battle.GetParticipant(second.ID)
+	target.Position = common.Coordinates{X: 1, Y: 0}
+
+	cyclone := &common.Ability{Name: "Steam Cyclone", Damage: 10, Effect: "knockback"}
+	if _, err := battle.ExecuteAction(cyclone, second.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := common.Coordinates{X: 1 + knockbackDistance, Y: 0}
+	if target.Position != expected {
+		t.Errorf("Expected position %v, got %v", expected, target.Position)
+	}
+
+	// On a grid the push stops at obstacles
+	battle.SkipTurn()
+	battle.Grid = NewGrid(battleGridWidth, battleGridHeight)
+	battle.Grid.SetTile(common.Coordinates{X: 4, Y: 0}, TileObstacle)
+	battle.ExecuteAction(cyclone, second.ID)
+	if target.Position.X != 3 {
+		t.Errorf("Expected knockback to stop in front of the obstacle, got %v", target.Position)
+	}
+}
+
+func TestArmorReduction(t *testing.T) {
+	battle, _, second := newDuel()
+	battle.GetParticipant("second").Attributes.Constitution.Value = 10
+
+	acid := &common.Ability{Name: "Acid Splash", Damage: 10, Effect: "armor_reduction"}
+	damage, _ := battle.ExecuteAction(acid, second.ID)
+	if damage != 5 { // 10 - 10/2
+		t.Errorf("Expected damage 5, got %d", damage)
+	}
+
+	battle.SkipTurn()
+	damage, _ = battle.ExecuteAction(acid, second.ID)
+	if damage != 8 { // 10 - (10-5)/2
+		t.Errorf("Expected damage 8 through reduced armor, got %d", damage)
+	}
+}
+
+func TestDodge(t *testing.T) {
+	battle, _, second := newDuel()
+	certain := *EvasionEffect
+	certain.Value = 100
+	battle.ApplyEffect("second", &certain, "second")
+
+	strike := &common.Ability{Name: "Strike", Damage: 10, Effect: "poison"}
+	damage, err := battle.ExecuteAction(strike, second.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if damage != 0 || second.Health != 100 {
+		t.Errorf("Expected attack to be dodged, got damage %d and health %d", damage, second.Health)
+	}
+	if len(battle.GetStatusEffects("second")) != 1 {
+		t.Errorf("Expected dodged attack not to poison, got %+v", battle.GetStatusEffects("second"))
+	}
+	last := battle.CombatLog[len(battle.CombatLog)-1]
+	if last.Effects[len(last.Effects)-1] != "dodged" {
+		t.Errorf("Expected dodge in combat log, got %+v", last)
+	}
+}
+
+func TestShieldAbsorb(t *testing.T) {
+	battle, _, second := newDuel()
+
+	// Shields can be put on any participant
+	shield := &common.Ability{Name: "Steam Shield", Effect: "damage_reduction"}
+	battle.ExecuteAction(shield, second.ID)
+	battle.SkipTurn()
+
+	strike := &common.Ability{Name: "Strike", Damage: 12}
+	if damage, _ := battle.ExecuteAction(strike, second.ID); damage != 0 {
+		t.Errorf("Expected shield to absorb all damage, got %d", damage)
+	}
+	if effects := battle.GetStatusEffects("second"); len(effects) != 1 || effects[0].Value != 8 {
+		t.Errorf("Expected 8 shield left, got %+v", effects)
+	}
+
+	battle.SkipTurn()
+	if damage, _ := battle.ExecuteAction(strike, second.ID); damage != 4 {
+		t.Errorf("Expected 4 damage through the shield, got %d", damage)
+	}
+	if len(battle.GetStatusEffects("second")) != 0 {
+		t.Errorf("Expected shield to break, got %+v", battle.GetStatusEffects("second"))
+	}
+	if second.Health != 96 {
+		t.Errorf("Expected health 96, got %d", second.Health)
+	}
+}
+
+func TestStatusAbilityEffects(t *testing.T) {
+	tests := []struct {
+		effect string
+		status string
+	}{
+		{"poison", "Poison"},
+		{"heal_over_time", "Healing"},
+		{"dodge_chance", "Evasion"},
+		{"unknown effect text", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.effect, func(t *testing.T) {
+			battle, _, second := newDuel()
+			ability := &common.Ability{Name: "Test", Type: "buff", Effect: tt.effect}
+			battle.ExecuteAction(ability, second.ID)
+
+			effects := battle.GetStatusEffects("second")
+			if tt.status == "" {
+				if len(effects) != 0 {
+					t.Errorf("Expected no effects, got %+v", effects)
+				}
+				return
+			}
+			if len(effects) != 1 || effects[0].Name != tt.status {
+				t.Errorf("Expected %s effect, got %+v", tt.status, effects)
+			}
+		})
+	}
+}
+
+func TestAreaDamageEffect(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	player := &character.Character{ID: "player", Name: "Player", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	battle.AddPlayer(player)
+	near := &mob.Mob{ID: "near", Name: "Near", Health: 100, MaxHealth: 100, Position: common.Coordinates{X: 1}}
+	far := &mob.Mob{ID: "far", Name: "Far", Health: 100, MaxHealth: 100, Position: common.Coordinates{X: 10}}
+	center := &mob.Mob{ID: "center", Name: "Center", Health: 100, MaxHealth: 100, Position: common.Coordinates{X: 2}}
+	battle.AddMob(near)
+	battle.AddMob(far)
+	battle.AddMob(center)
+
+	nova := &common.Ability{Name: "Steam Nova", Damage: 20, Range: 3, Effect: "area_damage"}
+	if _, err := battle.ExecuteAction(nova, center.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if center.Health != 80 || near.Health != 90 || far.Health != 100 {
+		t.Errorf("Expected health 80/90/100, got %d/%d/%d", center.Health, near.Health, far.Health)
+	}
+}
+
+func TestAreaDamageSplashIgnoresCenterMitigation(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	player := &character.Character{ID: "player", Name: "Player", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	battle.AddPlayer(player)
+	// The construct at the center resists half the physical damage
+	center := &mob.Mob{ID: "center", Name: "Center", Type: string(mob.Construct), Health: 100, MaxHealth: 100, Position: common.Coordinates{X: 2}}
+	near := &mob.Mob{ID: "near", Name: "Near", Health: 100, MaxHealth: 100, Position: common.Coordinates{X: 1}}
+	battle.AddMob(center)
+	battle.AddMob(near)
+
+	nova := &common.Ability{Name: "Steam Nova", Damage: 20, Range: 3, Effect: "area_damage"}
+	if _, err := battle.ExecuteAction(nova, center.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if center.Health != 90 {
+		t.Errorf("Expected the center to take 10 damage, got %d", 100-center.Health)
+	}
+	if near.Health != 90 {
+		t.Errorf("Expected the splash to deal half of the unmitigated 20 damage, got %d", 100-near.Health)
+	}
+}
+
+func TestRegisterAbilityEffect(t *testing.T) {
+	RegisterAbilityEffect("burn", AbilityEffect{Status: BurningEffect})
+	defer delete(abilityEffects, "burn")
+
+	battle, _, second := newDuel()
+	battle.ExecuteAction(&common.Ability{Name: "Flame Jet", Damage: 5, Effect: "burn"}, second.ID)
+	if effects := battle.GetStatusEffects("second"); len(effects) != 1 || effects[0].Name != "Burning" {
+		t.Errorf("Expected registered effect to apply, got %+v", effects)
+	}
+}
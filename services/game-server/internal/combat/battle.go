@@ -2,10 +2,13 @@ package combat
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"time"
 
 	"github.com/redfoxius/roleplay/services/game-server/internal/character"
 	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
 
 	"github.com/google/uuid"
 )
@@ -19,39 +22,104 @@ const (
 	BattleTypeRaid BattleType = "raid"
 )
 
+// Battle states
+const (
+	BattleActive    = "active"
+	BattleCompleted = "completed"
+	BattleCancelled = "cancelled"
+)
+
+// baseSteamRegen is the steam power every participant regains at the start of its turn
+const baseSteamRegen = 5
+
 // Battle represents a battle instance
 type Battle struct {
-	ID            string
-	Type          BattleType
-	State         string // "active", "completed", "cancelled"
-	Participants  []*Participant
-	TurnOrder     []*Participant
-	CurrentTurn   int
-	Round         int
-	CombatLog     []CombatLogEntry
-	Terrain       string
-	Weather       string
-	Teams         map[string][]string       // Team ID -> Participant IDs
-	StatusEffects map[string][]StatusEffect // Participant ID -> Status Effects
-	ActiveIndex   int                       // Index of the active participant in TurnOrder
+	ID              string
+	Type            BattleType
+	State           string // "active", "completed", "cancelled"
+	Participants    []*Participant
+	TurnOrder       []*Participant
+	CurrentTurn     int
+	Round           int
+	CombatLog       []CombatLogEntry
+	Terrain         string
+	Weather         string
+	SteamPowerBonus int                       // terrain bonus to steam regeneration
+	Grid            *Grid                     // tactical map, nil for an open field
+	Teams           map[string][]string       // Team ID -> Participant IDs
+	StatusEffects   map[string][]StatusEffect // Participant ID -> Status Effects
+	Threat          map[string]map[string]int // Mob ID -> participant ID -> threat
+	Boss            *BossEncounter            // raid boss and its script, nil without a boss
+	Stats           map[string]*CombatStats   // Participant ID -> statistics of this battle
+	Channels        map[string]Channel        // Participant ID -> ability being channelled
+	Combos          map[string]ComboChain     // side -> abilities chained this round
+	Bracket         string                    // ranked bracket the battle was matched in, empty if unranked
+	Public          bool                      // open to spectators
+	SpectatorDelay  time.Duration             // how far behind the fight spectators see it
+	StartedAt       time.Time
+	EndedAt         time.Time      // zero while the battle is being fought
+	TurnTimeout     time.Duration  // time a participant has to act on its turn, 0 for no limit
+	TurnStartedAt   time.Time      // when the active participant's turn began
+	Timeouts        map[string]int // Participant ID -> turns timed out in a row
+	Winners         []string       // Participant IDs of the winning side
+	Version         int            // Incremented on every change, for optimistic concurrency
+
+	// The random number state is kept from clients, who could otherwise
+	// predict every roll; storage saves it alongside the battle
+	Seed  int64 `json:"-"` // Seed of the battle's random number generator
+	Rolls int   `json:"-"` // Random numbers drawn so far
+
+	rng       roller
+	record    *BattleRecord          // event stream for replays, started by the first event
+	replaying bool                   // set while a record is replayed, which is not recorded again
+	scripted  bool                   // set during a summon's turn, which the turn before it records
+	joins     map[string]Participant // participants joining during a replay, by ID
 }
 
 // Participant represents a battle participant (player or mob)
 type Participant struct {
 	ID            string
 	Name          string
-	Type          string // "player" or "mob"
+	Type          string // "player", "mob", "summon" or "deployable"
 	Class         character.Class
+	Level         int
 	Team          string // Team ID, empty for free-for-all
+	OwnerID       string // participant that brought a summon or deployable, empty otherwise
+	Lifetime      int    // rounds a summon or deployable has left
+	Script        string // summon script run on a summon's or deployable's turns
 	Health        int
 	MaxHealth     int
 	SteamPower    int
 	MaxSteamPower int
 	Attributes    common.Attributes
 	Abilities     []common.Ability
+	Position      common.Coordinates // position on the battle grid
+	MovementLeft  int                // tiles the participant can still move this turn
+	Immunities    []string           // Effect names or types that cannot be applied
+	Resistances   map[string]int     // Damage type -> percent resisted, negative for weaknesses
+	Armor         int                // reduces all damage, less the attacker's penetration
+	Penetration   int                // ignores this much of the target's armor
+	Cooldowns     map[string]int     // Ability name -> turns of the participant until it can be used again
 	IsActive      bool
-	Experience    int
-	Money         common.Currency
+	Fled          bool             // left the battle alive
+	Experience    int              // experience a mob is worth, or a character won
+	Money         common.Currency  // money a mob drops, or a character won
+	LootTable     common.LootTable // items a mob may drop
+	Loot          []common.Item    // items a character won
+
+	combatant Combatant
+}
+
+// CombatLogEntry represents a single entry in the combat log
+type CombatLogEntry struct {
+	Round     int
+	Turn      int
+	Character string
+	Action    string
+	Target    string
+	Damage    int
+	Healing   int
+	Effects   []string
 }
 
 // NewBattle creates a new battle instance
@@ -59,7 +127,7 @@ func NewBattle(battleType BattleType) *Battle {
 	return &Battle{
 		ID:            uuid.New().String(),
 		Type:          battleType,
-		State:         "active",
+		State:         BattleActive,
 		Participants:  make([]*Participant, 0),
 		TurnOrder:     make([]*Participant, 0),
 		CurrentTurn:   0,
@@ -69,259 +137,472 @@ func NewBattle(battleType BattleType) *Battle {
 		Weather:       "clear",
 		Teams:         make(map[string][]string),
 		StatusEffects: make(map[string][]StatusEffect),
-		ActiveIndex:   0,
+		Seed:          time.Now().UnixNano(),
+		StartedAt:     time.Now(),
+		TurnTimeout:   DefaultTurnTimeout,
+		TurnStartedAt: time.Now(),
 	}
 }
 
 // AddPlayer adds a player to the battle
-func (b *Battle) AddPlayer(player *character.Character) {
-	participant := &Participant{
-		ID:            player.ID,
-		Name:          player.Name,
-		Type:          "player",
-		Class:         player.Class,
-		Health:        player.Health,
-		MaxHealth:     player.MaxHealth,
-		SteamPower:    player.SteamPower,
-		MaxSteamPower: player.MaxSteamPower,
-		Attributes: common.Attributes{
-			Strength:     common.Attribute{Name: "Strength", Value: player.Stats.Strength},
-			Dexterity:    common.Attribute{Name: "Dexterity", Value: player.Stats.Dexterity},
-			Intelligence: common.Attribute{Name: "Intelligence", Value: player.Stats.Intelligence},
-			Constitution: common.Attribute{Name: "Constitution", Value: player.Stats.Vitality},
-			SteamPower:   common.Attribute{Name: "SteamPower", Value: player.Stats.SteamPower},
-		},
-		Abilities:  player.GetAbilities(),
-		IsActive:   true,
-		Experience: 0,
-		Money:      common.NewCurrency(0, 0, 0, 0), // Mobs don't have money until defeated
-	}
-
-	b.Participants = append(b.Participants, participant)
-	b.TurnOrder = append(b.TurnOrder, participant)
-	b.sortTurnOrder()
+func (b *Battle) AddPlayer(player *character.Character) *Participant {
+	return b.AddCombatant(NewCharacterCombatant(player))
 }
 
 // AddMob adds a mob to the battle
-func (b *Battle) AddMob(mob *character.Mob) {
-	participant := &Participant{
-		ID:            mob.ID,
-		Name:          mob.Name,
-		Type:          "mob",
-		Health:        mob.Health,
-		MaxHealth:     mob.MaxHealth,
-		SteamPower:    mob.SteamPower,
-		MaxSteamPower: mob.MaxSteamPower,
-		Attributes: common.Attributes{
-			Strength:     common.Attribute{Value: mob.Attributes.Strength.Value},
-			Dexterity:    common.Attribute{Value: mob.Attributes.Dexterity.Value},
-			Intelligence: common.Attribute{Value: mob.Attributes.Intelligence.Value},
-			Constitution: common.Attribute{Value: mob.Attributes.Constitution.Value},
-			SteamPower:   common.Attribute{Value: mob.Attributes.SteamPower.Value},
-		},
-		Abilities:  convertAbilities(mob.Abilities),
-		IsActive:   true,
-		Experience: mob.Experience,
-		Money:      common.NewCurrency(0, 0, 0, 0), // Mobs don't have money until defeated
-	}
+func (b *Battle) AddMob(m *mob.Mob) *Participant {
+	return b.AddCombatant(NewMobCombatant(m))
+}
+
+// AddCombatant adds any combatant to the battle
+func (b *Battle) AddCombatant(c Combatant) *Participant {
+	participant := c.Participant()
+	participant.combatant = c
+	participant.MovementLeft = b.movement(participant)
 
 	b.Participants = append(b.Participants, participant)
+	b.place(participant)
 	b.TurnOrder = append(b.TurnOrder, participant)
 	b.sortTurnOrder()
+	return participant
+}
+
+// Attach reconnects a combatant to its participant, e.g. after the battle was
+// loaded from storage. It reports false if the combatant is not in the battle.
+func (b *Battle) Attach(c Combatant) bool {
+	p := b.GetParticipant(c.Participant().ID)
+	if p == nil {
+		return false
+	}
+	p.combatant = c
+	return true
 }
 
-// convertAbilities converts character.Ability to common.Ability
-func convertAbilities(abilities []character.Ability) []common.Ability {
-	result := make([]common.Ability, len(abilities))
-	for i, a := range abilities {
-		result[i] = common.Ability{
-			Name:        a.Name,
-			Description: a.Description,
-			Type:        a.Type,
-			Damage:      a.Damage,
-			Healing:     a.Healing,
-			SteamCost:   a.SteamCost,
-			Range:       1, // Default range
-			Area:        0, // Default single target
-			Cooldown:    a.Cooldown,
+// Relink points the turn order back at the battle's participants. Decoding a
+// stored battle creates separate copies for both lists.
+func (b *Battle) Relink() {
+	for i, p := range b.TurnOrder {
+		if participant := b.GetParticipant(p.ID); participant != nil {
+			b.TurnOrder[i] = participant
 		}
 	}
-	return result
+}
+
+// Combatant returns the combatant behind a participant, if it is attached
+func (p *Participant) Combatant() Combatant {
+	return p.combatant
 }
 
 // AddToTeam adds a participant to a team
 func (b *Battle) AddToTeam(participantID, teamID string) {
-	participant := b.getParticipant(participantID)
+	participant := b.GetParticipant(participantID)
 	if participant != nil {
 		participant.Team = teamID
 		b.Teams[teamID] = append(b.Teams[teamID], participantID)
+		// Teammates deploy together
+		b.place(participant)
+	}
+}
+
+// ActiveParticipant returns the participant whose turn it is
+func (b *Battle) ActiveParticipant() *Participant {
+	if len(b.TurnOrder) == 0 {
+		return nil
 	}
+	return b.TurnOrder[b.CurrentTurn]
 }
 
-// ExecuteAction executes a combat action
+// ExecuteAction performs an ability of the active participant on a target. It
+// returns the damage dealt, or the healing done for healing abilities.
 func (b *Battle) ExecuteAction(ability *common.Ability, targetID string) (int, error) {
-	if b.State != "active" {
+	if b.State != BattleActive {
 		return 0, fmt.Errorf("battle is not active")
 	}
 
-	attacker := b.TurnOrder[b.CurrentTurn]
-	if !attacker.IsActive {
+	b.refresh()
+
+	attacker := b.ActiveParticipant()
+	if attacker == nil || !attacker.IsActive {
 		return 0, fmt.Errorf("attacker is not active")
 	}
 
-	target := b.getParticipant(targetID)
+	target := b.GetParticipant(targetID)
 	if target == nil || !target.IsActive {
 		return 0, ErrInvalidTarget
 	}
 
 	// Check if action is valid
-	if !b.isValidAction(ability, attacker, target) {
-		return 0, ErrInvalidAction
+	if err := b.validateAction(ability, attacker, target); err != nil {
+		return 0, err
+	}
+	b.recordEvent(ReplayEvent{Kind: ReplayAction, ActorID: attacker.ID, Ability: ability, TargetID: targetID})
+	delete(b.Timeouts, attacker.ID)
+	b.spend(attacker, ability)
+
+	// Channelled abilities resolve at the start of the attacker's next turn
+	var damage, healing int
+	if ability.Channel > 0 {
+		b.channel(ability, attacker, target)
+	} else {
+		damage, healing = b.perform(ability, attacker, target)
+	}
+
+	b.checkDefeated()
+	b.checkBossPhase()
+	b.checkBattleCompletion()
+
+	// Move to next turn
+	if b.State == BattleActive {
+		b.nextTurn()
+	}
+	b.apply()
+	b.Version++
+
+	if damage > 0 {
+		return damage, nil
+	}
+	return healing, nil
+}
+
+// spend pays the steam power of an ability and starts its cooldown
+func (b *Battle) spend(p *Participant, ability *common.Ability) {
+	p.SteamPower -= ability.SteamCost
+	b.stats(p.ID).RecordAbilityUse(ability.Name)
+	b.stats(p.ID).RecordSteamPowerUsed(ability.SteamCost)
+	if ability.Cooldown > 0 {
+		if p.Cooldowns == nil {
+			p.Cooldowns = make(map[string]int)
+		}
+		// Cooldowns count down as the owner's turns start, so the ability is
+		// ready again on the turn after the Cooldown turns it sits out
+		p.Cooldowns[ability.Name] = ability.Cooldown + 1
+	}
+}
+
+// perform resolves an ability on a target and logs it. It returns the damage
+// dealt and the healing done.
+func (b *Battle) perform(ability *common.Ability, attacker, target *Participant) (int, int) {
+	// An ally of the target may step in to take the hit
+	if ability.Damage > 0 && b.side(target) != b.side(attacker) {
+		target = b.intercept(attacker, target)
 	}
 
-	// Calculate terrain and weather effects
-	terrainBonus := b.calculateTerrainBonus(ability)
-	weatherPenalty := b.calculateWeatherPenalty(ability)
+	// Calculate terrain, weather and combo effects
+	bonus, links := b.combo(ability, attacker)
+	modifier := b.calculateTerrainBonus(ability, attacker) * b.calculateWeatherPenalty(ability, attacker) * bonus
 
 	// Apply action effects
 	var damage, healing int
+	outcome := OutcomeHit
+	var rawDamage int
+	var effectiveness string
 	if ability.Damage > 0 {
-		damage = b.calculateDamage(ability.Damage, attacker, target)
-		damage = int(float64(damage) * terrainBonus * weatherPenalty)
+		outcome, rawDamage = b.resolveAttack(attacker, target, scale(b.calculateDamage(ability.Damage, attacker, target), modifier))
+		damage, effectiveness = b.mitigate(ability, attacker, target, rawDamage)
+		damage = b.absorb(target, damage)
 		target.Health -= damage
+		b.addThreat(target, attacker, damage)
+		b.recordDamage(attacker, target, damage)
+	}
+	evaded := outcome == OutcomeMiss || outcome == OutcomeDodge
+	switch {
+	case evaded:
+		b.stats(attacker.ID).RecordMiss()
+	case outcome == OutcomeCritical:
+		b.stats(attacker.ID).RecordCriticalHit()
 	}
 
 	if ability.Healing > 0 {
-		healing = b.calculateHealing(ability.Healing, attacker)
-		healing = int(float64(healing) * terrainBonus * weatherPenalty)
-		target.Health = min(target.Health+healing, target.MaxHealth)
+		healing = scale(b.calculateHealing(ability.Healing, attacker), modifier)
+		healing = min(healing, target.MaxHealth-target.Health)
+		target.Health += healing
+		b.healingThreat(attacker, healing)
+		b.recordHealing(attacker, target, healing)
 	}
 
 	// Apply area effect if applicable
 	if ability.Area > 0 {
-		b.applyAreaEffect(ability, attacker, target, damage, healing)
+		b.applyAreaEffect(ability, attacker, target, rawDamage, healing, ability.Area)
 	}
 
-	// Apply status effects
-	b.applyStatusEffects(attacker, target, ability)
+	// Cleansing abilities remove harmful effects from the target
+	if ability.Type == "cleanse" {
+		b.Cleanse(target.ID)
+	}
+
+	// Apply the ability's own effect and the class effects, unless the target evaded
+	if !evaded {
+		b.applyAbilityEffect(ability, attacker, target, rawDamage)
+		b.applyStatusEffects(attacker, target, ability)
+	}
 
 	// Log the action
 	b.logAction(ability, attacker, target, damage, healing)
+	entry := &b.CombatLog[len(b.CombatLog)-1]
+	if outcome != OutcomeHit {
+		entry.Effects = append(entry.Effects, outcome)
+	}
+	if effectiveness != "" {
+		entry.Effects = append(entry.Effects, effectiveness)
+	}
+	if links > 1 {
+		entry.Effects = append(entry.Effects, fmt.Sprintf("combo x%d", links))
+	}
 
-	// Check if target is defeated
-	if target.Health <= 0 {
-		target.IsActive = false
-		b.checkBattleCompletion()
+	// The target may strike back
+	if damage > 0 && target.Health > 0 {
+		b.counter(target, attacker)
 	}
+	return damage, healing
+}
 
-	// Move to next turn
+// SkipTurn passes the active participant's turn without acting
+func (b *Battle) SkipTurn() error {
+	if b.State != BattleActive {
+		return fmt.Errorf("battle is not active")
+	}
+	b.recordEvent(ReplayEvent{Kind: ReplaySkip, ActorID: b.ActiveParticipant().ID})
+	b.skipTurn()
+	return nil
+}
+
+// skipTurn ends the active participant's turn
+func (b *Battle) skipTurn() {
+	b.refresh()
 	b.nextTurn()
+	b.apply()
+	b.Version++
+}
+
+// Flee lets the active participant try to escape the battle. From the edge of
+// the grid the escape always succeeds; elsewhere it succeeds with the
+// participant's flee chance and a failed attempt ends its turn. It reports
+// whether the participant escaped. Fled participants leave alive and are not
+// counted as defeated.
+func (b *Battle) Flee() (bool, error) {
+	if b.State != BattleActive {
+		return false, fmt.Errorf("battle is not active")
+	}
+
+	b.refresh()
+	participant := b.ActiveParticipant()
+	if participant == nil || !participant.IsActive {
+		return false, fmt.Errorf("participant is not active")
+	}
+	// Raid bosses fight to the end
+	if b.IsBoss(participant.ID) {
+		return false, ErrCannotFlee
+	}
+	b.recordEvent(ReplayEvent{Kind: ReplayFlee, ActorID: participant.ID})
+	delete(b.Timeouts, participant.ID)
+
+	escaped := b.AtEdge(participant) || b.roll(100) < b.fleeChance(participant)
+	entry := CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: participant.Name,
+		Action:    "Flee",
+		Effects:   make([]string, 0),
+	}
+	if escaped {
+		participant.IsActive = false
+		participant.Fled = true
+	} else {
+		entry.Effects = append(entry.Effects, OutcomeFleeFailed)
+	}
+	b.CombatLog = append(b.CombatLog, entry)
+
+	b.checkBattleCompletion()
+	if b.State == BattleActive {
+		b.nextTurn()
+	}
+	b.apply()
+	b.Version++
+	return escaped, nil
+}
+
+// Forfeit removes a participant from the fight. The battle ends if only one
+// side is left standing.
+func (b *Battle) Forfeit(participantID string) error {
+	if b.State != BattleActive {
+		return fmt.Errorf("battle is not active")
+	}
+
+	participant := b.GetParticipant(participantID)
+	if participant == nil || !participant.IsActive {
+		return ErrInvalidTarget
+	}
+	b.recordEvent(ReplayEvent{Kind: ReplayForfeit, ActorID: participantID})
+	b.forfeit(participant)
+	return nil
+}
+
+// forfeit takes a participant out of the fight
+func (b *Battle) forfeit(participant *Participant) {
+	b.refresh()
+	participant.IsActive = false
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: participant.Name,
+		Action:    "Forfeit",
+		Effects:   make([]string, 0),
+	})
+
+	b.checkBattleCompletion()
+	if b.State == BattleActive && b.ActiveParticipant() == participant {
+		b.nextTurn()
+	}
+	b.apply()
+	b.Version++
+}
+
+// IsOver reports whether the battle has finished
+func (b *Battle) IsOver() bool {
+	return b.State != BattleActive
+}
+
+// Opponents returns the active participants on a different side than the given one
+func (b *Battle) Opponents(participantID string) []*Participant {
+	self := b.GetParticipant(participantID)
+	if self == nil {
+		return nil
+	}
+
+	var opponents []*Participant
+	for _, p := range b.Participants {
+		if p.IsActive && b.side(p) != b.side(self) {
+			opponents = append(opponents, p)
+		}
+	}
+	return opponents
+}
+
+// allies returns the active participants on the side of a participant,
+// the participant itself included
+func (b *Battle) allies(participantID string) []*Participant {
+	self := b.GetParticipant(participantID)
+	if self == nil {
+		return nil
+	}
+
+	var allies []*Participant
+	for _, p := range b.Participants {
+		if p.IsActive && b.side(p) == b.side(self) {
+			allies = append(allies, p)
+		}
+	}
+	return allies
+}
+
+// refresh pulls the current state of every attached combatant into the battle
+func (b *Battle) refresh() {
+	for _, p := range b.Participants {
+		if p.combatant != nil {
+			p.combatant.Refresh(p)
+		}
+		if p.Health <= 0 {
+			p.IsActive = false
+		}
+	}
+}
 
-	return damage, nil
+// apply pushes the battle state of every participant back to its combatant
+func (b *Battle) apply() {
+	for _, p := range b.Participants {
+		if p.combatant != nil {
+			p.combatant.Apply(p)
+		}
+	}
 }
 
-// applyAreaEffect applies an area effect to all valid targets
-func (b *Battle) applyAreaEffect(ability *common.Ability, attacker, centerTarget *Participant, baseDamage, baseHealing int) {
+// applyAreaEffect applies an area effect to all valid targets around the
+// center target. Enemies take half the damage before their own resistances.
+func (b *Battle) applyAreaEffect(ability *common.Ability, attacker, centerTarget *Participant, baseDamage, baseHealing, area int) {
+	covered := make(map[common.Coordinates]bool)
+	for _, tile := range b.AreaTiles(centerTarget.Position, area) {
+		covered[tile] = true
+	}
+
 	for _, target := range b.Participants {
-		if !target.IsActive || target == centerTarget {
+		if !target.IsActive || target == centerTarget || target == attacker {
 			continue
 		}
 
-		// Check if target is in range
-		if !b.isInRange(attacker, target, ability.Area) {
+		// Only participants on the covered tiles are hit
+		if !covered[target.Position] {
 			continue
 		}
 
-		// Apply reduced damage/healing to area targets
-		if ability.Damage > 0 {
-			damage := baseDamage / 2
+		// Apply reduced damage to enemies and reduced healing to the center's allies
+		if ability.Damage > 0 && b.side(target) != b.side(attacker) {
+			damage, _ := b.mitigate(ability, attacker, target, baseDamage/2)
+			damage = b.absorb(target, damage)
 			target.Health -= damage
+			b.addThreat(target, attacker, damage)
+			b.recordDamage(attacker, target, damage)
 		}
 
-		if ability.Healing > 0 {
-			healing := baseHealing / 2
-			target.Health = min(target.Health+healing, target.MaxHealth)
+		if ability.Healing > 0 && b.side(target) == b.side(centerTarget) {
+			healing := min(baseHealing/2, target.MaxHealth-target.Health)
+			target.Health += healing
+			b.healingThreat(attacker, healing)
+			b.recordHealing(attacker, target, healing)
 		}
 	}
 }
 
 // isInRange checks if two participants are within range of each other
-func (b *Battle) isInRange(attacker, target *Participant, rangeValue int) bool {
-	// For now, we'll consider all participants in range
-	// In a real implementation, this would check actual coordinates
-	return true
+func (b *Battle) isInRange(from, to *Participant, rangeValue int) bool {
+	return distanceBetween(from.Position, to.Position) <= rangeValue
 }
 
-// checkBattleCompletion checks if the battle is complete
-func (b *Battle) checkBattleCompletion() {
-	activeTeams := make(map[string]bool)
-	activeParticipants := 0
-
-	for _, p := range b.Participants {
-		if p.IsActive {
-			activeParticipants++
-			if p.Team != "" {
-				activeTeams[p.Team] = true
-			}
-		}
+// side returns the allegiance of a participant. In raids all players fight
+// the boss together, whatever their team. Otherwise participants without a
+// team fight for themselves in PvP and for their kind (players or mobs).
+// Summons and deployables fight for their owner.
+func (b *Battle) side(p *Participant) string {
+	p = b.owner(p)
+	if b.Type == BattleTypeRaid {
+		return p.Type
 	}
-
-	// Battle is complete if:
-	// 1. Only one participant remains (free-for-all)
-	// 2. Only one team remains (team battle)
-	// 3. No participants remain (draw)
-	if activeParticipants <= 1 || len(activeTeams) <= 1 {
-		b.State = "completed"
-		b.distributeRewards()
+	if p.Team != "" {
+		return p.Team
+	}
+	if b.Type == BattleTypePvP {
+		return p.ID
 	}
+	return p.Type
 }
 
-// distributeRewards distributes rewards to the winning team/participant
-func (b *Battle) distributeRewards() {
-	var winners []*Participant
-	var winningTeam string
-
-	// Find winners
-	if len(b.Teams) > 0 {
-		// Team battle
-		for teamID, participantIDs := range b.Teams {
-			teamActive := false
-			for _, id := range participantIDs {
-				if p := b.getParticipant(id); p != nil && p.IsActive {
-					teamActive = true
-					break
-				}
-			}
-			if teamActive {
-				winningTeam = teamID
-				break
-			}
+// checkDefeated marks participants without health as inactive
+func (b *Battle) checkDefeated() {
+	for _, p := range b.Participants {
+		if p.IsActive && p.Health <= 0 {
+			p.IsActive = false
 		}
+	}
+}
 
-		// Add all active participants from winning team
-		for _, id := range b.Teams[winningTeam] {
-			if p := b.getParticipant(id); p != nil && p.IsActive {
-				winners = append(winners, p)
-			}
-		}
-	} else {
-		// Free-for-all
-		for _, p := range b.Participants {
-			if p.IsActive {
-				winners = append(winners, p)
-			}
+// checkBattleCompletion completes the battle once at most one side is
+// standing. Summons and deployables leave with their owner.
+func (b *Battle) checkBattleCompletion() {
+	b.dismissSummons()
+	activeSides := make(map[string]bool)
+	for _, p := range b.Participants {
+		if p.IsActive {
+			activeSides[b.side(p)] = true
 		}
 	}
 
-	// Distribute rewards
-	rewardPerWinner := 200 // Base experience reward
-	for _, winner := range winners {
-		winner.Experience += rewardPerWinner
+	if len(activeSides) <= 1 {
+		b.State = BattleCompleted
+		b.distributeRewards()
 	}
 }
 
-// getParticipant returns a participant by ID
-func (b *Battle) getParticipant(id string) *Participant {
+// GetParticipant returns a participant by ID
+func (b *Battle) GetParticipant(id string) *Participant {
 	for _, p := range b.Participants {
 		if p.ID == id {
 			return p
@@ -330,118 +611,131 @@ func (b *Battle) getParticipant(id string) *Participant {
 	return nil
 }
 
-// sortTurnOrder sorts participants by initiative
+// sortTurnOrder sorts participants by initiative (Dexterity + SteamPower,
+// including buffs and debuffs). Ties keep the order in which participants joined.
 func (b *Battle) sortTurnOrder() {
-	sort.Slice(b.TurnOrder, func(i, j int) bool {
-		initiativeI := b.TurnOrder[i].Attributes.Dexterity.Value + b.TurnOrder[i].Attributes.SteamPower.Value
-		initiativeJ := b.TurnOrder[j].Attributes.Dexterity.Value + b.TurnOrder[j].Attributes.SteamPower.Value
-		return initiativeI > initiativeJ
+	sort.SliceStable(b.TurnOrder, func(i, j int) bool {
+		return b.initiative(b.TurnOrder[i]) > b.initiative(b.TurnOrder[j])
 	})
 }
 
-// nextTurn advances to the next turn
+// initiative returns how early a participant acts in a round
+func (b *Battle) initiative(p *Participant) int {
+	return b.attribute(p, StatDexterity) + b.attribute(p, StatSteamPower)
+}
+
+// nextTurn advances to the next active participant and starts its turn. A
+// participant killed by its effects at the start of its turn is skipped.
+// Summons and deployables take their scripted turn right away.
 func (b *Battle) nextTurn() {
-	b.CurrentTurn++
-	if b.CurrentTurn >= len(b.TurnOrder) {
-		b.CurrentTurn = 0
-		b.Round++
-		b.UpdateStatusEffects()
+	for range b.TurnOrder {
+		b.CurrentTurn++
+		if b.CurrentTurn >= len(b.TurnOrder) {
+			b.CurrentTurn = 0
+			b.Round++
+			// Buffs and debuffs may have changed the initiative
+			b.sortTurnOrder()
+			b.changeWeather()
+			b.startRound()
+			if b.State != BattleActive {
+				return
+			}
+		}
+
+		participant := b.TurnOrder[b.CurrentTurn]
+		if !participant.IsActive {
+			continue
+		}
+		stunned := b.startTurn(participant)
+		b.checkBossPhase()
+		if participant.IsActive {
+			b.completeChannel(participant, stunned)
+			if b.State != BattleActive {
+				return
+			}
+		}
+		switch {
+		case !participant.IsActive:
+		case stunned:
+			// Stunned participants lose the turn
+			b.CombatLog = append(b.CombatLog, CombatLogEntry{
+				Round:     b.Round,
+				Turn:      b.CurrentTurn,
+				Character: participant.Name,
+				Action:    "Skip Turn",
+				Effects:   []string{EffectStun},
+			})
+		case participant.OwnerID != "":
+			b.summonTurn(participant)
+		default:
+			b.TurnStartedAt = time.Now()
+			return
+		}
+		if participant.OwnerID != "" {
+			b.wearOff(participant)
+		}
+		b.checkBattleCompletion()
+		if b.State != BattleActive {
+			return
+		}
 	}
 }
 
-// isValidAction checks if an action is valid
-func (b *Battle) isValidAction(ability *common.Ability, attacker, target *Participant) bool {
+// validateAction checks if an action can be performed
+func (b *Battle) validateAction(ability *common.Ability, attacker, target *Participant) error {
 	if !target.IsActive {
-		return false
+		return ErrInvalidTarget
+	}
+
+	if ability.Reaction != "" {
+		return ErrReactionAbility
 	}
 
 	if attacker.SteamPower < ability.SteamCost {
-		return false
+		return ErrInsufficientSteamPower
 	}
 
-	if ability.Range > 0 {
-		// Replace with a default value or remove the check, since location is not tracked
-		// distance := distanceBetween(attacker.Location, target.Location)
-		// if distance > ability.Range {
-		// 	return false
-		// }
+	if attacker.Cooldowns[ability.Name] > 0 {
+		return ErrAbilityOnCooldown
 	}
 
-	return true
+	// A summon is deployed beside its caster, so its target only picks the side
+	if _, deploys := GetSummon(ability.Effect); !deploys && !b.isInRange(attacker, target, ability.Reach()) {
+		return ErrInvalidTarget
+	}
+
+	if !b.hasLineOfSight(attacker.Position, target.Position) {
+		return ErrNoLineOfSight
+	}
+
+	return nil
 }
 
 // calculateDamage calculates damage for an action
 func (b *Battle) calculateDamage(damage int, attacker, target *Participant) int {
 	// Add attribute bonuses
-	if attacker.Type == "player" {
-		damage += attacker.Attributes.Strength.Value / 2
-	} else {
-		damage += attacker.Attributes.Strength.Value / 2
-	}
+	damage += b.attribute(attacker, StatStrength) / 2
 
 	// Apply defense reduction
-	defense := target.Attributes.Constitution.Value / 2
-	damage = max(1, damage-defense)
-
-	return damage
+	defense := b.attribute(target, StatConstitution) / 2
+	return max(1, damage-defense)
 }
 
 // calculateHealing calculates healing for an action
-func (b *Battle) calculateHealing(healing int, attacker *Participant) int {
-	// Add attribute bonuses
-	if attacker.Type == "player" {
-		healing += attacker.Attributes.Wisdom.Value / 2
-	} else {
-		healing += attacker.Attributes.Wisdom.Value / 2
-	}
-
-	return healing
+func (b *Battle) calculateHealing(healing int, healer *Participant) int {
+	return healing + b.attribute(healer, StatIntelligence)/5
 }
 
-// calculateTerrainBonus calculates bonus damage/healing based on terrain
-func (b *Battle) calculateTerrainBonus(ability *common.Ability) float64 {
-	bonus := 1.0
-	switch b.Terrain {
-	case "steam-rich":
-		if ability.SteamCost > 0 {
-			bonus = 1.2
-		}
-	case "mechanical":
-		if b.TurnOrder[b.CurrentTurn].Type == "player" {
-			// Check class through abilities
-			for _, ab := range b.TurnOrder[b.CurrentTurn].Abilities {
-				if ab.Type == "mechanical" {
-					bonus = 1.15
-					break
-				}
-			}
-		}
-	case "toxic":
-		if b.TurnOrder[b.CurrentTurn].Type == "player" {
-			// Check class through abilities
-			for _, ab := range b.TurnOrder[b.CurrentTurn].Abilities {
-				if ab.Type == "chemical" {
-					bonus = 1.15
-					break
-				}
-			}
-		}
-	}
-	return bonus
+// calculateTerrainBonus calculates the damage/healing multiplier of the
+// battle's terrain modifiers
+func (b *Battle) calculateTerrainBonus(ability *common.Ability, attacker *Participant) float64 {
+	return modify(terrainModifiers[b.Terrain], ability, attacker)
 }
 
-// calculateWeatherPenalty calculates penalties based on weather
-func (b *Battle) calculateWeatherPenalty(ability *common.Ability) float64 {
-	penalty := 1.0
-	switch b.Weather {
-	case "steam-fog":
-		if ability.Type == "ranged" {
-			penalty = 0.8
-		}
-	case "acid-rain":
-		penalty = 0.9
-	}
-	return penalty
+// calculateWeatherPenalty calculates the damage/healing multiplier of the
+// battle's weather modifiers
+func (b *Battle) calculateWeatherPenalty(ability *common.Ability, attacker *Participant) float64 {
+	return modify(weatherModifiers[b.Weather], ability, attacker)
 }
 
 // applyStatusEffects applies status effects to participants
@@ -454,39 +748,19 @@ func (b *Battle) applyStatusEffects(attacker, target *Participant, ability *comm
 	switch attacker.Class {
 	case character.Engineer:
 		if ability.Type == "mechanical" {
-			b.addStatusEffect(target.ID, StatusEffect{
-				Name:        "Steam Burn",
-				Duration:    2,
-				Value:       5,
-				Description: "Takes 5 damage per turn",
-			})
+			b.ApplyEffect(target.ID, SteamBurnEffect, attacker.ID)
 		}
 	case character.Alchemist:
 		if ability.Type == "chemical" {
-			b.addStatusEffect(target.ID, StatusEffect{
-				Name:        "Poison",
-				Duration:    3,
-				Value:       3,
-				Description: "Takes 3 damage per turn",
-			})
+			b.ApplyEffect(target.ID, PoisonEffect, attacker.ID)
 		}
 	case character.SteamMage:
 		if ability.Type == "arcane" {
-			b.addStatusEffect(target.ID, StatusEffect{
-				Name:        "Steam Weakness",
-				Duration:    2,
-				Value:       -10,
-				Description: "Steam power reduced by 10",
-			})
+			b.ApplyEffect(target.ID, SteamWeaknessEffect, attacker.ID)
 		}
 	}
 }
 
-// addStatusEffect adds a status effect to a participant
-func (b *Battle) addStatusEffect(participantID string, effect StatusEffect) {
-	b.StatusEffects[participantID] = append(b.StatusEffects[participantID], effect)
-}
-
 // logAction adds an entry to the combat log
 func (b *Battle) logAction(ability *common.Ability, attacker, target *Participant, damage, healing int) {
 	entry := CombatLogEntry{
@@ -508,16 +782,12 @@ func (b *Battle) logAction(ability *common.Ability, attacker, target *Participan
 	b.CombatLog = append(b.CombatLog, entry)
 }
 
-// UpdateStatusEffects updates all status effects
-func (b *Battle) UpdateStatusEffects() {
-	for participantID, effects := range b.StatusEffects {
-		var remainingEffects []StatusEffect
-		for _, effect := range effects {
-			effect.Duration--
-			if effect.Duration > 0 {
-				remainingEffects = append(remainingEffects, effect)
-			}
-		}
-		b.StatusEffects[participantID] = remainingEffects
-	}
+// scale applies a terrain or weather modifier to an amount
+func scale(amount int, modifier float64) int {
+	return int(math.Round(float64(amount) * modifier))
+}
+
+// distanceBetween calculates the distance between two coordinates
+func distanceBetween(a, b common.Coordinates) int {
+	return max(abs(a.X-b.X), abs(a.Y-b.Y))
 }
@@ -2,12 +2,27 @@ package combat
 
 import (
 	"math"
+	"os"
 	"testing"
 
 	"github.com/redfoxius/roleplay/services/game-server/internal/character"
 	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
 )
 
+// steadyRoller always rolls the middle of the range: attacks hit for their
+// exact damage unless a test pins a seed
+type steadyRoller struct{}
+
+func (steadyRoller) Intn(n int) int {
+	return n / 2
+}
+
+func TestMain(m *testing.M) {
+	newRoller = func(int64) roller { return steadyRoller{} }
+	os.Exit(m.Run())
+}
+
 func TestNewBattle(t *testing.T) {
 	battle := NewBattle("PvP")
 
@@ -44,7 +59,7 @@ func TestAddPlayer(t *testing.T) {
 
 func TestAddMob(t *testing.T) {
 	battle := NewBattle("PvE")
-	mob := &character.Mob{
+	m := &mob.Mob{
 		ID:         "mob1",
 		Name:       "Mob 1",
 		Health:     50,
@@ -52,7 +67,7 @@ func TestAddMob(t *testing.T) {
 	}
 
 	// Test adding a mob to the battle
-	battle.AddMob(mob)
+	battle.AddMob(m)
 	if len(battle.Participants) != 1 {
 		t.Errorf("Expected 1 participant, got %d", len(battle.Participants))
 	}
@@ -138,7 +153,7 @@ func TestBattleEnd(t *testing.T) {
 		t.Errorf("Expected battle state 'completed', got %s", battle.State)
 	}
 
-	if !battle.Participants[1].IsActive {
+	if battle.GetParticipant(player2.ID).IsActive {
 		t.Error("Expected player 2 to be inactive")
 	}
 
@@ -201,18 +216,14 @@ func TestStatusEffects(t *testing.T) {
 		t.Errorf("Expected status effect 'Steam Burn', got %s", effects[0].Name)
 	}
 
-	// Update effects
-	battle.UpdateStatusEffects()
-
-	// Check effect duration
-	effects = battle.StatusEffects[target.ID]
-	if len(effects) != 1 {
-		t.Errorf("Expected 1 status effect after update, got %d", len(effects))
-	}
-
+	// The burn ticked once at the start of the target's turn
 	if effects[0].Duration != 1 {
 		t.Errorf("Expected effect duration 1, got %d", effects[0].Duration)
 	}
+	expectedHealth := 100 - int(math.Round(20*1.15)) - 5
+	if target.Health != expectedHealth {
+		t.Errorf("Expected target health %d, got %d", expectedHealth, target.Health)
+	}
 }
 
 func TestTerrainAndWeatherEffects(t *testing.T) {
@@ -246,7 +257,7 @@ func TestTerrainAndWeatherEffects(t *testing.T) {
 	ability := &common.Ability{
 		Name:        "Ranged Attack",
 		Description: "A ranged attack",
-		Type:        "ranged",
+		Type:        "mechanical",
 		Damage:      20,
 		SteamCost:   15,
 		Range:       3,
@@ -312,9 +323,17 @@ func TestInvalidActions(t *testing.T) {
 	ability.SteamCost = 5
 	ability.Range = 1
 	attacker.SteamPower = 50
-	attacker.Position = common.Coordinates{X: 10, Y: 10}
-	target.Position = common.Coordinates{X: 20, Y: 20}
+	battle.GetParticipant(attacker.ID).Position = common.Coordinates{X: 10, Y: 10}
+	battle.GetParticipant(target.ID).Position = common.Coordinates{X: 20, Y: 20}
+
+	_, err = battle.ExecuteAction(ability, target.ID)
+	if err != ErrInvalidTarget {
+		t.Errorf("Expected ErrInvalidTarget, got %v", err)
+	}
 
+	// Test abilities without a range only reach melee
+	ability.Range = 0
+	battle.GetParticipant(target.ID).Position = common.Coordinates{X: 12, Y: 10}
 	_, err = battle.ExecuteAction(ability, target.ID)
 	if err != ErrInvalidTarget {
 		t.Errorf("Expected ErrInvalidTarget, got %v", err)
@@ -526,6 +545,7 @@ func TestBattleRewards(t *testing.T) {
 	player := &character.Character{
 		ID:            "player",
 		Name:          "Player",
+		Level:         5,
 		Health:        100,
 		MaxHealth:     100,
 		SteamPower:    50,
@@ -535,18 +555,20 @@ func TestBattleRewards(t *testing.T) {
 	battle.AddPlayer(player)
 
 	// Create mob
-	m := &character.Mob{
+	m := &mob.Mob{
 		ID:            "mob",
 		Name:          "Test Mob",
-		Type:          string(character.Mechanical),
+		Type:          string(mob.Mechanical),
 		Level:         5,
 		Health:        50,
 		MaxHealth:     50,
 		SteamPower:    30,
 		MaxSteamPower: 30,
-		Attributes: character.Attributes{
-			Dexterity:  character.Attribute{Name: "Dexterity", Value: 10},
-			SteamPower: character.Attribute{Name: "Steam Power", Value: 5},
+		Experience:    500,
+		MoneyDrop:     common.NewCurrency(100, 0, 0, 0),
+		Attributes: common.Attributes{
+			Dexterity:  common.Attribute{Name: "Dexterity", Value: 10},
+			SteamPower: common.Attribute{Name: "Steam Power", Value: 5},
 		},
 	}
 	battle.AddMob(m)
@@ -609,6 +631,7 @@ func TestMultiPlayerBattle(t *testing.T) {
 			MaxSteamPower: 60,
 			Stats: common.Stats{
 				Intelligence: 15,
+				SteamPower:   5,
 			},
 		},
 		{
@@ -621,6 +644,7 @@ func TestMultiPlayerBattle(t *testing.T) {
 			MaxSteamPower: 70,
 			Stats: common.Stats{
 				Intelligence: 15,
+				SteamPower:   10,
 			},
 		},
 	}
@@ -639,13 +663,13 @@ func TestMultiPlayerBattle(t *testing.T) {
 	// DPS should go first (highest steam power)
 	// Healer second
 	// Tank last
-	if battle.Participants[0].ID != "dps" {
+	if battle.TurnOrder[0].ID != "dps" {
 		t.Error("Expected DPS to be first in turn order")
 	}
-	if battle.Participants[1].ID != "healer" {
+	if battle.TurnOrder[1].ID != "healer" {
 		t.Error("Expected Healer to be second in turn order")
 	}
-	if battle.Participants[2].ID != "tank" {
+	if battle.TurnOrder[2].ID != "tank" {
 		t.Error("Expected Tank to be last in turn order")
 	}
 
@@ -696,17 +720,18 @@ func TestMultiPlayerBattle(t *testing.T) {
 	}
 
 	// Verify health changes
-	tank := battle.Participants[2]
-	dps := battle.Participants[0]
+	tank := battle.GetParticipant("tank")
+	dps := battle.GetParticipant("dps")
 
-	// Tank should have taken damage and been healed
-	expectedTankHealth := 150 - 25 + 30
+	// Tank should have taken damage reduced by its constitution and been healed
+	// back up to full health
+	expectedTankHealth := min(150-(25-15/2)+30+15/5, 150)
 	if tank.Health != expectedTankHealth {
 		t.Errorf("Expected tank health %d, got %d", expectedTankHealth, tank.Health)
 	}
 
-	// DPS should have taken damage from tank
-	expectedDPSHealth := 80 - 20
+	// DPS should have taken damage from tank, boosted by its strength
+	expectedDPSHealth := 80 - (20 + 15/2)
 	if dps.Health != expectedDPSHealth {
 		t.Errorf("Expected DPS health %d, got %d", expectedDPSHealth, dps.Health)
 	}
@@ -717,7 +742,7 @@ func TestMultiPlayerBattle(t *testing.T) {
 		Name:        "Steam Explosion",
 		Description: "A powerful area attack",
 		Type:        "arcane",
-		Damage:      200,
+		Damage:      250,
 		SteamCost:   50,
 		Range:       3,
 		Area:        2,
@@ -789,9 +814,15 @@ func TestTeamBattle(t *testing.T) {
 		},
 	}
 
-	// Add all players to battle
-	for _, player := range append(team1, team2...) {
+	// Add all players to battle, with the teams facing each other
+	for _, player := range team1 {
+		battle.AddPlayer(player)
+		battle.AddToTeam(player.ID, "team1")
+	}
+	for _, player := range team2 {
+		player.Position = common.Coordinates{X: 3, Y: 0}
 		battle.AddPlayer(player)
+		battle.AddToTeam(player.ID, "team2")
 	}
 
 	// Test team-based combat
@@ -846,9 +877,9 @@ func TestTeamBattle(t *testing.T) {
 	team2Tank := battle.Participants[2]
 	team2Healer := battle.Participants[3]
 
-	// Team 1 Tank should be at full health (healed)
-	if team1Tank.Health != 150 {
-		t.Errorf("Expected Team 1 Tank health 150, got %d", team1Tank.Health)
+	// Team 1 Tank was healed to full health, then caught in the counter-attack's splash
+	if team1Tank.Health != 135 { // 150 - 30/2
+		t.Errorf("Expected Team 1 Tank health 135, got %d", team1Tank.Health)
 	}
 
 	// Team 1 Healer should have taken damage
@@ -868,9 +899,8 @@ func TestTeamBattle(t *testing.T) {
 		Description: "A finishing move",
 		Type:        "damage",
 		Damage:      200,
-		SteamCost:   50,
+		SteamCost:   20,
 		Range:       3,
-		Area:        2,
 	}
 
 	// Team 1 Tank eliminates Team 2 Tank
@@ -899,3 +929,117 @@ func TestTeamBattle(t *testing.T) {
 		t.Error("Expected Team 1 to receive victory experience")
 	}
 }
+
+func TestForfeit(t *testing.T) {
+	battle := NewBattle(BattleTypePvP)
+	player1 := &character.Character{ID: "player1", Name: "Player 1", Health: 100, MaxHealth: 100}
+	player2 := &character.Character{ID: "player2", Name: "Player 2", Health: 100, MaxHealth: 100}
+	battle.AddPlayer(player1)
+	battle.AddPlayer(player2)
+
+	if err := battle.Forfeit("player1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle.State != BattleCompleted {
+		t.Errorf("Expected battle state 'completed', got '%s'", battle.State)
+	}
+	if len(battle.Winners) != 1 || battle.Winners[0] != "player2" {
+		t.Errorf("Expected player2 to win, got %v", battle.Winners)
+	}
+	if battle.Version != 1 {
+		t.Errorf("Expected version 1, got %d", battle.Version)
+	}
+	if err := battle.Forfeit("player2"); err == nil {
+		t.Error("Expected error when forfeiting a finished battle")
+	}
+}
+
+func TestAbilityCooldowns(t *testing.T) {
+	battle := NewBattle(BattleTypePvP)
+	first := &character.Character{ID: "first", Name: "First", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	second := &character.Character{ID: "second", Name: "Second", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	battle.AddPlayer(first)
+	battle.AddPlayer(second)
+
+	blast := &common.Ability{Name: "Steam Blast", Damage: 10, SteamCost: 15, Cooldown: 2}
+	if _, err := battle.ExecuteAction(blast, second.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The ability sits out its owner's next two turns
+	for turns := 2; turns > 0; turns-- {
+		battle.SkipTurn()
+		if _, err := battle.ExecuteAction(blast, second.ID); err != ErrAbilityOnCooldown {
+			t.Errorf("Expected ErrAbilityOnCooldown, got %v", err)
+		}
+		if cd := battle.GetParticipant(first.ID).Cooldowns[blast.Name]; cd != turns {
+			t.Errorf("Expected the ability to be ready in %d turns, got %d", turns, cd)
+		}
+		battle.SkipTurn()
+	}
+
+	// and is ready again on the third
+	battle.SkipTurn()
+	if _, err := battle.ExecuteAction(blast, second.ID); err != nil {
+		t.Errorf("Expected ability to be ready, got %v", err)
+	}
+}
+
+func TestCooldownOfOneTurn(t *testing.T) {
+	battle, first, second := newDuel()
+	jab := &common.Ability{Name: "Jab", Damage: 5, Cooldown: 1}
+	if _, err := battle.ExecuteAction(jab, second.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	battle.SkipTurn()
+	if battle.ActiveParticipant().ID != first.ID {
+		t.Fatalf("Expected first's turn, got %s", battle.ActiveParticipant().ID)
+	}
+	if _, err := battle.ExecuteAction(jab, second.ID); err != ErrAbilityOnCooldown {
+		t.Errorf("Expected the ability to be unusable on the very next turn, got %v", err)
+	}
+
+	battle.SkipTurn()
+	battle.SkipTurn()
+	if _, err := battle.ExecuteAction(jab, second.ID); err != nil {
+		t.Errorf("Expected the ability to be ready a turn later, got %v", err)
+	}
+}
+
+func TestSteamRegeneration(t *testing.T) {
+	battle := NewBattle(BattleTypePvP)
+	battle.SteamPowerBonus = 20
+	first := &character.Character{
+		ID: "first", Name: "First", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50,
+		Stats: common.Stats{Dexterity: 20},
+	}
+	second := &character.Character{
+		ID: "second", Name: "Second", Health: 100, MaxHealth: 100, SteamPower: 10, MaxSteamPower: 50,
+		Stats: common.Stats{SteamPower: 10},
+	}
+	battle.AddPlayer(first)
+	battle.AddPlayer(second)
+
+	// The steam cost is paid up front
+	strike := &common.Ability{Name: "Strike", Damage: 10, SteamCost: 20}
+	if _, err := battle.ExecuteAction(strike, second.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.SteamPower != 30 {
+		t.Errorf("Expected steam power 30, got %d", first.SteamPower)
+	}
+
+	// The next participant regains base + attribute/5 + terrain/5 at the start of its turn
+	if expected := 10 + baseSteamRegen + 10/5 + 20/5; second.SteamPower != expected {
+		t.Errorf("Expected steam power %d, got %d", expected, second.SteamPower)
+	}
+
+	// Regeneration never exceeds the maximum
+	second.SteamPower = 48
+	battle.SkipTurn()
+	battle.SkipTurn()
+	if second.SteamPower != 50 {
+		t.Errorf("Expected steam power 50, got %d", second.SteamPower)
+	}
+}
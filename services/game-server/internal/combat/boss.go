@@ -0,0 +1,378 @@
+package combat
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// enrageDuration keeps an enraged boss enraged for the rest of the battle
+const enrageDuration = 1000
+
+//go:embed bosses/*.json
+var bossFiles embed.FS
+
+// BossDefinition describes a raid boss and the script of its fight. Bosses are
+// loaded from JSON files with the same field names.
+type BossDefinition struct {
+	ID             string
+	Name           string
+	Type           string // mob family, e.g. "mechanical"
+	Level          int
+	Health         int
+	SteamPower     int
+	Experience     int
+	Money          int // copper dropped when defeated
+	Abilities      []common.Ability
+	Phases         []BossPhase    // ordered by falling HealthPercent
+	EnrageRound    int            // round the boss enrages in, 0 for never
+	EnrageStrength int            // Strength the boss gains when enraged
+	Mechanics      []BossMechanic // scripted mechanics run at the start of rounds
+}
+
+// BossPhase is a stage of a boss fight that starts once the boss's health
+// drops to HealthPercent of its maximum
+type BossPhase struct {
+	Name          string
+	HealthPercent int
+	Abilities     []common.Ability // abilities the boss gains in the phase
+	Ability       *common.Ability  // used on every opponent when the phase starts
+	Adds          []BossAdd        // mobs joining the battle when the phase starts
+}
+
+// BossAdd describes mobs that join a boss fight
+type BossAdd struct {
+	Name  string
+	Type  string
+	Level int
+	Count int
+}
+
+// BossMechanic is a scripted action the boss takes at the start of every
+// Every-th round from its FromPhase on
+type BossMechanic struct {
+	Name      string
+	Script    string // identifier in the boss script registry
+	Every     int
+	FromPhase int
+	Ability   *common.Ability
+	Adds      []BossAdd
+}
+
+// BossScript runs a scripted boss mechanic
+type BossScript func(b *Battle, boss *Participant, mechanic BossMechanic)
+
+// BossEncounter tracks the boss of a raid battle through its fight
+type BossEncounter struct {
+	Definition *BossDefinition
+	MobID      string
+	Phase      int // index into the definition's phases
+	Enraged    bool
+	Adds       int // adds spawned so far
+}
+
+// bossScripts maps boss mechanic scripts to their behaviour
+var bossScripts = map[string]BossScript{
+	"strike_all":        strikeAll,
+	"strike_top_threat": strikeTopThreat,
+	"summon":            summon,
+}
+
+// bosses holds the known boss definitions, starting with the built-in ones
+var bosses = mustLoadBosses()
+
+// RegisterBossScript adds or replaces the behaviour of a boss mechanic script
+func RegisterBossScript(id string, script BossScript) {
+	bossScripts[id] = script
+}
+
+// RegisterBoss adds or replaces a boss definition
+func RegisterBoss(def *BossDefinition) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+	bosses[def.ID] = def
+	return nil
+}
+
+// GetBoss returns the boss definition with the given ID
+func GetBoss(id string) (*BossDefinition, bool) {
+	def, exists := bosses[id]
+	return def, exists
+}
+
+// ListBosses returns all boss definitions ordered by level
+func ListBosses() []*BossDefinition {
+	list := make([]*BossDefinition, 0, len(bosses))
+	for _, def := range bosses {
+		list = append(list, def)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Level != list[j].Level {
+			return list[i].Level < list[j].Level
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// LoadBosses reads the boss definitions from the JSON files in a directory
+func LoadBosses(fsys fs.FS, dir string) (map[string]*BossDefinition, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make(map[string]*BossDefinition)
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var def BossDefinition
+		if err := json.Unmarshal(data, &def); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		if err := def.validate(); err != nil {
+			return nil, fmt.Errorf("invalid boss in %s: %v", file, err)
+		}
+		if _, exists := defs[def.ID]; exists {
+			return nil, fmt.Errorf("duplicate boss %s in %s", def.ID, file)
+		}
+		defs[def.ID] = &def
+	}
+	return defs, nil
+}
+
+// mustLoadBosses loads the built-in boss definitions, which are part of the binary
+func mustLoadBosses() map[string]*BossDefinition {
+	defs, err := LoadBosses(bossFiles, "bosses")
+	if err != nil {
+		panic(err)
+	}
+	return defs
+}
+
+// validate checks that a boss definition can be fought
+func (def *BossDefinition) validate() error {
+	if def.ID == "" || def.Name == "" {
+		return fmt.Errorf("boss needs an ID and a name")
+	}
+	if def.Health <= 0 {
+		return fmt.Errorf("boss %s needs health", def.ID)
+	}
+	for i, phase := range def.Phases {
+		if i > 0 && phase.HealthPercent >= def.Phases[i-1].HealthPercent {
+			return fmt.Errorf("phases of boss %s must be ordered by falling health", def.ID)
+		}
+	}
+	for _, mechanic := range def.Mechanics {
+		if _, exists := bossScripts[mechanic.Script]; !exists {
+			return fmt.Errorf("boss %s uses unknown script %s", def.ID, mechanic.Script)
+		}
+		if mechanic.Every <= 0 {
+			return fmt.Errorf("mechanic %s of boss %s needs a positive interval", mechanic.Name, def.ID)
+		}
+	}
+	return nil
+}
+
+// NewBossMob creates the mob a boss definition is fought as
+func NewBossMob(def *BossDefinition) *mob.Mob {
+	m := mob.NewMob(def.Name, mob.MobType(def.Type), def.Level)
+	m.ID = "boss_" + uuid.New().String()
+	m.Health, m.MaxHealth = def.Health, def.Health
+	m.SteamPower, m.MaxSteamPower = def.SteamPower, def.SteamPower
+	m.Experience = def.Experience
+	m.MoneyDrop = common.NewCurrency(def.Money, 0, 0, 0)
+	m.Abilities = append([]common.Ability(nil), def.Abilities...)
+	if len(def.Phases) > 0 {
+		m.Abilities = append(m.Abilities, def.Phases[0].Abilities...)
+	}
+	return m
+}
+
+// AddBoss adds the mob of a boss to the battle and starts following the
+// boss's script
+func (b *Battle) AddBoss(m *mob.Mob, def *BossDefinition) *Participant {
+	p := b.AddMob(m)
+	b.Boss = &BossEncounter{Definition: def, MobID: m.ID}
+	return p
+}
+
+// IsBoss reports whether a participant is the battle's boss
+func (b *Battle) IsBoss(id string) bool {
+	return b.Boss != nil && b.Boss.MobID == id
+}
+
+// startRound runs the boss's enrage timer and scripted mechanics at the start
+// of a round
+func (b *Battle) startRound() {
+	if b.Boss == nil {
+		return
+	}
+	boss := b.GetParticipant(b.Boss.MobID)
+	if boss == nil || !boss.IsActive {
+		return
+	}
+	def := b.Boss.Definition
+
+	if def.EnrageRound > 0 && b.Round >= def.EnrageRound && !b.Boss.Enraged {
+		b.Boss.Enraged = true
+		enrage := *EnragedEffect
+		if def.EnrageStrength > 0 {
+			enrage.Value = def.EnrageStrength
+		}
+		b.ApplyEffect(boss.ID, &enrage, boss.ID)
+		b.logBoss(boss, "Enrage", "enraged")
+	}
+
+	for _, mechanic := range def.Mechanics {
+		if b.Boss.Phase >= mechanic.FromPhase && (b.Round-1)%mechanic.Every == 0 && b.Round > 1 {
+			bossScripts[mechanic.Script](b, boss, mechanic)
+			b.checkDefeated()
+		}
+	}
+	b.checkBattleCompletion()
+}
+
+// checkBossPhase moves the boss into the phases its health has dropped into
+func (b *Battle) checkBossPhase() {
+	if b.Boss == nil {
+		return
+	}
+	boss := b.GetParticipant(b.Boss.MobID)
+	if boss == nil || !boss.IsActive || boss.MaxHealth <= 0 {
+		return
+	}
+
+	phases := b.Boss.Definition.Phases
+	for b.Boss.Phase+1 < len(phases) && boss.Health*100 <= phases[b.Boss.Phase+1].HealthPercent*boss.MaxHealth {
+		b.Boss.Phase++
+		phase := phases[b.Boss.Phase]
+		b.logBoss(boss, "Phase: "+phase.Name, "phase")
+
+		boss.Abilities = append(boss.Abilities, phase.Abilities...)
+		if phase.Ability != nil {
+			for _, target := range b.Opponents(boss.ID) {
+				b.strike(boss, target, phase.Ability)
+			}
+		}
+		b.spawnAdds(phase.Adds)
+		b.checkDefeated()
+	}
+}
+
+// strike lets a participant use an ability on a target outside of the turns
+// players and mobs take, e.g. a boss's phase ability or a summon's action
+func (b *Battle) strike(attacker, target *Participant, ability *common.Ability) {
+	var rawDamage, damage, healing int
+	outcome := OutcomeHit
+	if ability.Damage > 0 {
+		outcome, rawDamage = b.resolveAttack(attacker, target, b.calculateDamage(ability.Damage, attacker, target))
+		damage, _ = b.mitigate(ability, attacker, target, rawDamage)
+		damage = b.absorb(target, damage)
+		target.Health -= damage
+		b.addThreat(target, attacker, damage)
+		b.recordDamage(attacker, target, damage)
+	}
+	if ability.Healing > 0 {
+		healing = min(b.calculateHealing(ability.Healing, attacker), target.MaxHealth-target.Health)
+		target.Health += healing
+		b.healingThreat(attacker, healing)
+		b.recordHealing(attacker, target, healing)
+	}
+	if outcome != OutcomeMiss && outcome != OutcomeDodge {
+		b.applyAbilityEffect(ability, attacker, target, rawDamage)
+	}
+
+	b.logAction(ability, attacker, target, damage, healing)
+	if outcome != OutcomeHit {
+		entry := &b.CombatLog[len(b.CombatLog)-1]
+		entry.Effects = append(entry.Effects, outcome)
+	}
+}
+
+// spawnAdds brings mobs into the boss fight on the boss's side. The turn
+// stays with the participant that has it.
+func (b *Battle) spawnAdds(adds []BossAdd) {
+	active := b.ActiveParticipant()
+	for _, add := range adds {
+		for i := 0; i < max(1, add.Count); i++ {
+			b.Boss.Adds++
+			id := fmt.Sprintf("%s_add_%d", b.Boss.MobID, b.Boss.Adds)
+			if joined, exists := b.joins[id]; exists {
+				// Replays bring back the add that was rolled in the battle
+				b.AddCombatant(battleCombatant{participant: joined})
+			} else {
+				m := mob.NewMob(add.Name, mob.MobType(add.Type), add.Level)
+				m.ID = id
+				b.recordJoin(b.AddMob(m))
+			}
+
+			entry := CombatLogEntry{
+				Round:     b.Round,
+				Turn:      b.CurrentTurn,
+				Character: b.participantName(b.Boss.MobID),
+				Action:    "Summon",
+				Target:    b.participantName(id),
+				Effects:   []string{"summon"},
+			}
+			b.CombatLog = append(b.CombatLog, entry)
+		}
+	}
+	for i, p := range b.TurnOrder {
+		if p == active {
+			b.CurrentTurn = i
+		}
+	}
+}
+
+// logBoss writes a boss event to the combat log
+func (b *Battle) logBoss(boss *Participant, action, effect string) {
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: boss.Name,
+		Action:    action,
+		Effects:   []string{effect},
+	})
+}
+
+// strikeAll uses the mechanic's ability on every opponent of the boss
+func strikeAll(b *Battle, boss *Participant, mechanic BossMechanic) {
+	if mechanic.Ability == nil {
+		return
+	}
+	for _, target := range b.Opponents(boss.ID) {
+		b.strike(boss, target, mechanic.Ability)
+	}
+}
+
+// strikeTopThreat uses the mechanic's ability on the opponent at the top of
+// the boss's threat table, or the first opponent while nobody has threat
+func strikeTopThreat(b *Battle, boss *Participant, mechanic BossMechanic) {
+	opponents := b.Opponents(boss.ID)
+	if mechanic.Ability == nil || len(opponents) == 0 {
+		return
+	}
+	target := opponents[0]
+	for _, p := range opponents {
+		if b.GetThreat(boss.ID, p.ID) > b.GetThreat(boss.ID, target.ID) {
+			target = p
+		}
+	}
+	b.strike(boss, target, mechanic.Ability)
+}
+
+// summon brings the mechanic's adds into the battle
+func summon(b *Battle, boss *Participant, mechanic BossMechanic) {
+	b.spawnAdds(mechanic.Adds)
+}
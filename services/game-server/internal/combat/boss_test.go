@@ -0,0 +1,150 @@
+package combat
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// newRaid creates a raid of two single-character teams against a boss; the
+// characters act before the boss
+func newRaid(def *BossDefinition) (*Battle, *Participant) {
+	battle := NewBattle(BattleTypeRaid)
+	for i, id := range []string{"first", "second"} {
+		char := &character.Character{ID: id, Name: id, Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+		char.Stats.Dexterity = 30 - i
+		battle.AddPlayer(char)
+		battle.AddToTeam(id, []string{"red", "blue"}[i])
+	}
+	m := NewBossMob(def)
+	m.Attributes = common.Attributes{}
+	return battle, battle.AddBoss(m, def)
+}
+
+func TestLoadBosses(t *testing.T) {
+	def, exists := GetBoss("clockwork_dragon")
+	if !exists {
+		t.Fatal("Expected the Clockwork Dragon to be built in")
+	}
+	if def.Name != "Clockwork Dragon" || len(def.Phases) != 3 || def.EnrageRound == 0 {
+		t.Errorf("Expected a three phase dragon with an enrage timer, got %+v", def)
+	}
+	if len(ListBosses()) < 3 {
+		t.Errorf("Expected at least 3 bosses, got %d", len(ListBosses()))
+	}
+
+	files := fstest.MapFS{
+		"bosses/bad.json": {Data: []byte(`{"ID": "bad", "Name": "Bad", "Health": 10, "Mechanics": [{"Name": "X", "Script": "dance", "Every": 1}]}`)},
+	}
+	if _, err := LoadBosses(files, "bosses"); err == nil {
+		t.Error("Expected an unknown script to be rejected")
+	}
+	files["bosses/bad.json"] = &fstest.MapFile{Data: []byte(`{"ID": "bad", "Name": "Bad", "Health": 10, "Phases": [{"HealthPercent": 50}, {"HealthPercent": 80}]}`)}
+	if _, err := LoadBosses(files, "bosses"); err == nil {
+		t.Error("Expected unordered phases to be rejected")
+	}
+}
+
+func TestRaidTeamsAreAllies(t *testing.T) {
+	battle, boss := newRaid(&BossDefinition{ID: "dummy", Name: "Dummy", Health: 100})
+
+	opponents := battle.Opponents("first")
+	if len(opponents) != 1 || opponents[0] != boss {
+		t.Errorf("Expected the boss to be the only opponent, got %d opponents", len(opponents))
+	}
+	if battle.GetParticipant("first").Position.X != 0 || battle.GetParticipant("second").Position.X != 0 {
+		t.Error("Expected both teams to deploy together")
+	}
+}
+
+func TestBossPhases(t *testing.T) {
+	def := &BossDefinition{
+		ID:     "dummy",
+		Name:   "Dummy",
+		Type:   "mechanical",
+		Level:  1,
+		Health: 100,
+		Phases: []BossPhase{
+			{Name: "Calm", HealthPercent: 100},
+			{
+				Name:          "Angry",
+				HealthPercent: 50,
+				Abilities:     []common.Ability{{Name: "Stomp", Damage: 10}},
+				Ability:       &common.Ability{Name: "Roar", Damage: 5},
+				Adds:          []BossAdd{{Name: "Cog", Type: "mechanical", Level: 1, Count: 2}},
+			},
+		},
+	}
+	battle, boss := newRaid(def)
+	boss.Combatant().(*MobCombatant).Mob.Health = 52
+
+	if _, err := battle.ExecuteAction(&common.Ability{Name: "Strike", Damage: 10}, boss.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if battle.Boss.Phase != 1 {
+		t.Fatalf("Expected phase 1, got %d", battle.Boss.Phase)
+	}
+	if boss.Abilities[len(boss.Abilities)-1].Name != "Stomp" {
+		t.Error("Expected the boss to gain the phase's ability")
+	}
+	roars := 0
+	for _, entry := range battle.CombatLog {
+		if entry.Action == "Roar" {
+			roars++
+		}
+	}
+	if roars != 2 {
+		t.Errorf("Expected the phase ability to hit both characters, got %d hits", roars)
+	}
+	if len(battle.Participants) != 5 || !battle.GetParticipant(boss.ID+"_add_2").IsActive {
+		t.Errorf("Expected 2 adds to join, got %d participants", len(battle.Participants))
+	}
+	if battle.ActiveParticipant().ID != "second" {
+		t.Errorf("Expected the second character's turn, got %s", battle.ActiveParticipant().ID)
+	}
+}
+
+func TestBossEnrageAndMechanics(t *testing.T) {
+	def := &BossDefinition{
+		ID:             "dummy",
+		Name:           "Dummy",
+		Health:         100,
+		EnrageRound:    2,
+		EnrageStrength: 50,
+		Mechanics: []BossMechanic{
+			{Name: "Quake", Script: "strike_all", Every: 1, Ability: &common.Ability{Name: "Quake", Damage: 5}},
+		},
+	}
+	battle, boss := newRaid(def)
+
+	for battle.Round < 2 {
+		battle.SkipTurn()
+	}
+
+	if !battle.Boss.Enraged || battle.attribute(boss, StatStrength) != 50 {
+		t.Errorf("Expected an enraged boss with 50 strength, got %d", battle.attribute(boss, StatStrength))
+	}
+	for _, id := range []string{"first", "second"} {
+		if p := battle.GetParticipant(id); p.Health >= 100 {
+			t.Errorf("Expected the quake to hurt %s, got health %d", id, p.Health)
+		}
+	}
+}
+
+func TestBossMechanicsRespectArmor(t *testing.T) {
+	battle, boss := newRaid(&BossDefinition{ID: "dummy", Name: "Dummy", Health: 100})
+	armored := battle.GetParticipant("second")
+	armored.Armor = armorScale
+	quake := &common.Ability{Name: "Quake", Damage: 40}
+
+	for _, id := range []string{"first", "second"} {
+		battle.strike(boss, battle.GetParticipant(id), quake)
+	}
+
+	if lost, armoredLost := 100-battle.GetParticipant("first").Health, 100-armored.Health; armoredLost >= lost {
+		t.Errorf("Expected armor to soften the quake, got %d damage against %d unarmored", armoredLost, lost)
+	}
+}
@@ -0,0 +1,146 @@
+package combat
+
+import (
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// Combatant is a character or mob that can take part in a battle. The battle
+// works on its own Participant copy and keeps the combatant in sync with it.
+type Combatant interface {
+	// Participant builds a new battle participant for the combatant
+	Participant() *Participant
+	// Refresh copies the combatant's current vitals into its participant
+	Refresh(p *Participant)
+	// Apply writes a participant's vitals back to the combatant
+	Apply(p *Participant)
+	// Reward grants experience, money and items won in battle
+	Reward(experience int, money common.Currency, loot []common.Item)
+}
+
+// CharacterCombatant adapts a player character to the Combatant interface
+type CharacterCombatant struct {
+	Character *character.Character
+}
+
+// NewCharacterCombatant wraps a character for battle
+func NewCharacterCombatant(c *character.Character) *CharacterCombatant {
+	return &CharacterCombatant{Character: c}
+}
+
+// Participant builds a new battle participant for the character
+func (cc *CharacterCombatant) Participant() *Participant {
+	c := cc.Character
+	p := &Participant{
+		ID:    c.ID,
+		Name:  c.Name,
+		Type:  "player",
+		Class: c.Class,
+		Level: c.Level,
+		Attributes: common.Attributes{
+			Strength:            common.Attribute{Name: "Strength", Value: c.Stats.Strength},
+			Dexterity:           common.Attribute{Name: "Dexterity", Value: c.Stats.Dexterity},
+			Intelligence:        common.Attribute{Name: "Intelligence", Value: c.Stats.Intelligence},
+			Constitution:        common.Attribute{Name: "Constitution", Value: c.Stats.Vitality},
+			SteamPower:          common.Attribute{Name: "SteamPower", Value: c.Stats.SteamPower},
+			MechanicalPrecision: common.Attribute{Name: "Mechanical Precision", Value: c.MechanicalPrecision()},
+		},
+		Abilities: c.GetAbilities(),
+		Position:  c.Position,
+		IsActive:  true,
+		Money:     common.NewCurrency(0, 0, 0, 0),
+	}
+	// Equipment provides resistances, armor and penetration
+	for _, item := range c.Equipment {
+		for damageType, resistance := range item.Resistances {
+			if p.Resistances == nil {
+				p.Resistances = make(map[string]int)
+			}
+			p.Resistances[damageType] += resistance
+		}
+		p.Armor += item.Armor
+		p.Penetration += item.Penetration
+	}
+	cc.Refresh(p)
+	return p
+}
+
+// Refresh copies the character's vitals into its participant
+func (cc *CharacterCombatant) Refresh(p *Participant) {
+	c := cc.Character
+	p.Health, p.MaxHealth = c.Health, c.MaxHealth
+	p.SteamPower, p.MaxSteamPower = c.SteamPower, c.MaxSteamPower
+}
+
+// Apply writes the participant's vitals back to the character
+func (cc *CharacterCombatant) Apply(p *Participant) {
+	cc.Character.Health = p.Health
+	cc.Character.SteamPower = p.SteamPower
+}
+
+// Reward grants experience, money and items to the character
+func (cc *CharacterCombatant) Reward(experience int, money common.Currency, loot []common.Item) {
+	cc.Character.AddExperience(experience)
+	cc.Character.AddMoney(money)
+	for _, item := range loot {
+		cc.Character.AddItem(item)
+	}
+}
+
+// mobImmunities lists the effects each mob type shrugs off
+var mobImmunities = map[mob.MobType][]string{
+	mob.Mechanical: {"Poison"},
+	mob.Construct:  {"Poison", "Steam Weakness"},
+	mob.Elemental:  {"Steam Burn", "Burning"},
+}
+
+// MobCombatant adapts a mob to the Combatant interface
+type MobCombatant struct {
+	Mob *mob.Mob
+}
+
+// NewMobCombatant wraps a mob for battle
+func NewMobCombatant(m *mob.Mob) *MobCombatant {
+	return &MobCombatant{Mob: m}
+}
+
+// Participant builds a new battle participant for the mob. The participant
+// carries the mob's experience and money drop as its reward value.
+func (mc *MobCombatant) Participant() *Participant {
+	m := mc.Mob
+	p := &Participant{
+		ID:          m.ID,
+		Name:        m.Name,
+		Type:        "mob",
+		Level:       m.Level,
+		Attributes:  m.Attributes,
+		Abilities:   m.Abilities,
+		Position:    m.Position,
+		Immunities:  mobImmunities[mob.MobType(m.Type)],
+		Resistances: familyResistances[mob.MobType(m.Type)],
+		Armor:       familyArmor[mob.MobType(m.Type)] * m.Level,
+		IsActive:    true,
+		Experience:  m.Experience,
+		Money:       m.MoneyDrop,
+		LootTable:   m.LootTable,
+	}
+	mc.Refresh(p)
+	return p
+}
+
+// Refresh copies the mob's vitals into its participant
+func (mc *MobCombatant) Refresh(p *Participant) {
+	m := mc.Mob
+	p.Health, p.MaxHealth = m.Health, m.MaxHealth
+	p.SteamPower, p.MaxSteamPower = m.SteamPower, m.MaxSteamPower
+}
+
+// Apply writes the participant's vitals back to the mob
+func (mc *MobCombatant) Apply(p *Participant) {
+	mc.Mob.Health = p.Health
+	mc.Mob.SteamPower = p.SteamPower
+}
+
+// Reward does nothing; mobs do not collect rewards
+func (mc *MobCombatant) Reward(experience int, money common.Currency, loot []common.Item) {}
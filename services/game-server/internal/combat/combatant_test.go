@@ -0,0 +1,133 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+func TestCharacterCombatant(t *testing.T) {
+	char := &character.Character{
+		ID:            "player",
+		Name:          "Player",
+		Class:         character.Engineer,
+		Health:        80,
+		MaxHealth:     100,
+		SteamPower:    40,
+		MaxSteamPower: 50,
+		Position:      common.Coordinates{X: 2, Y: 3},
+		Stats: common.Stats{
+			Strength:   12,
+			Vitality:   8,
+			SteamPower: 6,
+		},
+	}
+
+	p := NewCharacterCombatant(char).Participant()
+	if p.Type != "player" {
+		t.Errorf("Expected participant type 'player', got '%s'", p.Type)
+	}
+	if p.Health != 80 || p.MaxHealth != 100 {
+		t.Errorf("Expected health 80/100, got %d/%d", p.Health, p.MaxHealth)
+	}
+	if p.Attributes.Strength.Value != 12 {
+		t.Errorf("Expected strength 12, got %d", p.Attributes.Strength.Value)
+	}
+	if p.Attributes.Constitution.Value != 8 {
+		t.Errorf("Expected constitution 8, got %d", p.Attributes.Constitution.Value)
+	}
+	if p.Position != char.Position {
+		t.Errorf("Expected position %v, got %v", char.Position, p.Position)
+	}
+}
+
+func TestPvEBattleAgainstMob(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+
+	player := &character.Character{
+		ID:            "player",
+		Name:          "Player",
+		Health:        100,
+		MaxHealth:     100,
+		SteamPower:    50,
+		MaxSteamPower: 50,
+		Stats: common.Stats{
+			Strength:  10,
+			Dexterity: 10,
+		},
+	}
+	battle.AddPlayer(player)
+
+	m := &mob.Mob{
+		ID:            "mob",
+		Name:          "Steam Rat",
+		Health:        40,
+		MaxHealth:     40,
+		SteamPower:    20,
+		MaxSteamPower: 20,
+		Experience:    50,
+		MoneyDrop:     common.NewCurrency(10, 0, 0, 0),
+		Attributes: common.Attributes{
+			Strength:     common.Attribute{Name: "Strength", Value: 4},
+			Constitution: common.Attribute{Name: "Constitution", Value: 4},
+		},
+	}
+	battle.AddMob(m)
+
+	bite := &common.Ability{Name: "Bite", Damage: 10, SteamCost: 5}
+	slash := &common.Ability{Name: "Slash", Damage: 20, SteamCost: 10}
+
+	// The player acts first and the damage is written back to the mob
+	damage, err := battle.ExecuteAction(slash, m.ID)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if damage != 23 { // 20 + 10/2 - 4/2
+		t.Errorf("Expected damage 23, got %d", damage)
+	}
+	if m.Health != 17 {
+		t.Errorf("Expected mob health 17, got %d", m.Health)
+	}
+	if player.SteamPower != 40 {
+		t.Errorf("Expected player steam power 40, got %d", player.SteamPower)
+	}
+
+	// The mob answers through the same engine
+	if battle.ActiveParticipant().ID != m.ID {
+		t.Fatalf("Expected mob to be active, got %s", battle.ActiveParticipant().ID)
+	}
+	if _, err := battle.ExecuteAction(bite, player.ID); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if player.Health != 88 { // 100 - (10 + 4/2)
+		t.Errorf("Expected player health 88, got %d", player.Health)
+	}
+
+	// Changes made outside the battle are picked up before the next action
+	m.Health = 1
+	if _, err := battle.ExecuteAction(slash, m.ID); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !battle.IsOver() {
+		t.Fatal("Expected battle to be over")
+	}
+	if len(battle.Winners) != 1 || battle.Winners[0] != player.ID {
+		t.Errorf("Expected player to win, got %v", battle.Winners)
+	}
+	if player.Experience != 50 {
+		t.Errorf("Expected player experience 50, got %d", player.Experience)
+	}
+	if player.GetMoney().Copper != 10 {
+		t.Errorf("Expected 10 copper, got %v", player.GetMoney())
+	}
+}
+
+func TestSplitCurrency(t *testing.T) {
+	share := splitCurrency(common.NewCurrency(0, 1, 1, 0), 2)
+	expected := common.NewCurrency(50, 50, 0, 0)
+	if share != expected {
+		t.Errorf("Expected share %v, got %v", expected, share)
+	}
+}
@@ -0,0 +1,81 @@
+package combat
+
+import (
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// Damage types. An ability deals the damage type its type names, and
+// physical damage otherwise.
+const (
+	DamagePhysical   = "physical"
+	DamageMechanical = "mechanical"
+	DamageChemical   = "chemical"
+	DamageArcane     = "arcane"
+)
+
+// Effectiveness of a hit against the target's resistances, as shown in the
+// combat log
+const (
+	EffectivenessSuper    = "super effective"
+	EffectivenessResisted = "resisted"
+)
+
+// Resistance and armor tuning; resistances and reductions are in percent
+const (
+	maxResistance      = 75   // resistances never stop more of a damage type
+	minResistance      = -100 // weaknesses never more than double the damage
+	effectiveThreshold = 25   // resistance from which hits are logged as resisted or super effective
+	armorScale         = 50   // armor at which half the damage is stopped
+	maxArmorReduction  = 75
+)
+
+// familyResistances is the damage type matrix: the percentage of each damage
+// type a mob family resists. Negative values are weaknesses.
+var familyResistances = map[mob.MobType]map[string]int{
+	mob.Mechanical: {DamagePhysical: 25, DamageChemical: 50, DamageMechanical: -50},
+	mob.Biological: {DamageChemical: -50, DamageArcane: 25},
+	mob.Hybrid:     {DamageChemical: -25, DamageMechanical: -25, DamageArcane: 25},
+	mob.Elemental:  {DamagePhysical: 50, DamageMechanical: 25, DamageArcane: -50},
+	mob.Construct:  {DamagePhysical: 50, DamageChemical: 50, DamageArcane: -50},
+}
+
+// familyArmor is the armor a mob of each family has per level
+var familyArmor = map[mob.MobType]int{
+	mob.Mechanical: 2,
+	mob.Hybrid:     1,
+	mob.Construct:  3,
+}
+
+// damageType returns the type of damage an ability deals
+func damageType(ability *common.Ability) string {
+	switch ability.Type {
+	case DamageMechanical, DamageChemical, DamageArcane:
+		return ability.Type
+	}
+	return DamagePhysical
+}
+
+// mitigate applies the target's resistance to the ability's damage type and
+// its armor, less the attacker's penetration, to damage. It returns the damage
+// taken and how effective the hit was, if notably.
+func (b *Battle) mitigate(ability *common.Ability, attacker, target *Participant, damage int) (int, string) {
+	if damage <= 0 {
+		return damage, ""
+	}
+
+	resistance := clamp(target.Resistances[damageType(ability)], minResistance, maxResistance)
+	damage = scale(damage, float64(100-resistance)/100)
+
+	armor := max(0, target.Armor-attacker.Penetration)
+	reduction := min(armor*100/(armor+armorScale), maxArmorReduction)
+	damage = max(1, scale(damage, float64(100-reduction)/100))
+
+	switch {
+	case resistance <= -effectiveThreshold:
+		return damage, EffectivenessSuper
+	case resistance >= effectiveThreshold:
+		return damage, EffectivenessResisted
+	}
+	return damage, ""
+}
@@ -0,0 +1,89 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+func TestDamageTypes(t *testing.T) {
+	tests := []struct {
+		abilityType   string
+		family        mob.MobType
+		damage        int
+		effectiveness string
+	}{
+		{"mechanical", mob.Mechanical, 150, EffectivenessSuper},
+		{"chemical", mob.Biological, 150, EffectivenessSuper},
+		{"arcane", mob.Elemental, 150, EffectivenessSuper},
+		{"chemical", mob.Mechanical, 50, EffectivenessResisted},
+		{"damage", mob.Construct, 50, EffectivenessResisted},
+		{"damage", mob.Biological, 100, ""},
+		{"arcane", mob.Hybrid, 75, EffectivenessResisted},
+	}
+
+	battle := NewBattle(BattleTypePvE)
+	attacker := &Participant{ID: "attacker"}
+	for _, tt := range tests {
+		target := &Participant{ID: "target", Resistances: familyResistances[tt.family]}
+		ability := &common.Ability{Name: "Test", Type: tt.abilityType, Damage: 100}
+
+		damage, effectiveness := battle.mitigate(ability, attacker, target, 100)
+		if damage != tt.damage || effectiveness != tt.effectiveness {
+			t.Errorf("Expected %s against %s to deal %d (%q), got %d (%q)", tt.abilityType, tt.family, tt.damage, tt.effectiveness, damage, effectiveness)
+		}
+	}
+}
+
+func TestArmorAndPenetration(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	ability := &common.Ability{Name: "Test", Damage: 100}
+	target := &Participant{ID: "target", Armor: armorScale}
+
+	if damage, _ := battle.mitigate(ability, &Participant{ID: "attacker"}, target, 100); damage != 50 {
+		t.Errorf("Expected armor equal to the armor scale to stop half the damage, got %d", damage)
+	}
+	if damage, _ := battle.mitigate(ability, &Participant{ID: "attacker", Penetration: armorScale}, target, 100); damage != 100 {
+		t.Errorf("Expected penetration to ignore the armor, got %d", damage)
+	}
+	target.Armor = 10000
+	if damage, _ := battle.mitigate(ability, &Participant{ID: "attacker"}, target, 100); damage != 100-maxArmorReduction {
+		t.Errorf("Expected armor to stop at most %d%%, got %d damage", maxArmorReduction, damage)
+	}
+}
+
+func TestDefensesFromCombatants(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	char := character.NewCharacter("Tinker", character.Engineer)
+	char.AddToInventory(character.SteamPistol.Item())
+	char.EquipItem(character.SteamPistol.ID)
+	player := battle.AddPlayer(char)
+
+	if player.Armor != character.SteamVest.Armor || player.Penetration != character.SteamPistol.Penetration {
+		t.Errorf("Expected armor %d and penetration %d from equipment, got %d and %d", character.SteamVest.Armor, character.SteamPistol.Penetration, player.Armor, player.Penetration)
+	}
+	if player.Resistances[DamageChemical] != 10 {
+		t.Errorf("Expected 10%% chemical resistance from the goggles, got %d", player.Resistances[DamageChemical])
+	}
+
+	m := battle.AddMob(mob.NewMob("Golem", mob.Construct, 4))
+	if m.Armor != 12 || m.Resistances[DamagePhysical] != 50 {
+		t.Errorf("Expected a level 4 construct to have 12 armor and resist physical damage, got %d and %v", m.Armor, m.Resistances)
+	}
+}
+
+func TestEffectivenessLogged(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	battle.AddPlayer(&character.Character{ID: "player", Name: "Player", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50})
+	battle.AddMob(&mob.Mob{ID: "bot", Name: "Bot", Type: string(mob.Mechanical), Health: 200, MaxHealth: 200})
+
+	if _, err := battle.ExecuteAction(&common.Ability{Name: "Sabotage", Type: "mechanical", Damage: 20}, "bot"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	effects := battle.CombatLog[len(battle.CombatLog)-1].Effects
+	if len(effects) == 0 || effects[len(effects)-1] != EffectivenessSuper {
+		t.Errorf("Expected the hit to be logged as super effective, got %v", effects)
+	}
+}
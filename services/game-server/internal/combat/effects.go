@@ -1,13 +1,72 @@
 package combat
 
+// Effect types
+const (
+	EffectBuff   = "buff"
+	EffectDebuff = "debuff"
+	EffectDoT    = "dot"
+	EffectHoT    = "hot"
+	EffectStun   = "stun"   // the participant loses its turns
+	EffectDodge  = "dodge"  // Value is the percent chance to evade attacks
+	EffectShield = "shield" // Value is the damage left to absorb
+)
+
+// Stats that buffs and debuffs can modify
+const (
+	StatStrength            = "strength"
+	StatDexterity           = "dexterity"
+	StatIntelligence        = "intelligence"
+	StatConstitution        = "constitution"
+	StatSteamPower          = "steam_power"
+	StatMechanicalPrecision = "mechanical_precision"
+	StatSteamRegen          = "steam_regen" // steam power gained at the start of each turn
+)
+
+// StackingRule decides what happens when an effect is applied to a
+// participant that already has it
+type StackingRule string
+
+const (
+	// StackRefresh resets the duration of the existing effect
+	StackRefresh StackingRule = "refresh"
+	// StackAdd adds a stack up to MaxStacks and resets the duration
+	StackAdd StackingRule = "stack"
+	// StackIgnore keeps the existing effect unchanged
+	StackIgnore StackingRule = "ignore"
+)
+
 // Effect represents a status effect in combat
 type Effect struct {
 	Name        string
 	Description string
-	Type        string // buff, debuff, dot, hot
-	Duration    int    // in turns
-	Value       int    // effect value (damage, healing, stat modifier)
-	Remaining   int    // remaining duration
+	Type        string       // buff, debuff, dot, hot, stun, dodge, shield
+	Stat        string       // stat modified by buffs and debuffs
+	Duration    int          // in turns
+	Value       int          // effect value (damage, healing, stat modifier)
+	Remaining   int          // remaining duration
+	Stacking    StackingRule // defaults to StackRefresh
+	MaxStacks   int          // limit for StackAdd, 0 for no limit
+}
+
+// IsHarmful reports whether the effect hurts the participant it is on
+func (e *Effect) IsHarmful() bool {
+	return e.Type == EffectDebuff || e.Type == EffectDoT || e.Type == EffectStun
+}
+
+// StatusEffect creates a battle status effect from the effect, applied by sourceID
+func (e *Effect) StatusEffect(sourceID string) StatusEffect {
+	return StatusEffect{
+		Name:        e.Name,
+		Type:        e.Type,
+		Stat:        e.Stat,
+		Duration:    e.Duration,
+		Value:       e.Value,
+		Stacks:      1,
+		Stacking:    e.Stacking,
+		MaxStacks:   e.MaxStacks,
+		SourceID:    sourceID,
+		Description: e.Description,
+	}
 }
 
 // EffectManager manages active effects on a character or mob
@@ -65,6 +124,8 @@ var (
 		Duration:    3,
 		Value:       5,
 		Remaining:   3,
+		Stacking:    StackAdd,
+		MaxStacks:   3,
 	}
 
 	BurningEffect = &Effect{
@@ -89,6 +150,7 @@ var (
 		Name:        "Strength Buff",
 		Description: "Increased strength",
 		Type:        "buff",
+		Stat:        StatStrength,
 		Duration:    3,
 		Value:       5,
 		Remaining:   3,
@@ -98,6 +160,7 @@ var (
 		Name:        "Weakness",
 		Description: "Reduced strength",
 		Type:        "debuff",
+		Stat:        StatStrength,
 		Duration:    2,
 		Value:       -3,
 		Remaining:   2,
@@ -107,6 +170,7 @@ var (
 		Name:        "Steam Powered",
 		Description: "Increased steam power regeneration",
 		Type:        "buff",
+		Stat:        StatSteamRegen,
 		Duration:    3,
 		Value:       2,
 		Remaining:   3,
@@ -116,8 +180,89 @@ var (
 		Name:        "Steam Exhaustion",
 		Description: "Reduced steam power regeneration",
 		Type:        "debuff",
+		Stat:        StatSteamRegen,
 		Duration:    2,
 		Value:       -2,
 		Remaining:   2,
 	}
+
+	SteamBurnEffect = &Effect{
+		Name:        "Steam Burn",
+		Description: "Takes 5 damage per turn",
+		Type:        "dot",
+		Duration:    2,
+		Value:       5,
+		Remaining:   2,
+	}
+
+	SteamWeaknessEffect = &Effect{
+		Name:        "Steam Weakness",
+		Description: "Steam power reduced by 10",
+		Type:        "debuff",
+		Stat:        StatSteamPower,
+		Duration:    2,
+		Value:       -10,
+		Remaining:   2,
+		Stacking:    StackIgnore,
+	}
+
+	StunEffect = &Effect{
+		Name:        "Stunned",
+		Description: "Loses the next turn",
+		Type:        EffectStun,
+		Duration:    1,
+		Remaining:   1,
+		Stacking:    StackIgnore,
+	}
+
+	ArmorBreakEffect = &Effect{
+		Name:        "Armor Break",
+		Description: "Reduced constitution",
+		Type:        "debuff",
+		Stat:        StatConstitution,
+		Duration:    3,
+		Value:       -5,
+		Remaining:   3,
+		Stacking:    StackAdd,
+		MaxStacks:   3,
+	}
+
+	EvasionEffect = &Effect{
+		Name:        "Evasion",
+		Description: "30% chance to dodge attacks",
+		Type:        EffectDodge,
+		Duration:    2,
+		Value:       30,
+		Remaining:   2,
+	}
+
+	SteamBarrierEffect = &Effect{
+		Name:        "Steam Barrier",
+		Description: "Absorbs up to 20 damage",
+		Type:        EffectShield,
+		Duration:    3,
+		Value:       20,
+		Remaining:   3,
+	}
+
+	FortifiedEffect = &Effect{
+		Name:        "Fortified",
+		Description: "Increased constitution",
+		Type:        "buff",
+		Stat:        StatConstitution,
+		Duration:    3,
+		Value:       10,
+		Remaining:   3,
+	}
+
+	EnragedEffect = &Effect{
+		Name:        "Enraged",
+		Description: "Increased strength until the end of the battle",
+		Type:        "buff",
+		Stat:        StatStrength,
+		Duration:    enrageDuration,
+		Value:       20,
+		Remaining:   enrageDuration,
+		Stacking:    StackIgnore,
+	}
 )
@@ -0,0 +1,172 @@
+package combat
+
+import (
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// Weather a battle can be fought in
+const (
+	WeatherClear      = "clear"
+	WeatherSteamFog   = "steam-fog"
+	WeatherAcidRain   = "acid-rain"
+	WeatherStrongWind = "strong-wind"
+	WeatherSandstorm  = "sandstorm"
+)
+
+// Weather changes: from weatherChangeRound on, every new round has a
+// weatherChangeChance percent chance to bring other weather of the terrain
+const (
+	weatherChangeRound  = 5
+	weatherChangeChance = 25
+)
+
+// Modifier scales the damage and healing of the abilities it applies to: those
+// of a listed type, used by a listed class, with Steam, costing steam power or,
+// with Ranged, reaching beyond the adjacent tiles. A modifier that lists
+// nothing applies to every ability.
+type Modifier struct {
+	AbilityTypes []string
+	Classes      []character.Class
+	Steam        bool
+	Ranged       bool
+	Multiplier   float64
+}
+
+// appliesTo reports whether the modifier applies to an ability used by attacker
+func (m Modifier) appliesTo(ability *common.Ability, attacker *Participant) bool {
+	if len(m.AbilityTypes) == 0 && len(m.Classes) == 0 && !m.Steam && !m.Ranged {
+		return true
+	}
+	if contains(m.AbilityTypes, ability.Type) || (m.Steam && ability.SteamCost > 0) || (m.Ranged && ability.Range > 1) {
+		return true
+	}
+	for _, class := range m.Classes {
+		if attacker.Class == class {
+			return true
+		}
+	}
+	return false
+}
+
+// terrainModifiers are the modifiers each terrain applies. Besides the world
+// terrains there are the special battlefields steam-rich, mechanical and toxic.
+var terrainModifiers = map[string][]Modifier{
+	"plains": {
+		{Ranged: true, Multiplier: 1.1}, // clear lines of fire
+	},
+	"forest": {
+		{AbilityTypes: []string{"healing"}, Multiplier: 1.15},
+		{Ranged: true, Multiplier: 0.9},
+	},
+	"mountain": {
+		{Classes: []character.Class{character.ClockworkKnight}, Multiplier: 1.15},
+		{Ranged: true, Multiplier: 1.1}, // high ground
+	},
+	"water": {
+		{Steam: true, Multiplier: 1.1},
+		{AbilityTypes: []string{"chemical"}, Multiplier: 0.85},
+	},
+	"desert": {
+		{Classes: []character.Class{character.Aeronaut}, Multiplier: 1.15},
+		{AbilityTypes: []string{"mechanical"}, Multiplier: 0.9}, // sand in the gears
+	},
+	"swamp": {
+		{Classes: []character.Class{character.Alchemist}, AbilityTypes: []string{"chemical"}, Multiplier: 1.15},
+		{Ranged: true, Multiplier: 0.9},
+	},
+	"steam_city": {
+		{Steam: true, Multiplier: 1.2},
+		{Classes: []character.Class{character.Engineer}, Multiplier: 1.1},
+	},
+	"steam-rich": {
+		{Steam: true, Multiplier: 1.2},
+	},
+	"mechanical": {
+		{Classes: []character.Class{character.Engineer}, AbilityTypes: []string{"mechanical"}, Multiplier: 1.15},
+	},
+	"toxic": {
+		{Classes: []character.Class{character.Alchemist}, AbilityTypes: []string{"chemical"}, Multiplier: 1.15},
+	},
+}
+
+// weatherModifiers are the modifiers each kind of weather applies
+var weatherModifiers = map[string][]Modifier{
+	WeatherSteamFog:   {{Ranged: true, Multiplier: 0.8}},
+	WeatherAcidRain:   {{Multiplier: 0.9}},
+	WeatherStrongWind: {{Ranged: true, Multiplier: 0.85}, {AbilityTypes: []string{"arcane"}, Multiplier: 1.1}},
+	WeatherSandstorm:  {{Ranged: true, Multiplier: 0.8}, {AbilityTypes: []string{"mechanical"}, Multiplier: 0.9}},
+}
+
+// weatherChance is how likely a kind of weather is, relative to the others of
+// a terrain
+type weatherChance struct {
+	weather string
+	weight  int
+}
+
+// climates lists the weather each terrain can have. Terrains without a
+// climate are always clear.
+var climates = map[string][]weatherChance{
+	"plains":     {{WeatherClear, 6}, {WeatherStrongWind, 3}, {WeatherAcidRain, 1}},
+	"forest":     {{WeatherClear, 5}, {WeatherSteamFog, 4}, {WeatherAcidRain, 1}},
+	"mountain":   {{WeatherClear, 4}, {WeatherStrongWind, 5}, {WeatherSteamFog, 1}},
+	"water":      {{WeatherClear, 4}, {WeatherSteamFog, 6}},
+	"desert":     {{WeatherClear, 5}, {WeatherSandstorm, 4}, {WeatherStrongWind, 1}},
+	"swamp":      {{WeatherClear, 1}, {WeatherSteamFog, 5}, {WeatherAcidRain, 4}},
+	"steam_city": {{WeatherClear, 4}, {WeatherSteamFog, 4}, {WeatherAcidRain, 2}},
+}
+
+// modify multiplies the modifiers of a list that apply to an ability
+func modify(modifiers []Modifier, ability *common.Ability, attacker *Participant) float64 {
+	multiplier := 1.0
+	for _, m := range modifiers {
+		if m.appliesTo(ability, attacker) {
+			multiplier *= m.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// rollWeather picks the battle's weather from its terrain's climate, other
+// than the current weather if except is set
+func (b *Battle) rollWeather(except bool) {
+	var chances []weatherChance
+	total := 0
+	for _, c := range climates[b.Terrain] {
+		if !except || c.weather != b.Weather {
+			chances = append(chances, c)
+			total += c.weight
+		}
+	}
+	if total == 0 {
+		if !except {
+			b.Weather = WeatherClear
+		}
+		return
+	}
+
+	n := b.roll(total)
+	for _, c := range chances {
+		if n < c.weight {
+			b.Weather = c.weather
+			return
+		}
+		n -= c.weight
+	}
+}
+
+// changeWeather may bring new weather at the start of a round of a long battle
+func (b *Battle) changeWeather() {
+	if b.Round < weatherChangeRound || len(climates[b.Terrain]) < 2 || b.roll(100) >= weatherChangeChance {
+		return
+	}
+	b.rollWeather(true)
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: "Weather",
+		Action:    "Change",
+		Effects:   []string{b.Weather},
+	})
+}
@@ -0,0 +1,133 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/world"
+)
+
+func TestEveryTerrainHasModifiers(t *testing.T) {
+	for _, terrain := range []world.TerrainType{world.Forest, world.Mountain, world.Water, world.Plains, world.Desert, world.Swamp, world.SteamCity} {
+		if len(terrainModifiers[string(terrain)]) == 0 {
+			t.Errorf("Expected modifiers for terrain %s", terrain)
+		}
+		if len(climates[string(terrain)]) == 0 {
+			t.Errorf("Expected a climate for terrain %s", terrain)
+		}
+	}
+}
+
+func TestTerrainModifiers(t *testing.T) {
+	battle := NewBattle(BattleTypePvP)
+	alchemist := &Participant{Class: character.Alchemist}
+	engineer := &Participant{Class: character.Engineer}
+	toxin := &common.Ability{Type: "chemical"}
+	shot := &common.Ability{Type: "mechanical", Range: 4}
+
+	tests := []struct {
+		terrain  string
+		ability  *common.Ability
+		attacker *Participant
+		expected float64
+	}{
+		{"swamp", toxin, engineer, 1.15},
+		{"swamp", shot, alchemist, 1.15 * 0.9},
+		{"forest", shot, engineer, 0.9},
+		{"plains", shot, engineer, 1.1},
+		{"steam_city", &common.Ability{SteamCost: 5}, engineer, 1.2 * 1.1},
+		{"water", toxin, engineer, 0.85},
+		{"neutral", shot, engineer, 1},
+	}
+	for _, tt := range tests {
+		battle.Terrain = tt.terrain
+		if bonus := battle.calculateTerrainBonus(tt.ability, tt.attacker); bonus != tt.expected {
+			t.Errorf("Expected a %s bonus of %v for %s, got %v", tt.terrain, tt.expected, tt.ability.Type, bonus)
+		}
+	}
+
+	battle.Weather = WeatherSandstorm
+	if penalty := battle.calculateWeatherPenalty(&common.Ability{Type: "mechanical"}, engineer); penalty != 0.9 {
+		t.Errorf("Expected a sandstorm penalty of 0.9 for mechanical abilities, got %v", penalty)
+	}
+}
+
+func TestRangedClassAbilities(t *testing.T) {
+	abilities := map[string]*common.Ability{}
+	for _, class := range []character.Class{character.SteamMage, character.ClockworkKnight} {
+		for _, ability := range character.GetCharacterAbilities(class, 1) {
+			converted := ability.Common()
+			abilities[ability.Name] = &converted
+		}
+	}
+	bolt, strike := abilities["Steam Bolt"], abilities["Steam-powered Strike"]
+	if bolt == nil || strike == nil {
+		t.Fatal("Expected the Steam Bolt and Steam-powered Strike class abilities")
+	}
+
+	battle := NewBattle(BattleTypePvP)
+	battle.Terrain = "forest"
+	battle.Weather = WeatherSteamFog
+	mage := &Participant{Class: character.SteamMage}
+	knight := &Participant{Class: character.ClockworkKnight}
+	if bonus, penalty := battle.calculateTerrainBonus(bolt, mage), battle.calculateWeatherPenalty(bolt, mage); bonus != 0.9 || penalty != 0.8 {
+		t.Errorf("Expected the forest and fog to hamper Steam Bolt by 0.9 and 0.8, got %v and %v", bonus, penalty)
+	}
+	if bonus, penalty := battle.calculateTerrainBonus(strike, knight), battle.calculateWeatherPenalty(strike, knight); bonus != 1 || penalty != 1 {
+		t.Errorf("Expected the melee strike to be unaffected, got %v and %v", bonus, penalty)
+	}
+}
+
+func TestWeatherFromTerrain(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	battle.SetTerrain("swamp")
+	// The steady roller lands in the middle of the swamp's climate
+	if battle.Weather != WeatherSteamFog {
+		t.Errorf("Expected steam-fog in the swamp, got %s", battle.Weather)
+	}
+
+	battle = NewBattle(BattleTypePvE)
+	battle.SetTerrain("neutral")
+	if battle.Weather != WeatherClear {
+		t.Errorf("Expected clear weather without a climate, got %s", battle.Weather)
+	}
+}
+
+func TestWeatherChanges(t *testing.T) {
+	useSeededRolls(t)
+
+	battle := NewBattle(BattleTypePvP)
+	battle.SetSeed(7)
+	battle.SetTerrain("swamp")
+	for _, id := range []string{"a", "b"} {
+		battle.AddPlayer(&character.Character{ID: id, Name: id, Health: 100, MaxHealth: 100})
+	}
+
+	changes := 0
+	for battle.Round <= 40 {
+		battle.SkipTurn()
+		for _, entry := range battle.CombatLog {
+			if entry.Character == "Weather" && entry.Round < weatherChangeRound {
+				t.Fatalf("Expected the weather to hold until round %d, changed in round %d", weatherChangeRound, entry.Round)
+			}
+		}
+	}
+	previous := ""
+	for _, entry := range battle.CombatLog {
+		if entry.Character != "Weather" {
+			continue
+		}
+		changes++
+		if entry.Effects[0] == previous {
+			t.Errorf("Expected the weather to change, stayed %s", previous)
+		}
+		previous = entry.Effects[0]
+	}
+	if changes == 0 {
+		t.Errorf("Expected the weather to change in a long fight")
+	}
+	if previous != "" && battle.Weather != previous {
+		t.Errorf("Expected the battle to have the last logged weather %s, got %s", previous, battle.Weather)
+	}
+}
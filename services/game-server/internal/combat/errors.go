@@ -6,4 +6,11 @@ var (
 	ErrInsufficientSteamPower = errors.New("insufficient steam power")
 	ErrInvalidTarget          = errors.New("invalid target")
 	ErrInvalidAction          = errors.New("invalid action")
+	ErrAbilityOnCooldown      = errors.New("ability is on cooldown")
+	ErrNoLineOfSight          = errors.New("target is not in line of sight")
+	ErrInvalidMove            = errors.New("invalid move")
+	ErrNotEnoughMovement      = errors.New("not enough movement left")
+	ErrCannotFlee             = errors.New("cannot flee from this battle")
+	ErrReplayDiverged         = errors.New("replay diverged from the recorded battle")
+	ErrReactionAbility        = errors.New("reactions cannot be used on a turn")
 )
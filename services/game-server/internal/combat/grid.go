@@ -0,0 +1,472 @@
+package combat
+
+import (
+	"fmt"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// Tile represents the ground of a battle grid cell
+type Tile string
+
+const (
+	TileOpen     Tile = "open"
+	TileRough    Tile = "rough"    // costs double movement
+	TileObstacle Tile = "obstacle" // blocks movement and line of sight
+)
+
+// Battle grid dimensions; the first and last columns are the deployment zones
+const (
+	battleGridWidth  = 8
+	battleGridHeight = 6
+)
+
+// baseMovement is the number of tiles a participant can move each turn
+const baseMovement = 3
+
+// terrainFeatures lists how many obstacles and rough tiles each world terrain
+// puts on a battle grid
+var terrainFeatures = map[string]struct{ obstacles, rough int }{
+	"plains":     {0, 2},
+	"forest":     {6, 4},
+	"mountain":   {8, 4},
+	"water":      {2, 10},
+	"desert":     {2, 6},
+	"swamp":      {3, 12},
+	"steam_city": {6, 2},
+}
+
+// Grid is the tactical map a battle is fought on
+type Grid struct {
+	Width  int
+	Height int
+	Tiles  [][]Tile // Tiles[y][x]
+}
+
+// NewGrid creates an open grid
+func NewGrid(width, height int) *Grid {
+	tiles := make([][]Tile, height)
+	for y := range tiles {
+		tiles[y] = make([]Tile, width)
+		for x := range tiles[y] {
+			tiles[y][x] = TileOpen
+		}
+	}
+	return &Grid{Width: width, Height: height, Tiles: tiles}
+}
+
+// InBounds reports whether a position lies on the grid
+func (g *Grid) InBounds(c common.Coordinates) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < g.Width && c.Y < g.Height
+}
+
+// Tile returns the tile at a position; positions off the grid are obstacles
+func (g *Grid) Tile(c common.Coordinates) Tile {
+	if !g.InBounds(c) {
+		return TileObstacle
+	}
+	return g.Tiles[c.Y][c.X]
+}
+
+// SetTile changes the tile at a position on the grid
+func (g *Grid) SetTile(c common.Coordinates, tile Tile) {
+	if g.InBounds(c) {
+		g.Tiles[c.Y][c.X] = tile
+	}
+}
+
+// LineOfSight reports whether no obstacle lies on the straight line between
+// two positions
+func (g *Grid) LineOfSight(from, to common.Coordinates) bool {
+	for _, c := range line(from, to) {
+		if c != from && c != to && g.Tile(c) == TileObstacle {
+			return false
+		}
+	}
+	return true
+}
+
+// line returns the tiles on the straight line between two positions
+func line(from, to common.Coordinates) []common.Coordinates {
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	sx, sy := sign(to.X-from.X), sign(to.Y-from.Y)
+	err := dx + dy
+
+	var tiles []common.Coordinates
+	c := from
+	for {
+		tiles = append(tiles, c)
+		if c == to {
+			return tiles
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			c.X += sx
+		}
+		if e2 <= dx {
+			err += dx
+			c.Y += sy
+		}
+	}
+}
+
+// generateGrid lays out a battle grid for a world terrain, keeping the
+// deployment zones clear and both of them connected
+func (b *Battle) generateGrid(terrain string) *Grid {
+	features := terrainFeatures[terrain]
+	for attempt := 0; attempt < 10; attempt++ {
+		grid := NewGrid(battleGridWidth, battleGridHeight)
+		b.scatter(grid, TileRough, features.rough)
+		b.scatter(grid, TileObstacle, features.obstacles)
+		if grid.connected() {
+			return grid
+		}
+	}
+	return NewGrid(battleGridWidth, battleGridHeight)
+}
+
+// scatter puts up to count tiles of a kind between the deployment zones
+func (b *Battle) scatter(grid *Grid, tile Tile, count int) {
+	for i := 0; i < count; i++ {
+		grid.SetTile(common.Coordinates{X: 1 + b.roll(grid.Width-2), Y: b.roll(grid.Height)}, tile)
+	}
+}
+
+// connected reports whether the last column can be reached from the first
+func (g *Grid) connected() bool {
+	costs := g.movementCosts(common.Coordinates{X: 0, Y: 0}, nil)
+	for y := 0; y < g.Height; y++ {
+		if _, reachable := costs[common.Coordinates{X: g.Width - 1, Y: y}]; reachable {
+			return true
+		}
+	}
+	return false
+}
+
+// movementCosts returns the cheapest movement cost from a position to every
+// reachable tile, moving in eight directions around obstacles and blocked tiles
+func (g *Grid) movementCosts(from common.Coordinates, blocked map[common.Coordinates]bool) map[common.Coordinates]int {
+	costs := map[common.Coordinates]int{from: 0}
+	queue := []common.Coordinates{from}
+	for len(queue) > 0 {
+		// The grid is tiny, so a linear search for the cheapest tile is fine
+		best := 0
+		for i := range queue {
+			if costs[queue[i]] < costs[queue[best]] {
+				best = i
+			}
+		}
+		c := queue[best]
+		queue = append(queue[:best], queue[best+1:]...)
+
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				next := common.Coordinates{X: c.X + dx, Y: c.Y + dy}
+				tile := g.Tile(next)
+				if next == c || tile == TileObstacle || blocked[next] {
+					continue
+				}
+				cost := costs[c] + 1
+				if tile == TileRough {
+					cost++
+				}
+				if known, seen := costs[next]; !seen || cost < known {
+					costs[next] = cost
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return costs
+}
+
+// SetTerrain sets the world terrain the battle is fought on, lays out its
+// grid and rolls the weather from the terrain's climate. It must be called
+// before participants join, who are then placed in the deployment zones on
+// either side of the grid.
+func (b *Battle) SetTerrain(terrain string) {
+	b.Terrain = terrain
+	b.Grid = b.generateGrid(terrain)
+	b.rollWeather(false)
+}
+
+// place puts a participant on a free tile of its side's deployment zone. The
+// first side deploys on the left edge of the grid and the next on the right.
+func (b *Battle) place(p *Participant) {
+	if b.Grid == nil {
+		return
+	}
+
+	column := 0
+	if b.deploymentSide(p) {
+		column = b.Grid.Width - 1
+	}
+	step := 1
+	if column > 0 {
+		step = -1
+	}
+
+	for ; column >= 0 && column < b.Grid.Width; column += step {
+		for i := 0; i < b.Grid.Height; i++ {
+			// Fill rows from the middle outwards
+			offset := (i + 1) / 2
+			if i%2 == 1 {
+				offset = -offset
+			}
+			c := common.Coordinates{X: column, Y: b.Grid.Height/2 + offset}
+			if occupant := b.occupant(c); b.Grid.Tile(c) != TileObstacle && (occupant == nil || occupant == p) {
+				p.Position = c
+				return
+			}
+		}
+	}
+}
+
+// deploymentSide reports whether a participant deploys on the right edge:
+// sides alternate between the edges in the order they joined
+func (b *Battle) deploymentSide(p *Participant) bool {
+	var sides []string
+	for _, other := range b.Participants {
+		side := b.side(other)
+		if side == b.side(p) {
+			break
+		}
+		if !contains(sides, side) {
+			sides = append(sides, side)
+		}
+	}
+	return len(sides)%2 == 1
+}
+
+// occupant returns the active participant standing on a position
+func (b *Battle) occupant(c common.Coordinates) *Participant {
+	for _, p := range b.Participants {
+		if p.IsActive && p.Position == c {
+			return p
+		}
+	}
+	return nil
+}
+
+// passable reports whether a participant can enter a position
+func (b *Battle) passable(c common.Coordinates) bool {
+	if b.Grid != nil && b.Grid.Tile(c) == TileObstacle {
+		return false
+	}
+	return b.occupant(c) == nil
+}
+
+// Move moves the active participant to a position, spending movement for
+// every tile on the way. Moving does not end the turn.
+func (b *Battle) Move(to common.Coordinates) error {
+	if b.State != BattleActive {
+		return fmt.Errorf("battle is not active")
+	}
+
+	p := b.ActiveParticipant()
+	if p == nil || !p.IsActive {
+		return fmt.Errorf("participant is not active")
+	}
+	if to == p.Position || !b.passable(to) {
+		return ErrInvalidMove
+	}
+
+	cost := b.movementCost(p, to)
+	if cost < 0 {
+		return ErrInvalidMove
+	}
+	if cost > p.MovementLeft {
+		return ErrNotEnoughMovement
+	}
+
+	b.recordEvent(ReplayEvent{Kind: ReplayMove, ActorID: p.ID, To: &to})
+	from := p.Position
+	p.Position = to
+	p.MovementLeft -= cost
+	delete(b.Timeouts, p.ID)
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: p.Name,
+		Action:    "Move",
+		Effects:   []string{fmt.Sprintf("(%d,%d) -> (%d,%d)", from.X, from.Y, to.X, to.Y)},
+	})
+	b.Version++
+	return nil
+}
+
+// movementCost returns the movement a participant needs to reach a position,
+// or -1 if it cannot get there
+func (b *Battle) movementCost(p *Participant, to common.Coordinates) int {
+	if b.Grid == nil {
+		return distanceBetween(p.Position, to)
+	}
+
+	blocked := make(map[common.Coordinates]bool)
+	for _, other := range b.Participants {
+		if other != p && other.IsActive {
+			blocked[other.Position] = true
+		}
+	}
+	cost, reachable := b.Grid.movementCosts(p.Position, blocked)[to]
+	if !reachable {
+		return -1
+	}
+	return cost
+}
+
+// Approach moves the active participant towards a target along the cheapest
+// path its movement allows, stopping once the target is within distance
+func (b *Battle) Approach(targetID string, distance int) error {
+	p := b.ActiveParticipant()
+	target := b.GetParticipant(targetID)
+	if p == nil || target == nil {
+		return ErrInvalidTarget
+	}
+
+	// Getting closer than the distance is no better than reaching it
+	score := func(c common.Coordinates) int {
+		return max(distanceBetween(c, target.Position), distance)
+	}
+	return b.moveToBest(p, score)
+}
+
+// MoveToEdge moves the active participant towards the nearest edge of the
+// grid, from where it can flee, keeping away from its opponents
+func (b *Battle) MoveToEdge() error {
+	p := b.ActiveParticipant()
+	if p == nil || b.Grid == nil {
+		return nil
+	}
+
+	opponents := b.Opponents(p.ID)
+	score := func(c common.Coordinates) int {
+		nearest := b.Grid.Width + b.Grid.Height
+		for _, o := range opponents {
+			nearest = min(nearest, distanceBetween(c, o.Position))
+		}
+		return b.Grid.edgeDistance(c)*100 - nearest
+	}
+	return b.moveToBest(p, score)
+}
+
+// moveToBest moves a participant to the reachable tile with the lowest score,
+// preferring cheaper tiles on ties. Staying put is not a move.
+func (b *Battle) moveToBest(p *Participant, score func(common.Coordinates) int) error {
+	best, bestScore, bestCost := p.Position, score(p.Position), 0
+	for c, cost := range b.reachable(p) {
+		s := score(c)
+		// Ties are broken on the position so the choice does not depend on map order
+		if s < bestScore || (s == bestScore && (cost < bestCost || (cost == bestCost && before(c, best)))) {
+			best, bestScore, bestCost = c, s, cost
+		}
+	}
+	if best == p.Position {
+		return nil
+	}
+	return b.Move(best)
+}
+
+// before orders positions row by row
+func before(a, b common.Coordinates) bool {
+	return a.Y < b.Y || (a.Y == b.Y && a.X < b.X)
+}
+
+// reachable returns the tiles a participant can move to with its movement
+// left and what each costs
+func (b *Battle) reachable(p *Participant) map[common.Coordinates]int {
+	tiles := make(map[common.Coordinates]int)
+	if b.Grid == nil {
+		for dy := -p.MovementLeft; dy <= p.MovementLeft; dy++ {
+			for dx := -p.MovementLeft; dx <= p.MovementLeft; dx++ {
+				c := common.Coordinates{X: p.Position.X + dx, Y: p.Position.Y + dy}
+				if c != p.Position && b.passable(c) {
+					tiles[c] = max(abs(dx), abs(dy))
+				}
+			}
+		}
+		return tiles
+	}
+
+	blocked := make(map[common.Coordinates]bool)
+	for _, other := range b.Participants {
+		if other != p && other.IsActive {
+			blocked[other.Position] = true
+		}
+	}
+	for c, cost := range b.Grid.movementCosts(p.Position, blocked) {
+		if c != p.Position && cost <= p.MovementLeft {
+			tiles[c] = cost
+		}
+	}
+	return tiles
+}
+
+// AtEdge reports whether a participant stands on the edge of the grid, from
+// where it can flee. Without a grid every participant can flee.
+func (b *Battle) AtEdge(p *Participant) bool {
+	return b.Grid == nil || b.Grid.edgeDistance(p.Position) == 0
+}
+
+// edgeDistance returns how many tiles a position is away from the edge of the grid
+func (g *Grid) edgeDistance(c common.Coordinates) int {
+	return min(c.X, c.Y, g.Width-1-c.X, g.Height-1-c.Y)
+}
+
+// Distance returns the distance in tiles between two participants
+func (b *Battle) Distance(fromID, toID string) int {
+	from, to := b.GetParticipant(fromID), b.GetParticipant(toID)
+	if from == nil || to == nil {
+		return -1
+	}
+	return distanceBetween(from.Position, to.Position)
+}
+
+// movement returns the tiles a participant can move each turn. Deployables
+// stay where they were put.
+func (b *Battle) movement(p *Participant) int {
+	if p.Type == ParticipantDeployable {
+		return 0
+	}
+	return baseMovement + b.attribute(p, StatDexterity)/10
+}
+
+// hasLineOfSight reports whether nothing blocks the view between two positions
+func (b *Battle) hasLineOfSight(from, to common.Coordinates) bool {
+	return b.Grid == nil || b.Grid.LineOfSight(from, to)
+}
+
+// AreaTiles returns the tiles an area ability centred on a position covers:
+// every tile within the radius that the blast can reach from the centre
+func (b *Battle) AreaTiles(center common.Coordinates, radius int) []common.Coordinates {
+	var tiles []common.Coordinates
+	for y := center.Y - radius; y <= center.Y+radius; y++ {
+		for x := center.X - radius; x <= center.X+radius; x++ {
+			c := common.Coordinates{X: x, Y: y}
+			if b.Grid != nil && (b.Grid.Tile(c) == TileObstacle || !b.Grid.LineOfSight(center, c)) {
+				continue
+			}
+			tiles = append(tiles, c)
+		}
+	}
+	return tiles
+}
+
+// abs returns the absolute value of v
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// contains reports whether a slice holds a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
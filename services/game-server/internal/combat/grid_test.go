@@ -0,0 +1,236 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// newGridDuel creates a PvP battle on an open grid; "first" acts first
+func newGridDuel() (*Battle, *Participant, *Participant) {
+	battle := NewBattle(BattleTypePvP)
+	battle.Grid = NewGrid(battleGridWidth, battleGridHeight)
+	battle.AddPlayer(&character.Character{ID: "first", Name: "First", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50})
+	battle.AddPlayer(&character.Character{ID: "second", Name: "Second", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50})
+	return battle, battle.GetParticipant("first"), battle.GetParticipant("second")
+}
+
+func TestSetTerrain(t *testing.T) {
+	useSeededRolls(t)
+
+	for terrain := range terrainFeatures {
+		battle := NewBattle(BattleTypePvE)
+		battle.SetTerrain(terrain)
+
+		grid := battle.Grid
+		if grid.Width != battleGridWidth || grid.Height != battleGridHeight {
+			t.Errorf("Expected %dx%d grid for %s, got %dx%d", battleGridWidth, battleGridHeight, terrain, grid.Width, grid.Height)
+		}
+		for y := 0; y < grid.Height; y++ {
+			for _, x := range []int{0, grid.Width - 1} {
+				if grid.Tiles[y][x] != TileOpen {
+					t.Errorf("Expected clear deployment zones for %s, got %s at (%d,%d)", terrain, grid.Tiles[y][x], x, y)
+				}
+			}
+		}
+		if !grid.connected() {
+			t.Errorf("Expected the deployment zones of %s to be connected", terrain)
+		}
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	battle.SetTerrain("plains")
+	battle.AddPlayer(&character.Character{ID: "p1", Name: "P1", Health: 100, MaxHealth: 100})
+	battle.AddMob(&mob.Mob{ID: "m1", Name: "M1", Health: 100, MaxHealth: 100})
+	battle.AddPlayer(&character.Character{ID: "p2", Name: "P2", Health: 100, MaxHealth: 100})
+
+	for _, id := range []string{"p1", "p2"} {
+		if x := battle.GetParticipant(id).Position.X; x != 0 {
+			t.Errorf("Expected %s on the left edge, got x=%d", id, x)
+		}
+	}
+	if x := battle.GetParticipant("m1").Position.X; x != battleGridWidth-1 {
+		t.Errorf("Expected mob on the right edge, got x=%d", x)
+	}
+	if battle.GetParticipant("p1").Position == battle.GetParticipant("p2").Position {
+		t.Error("Expected players on different tiles")
+	}
+}
+
+func TestMove(t *testing.T) {
+	battle, first, second := newGridDuel()
+	start := first.Position
+
+	if err := battle.Move(common.Coordinates{X: start.X + 4, Y: start.Y}); err != ErrNotEnoughMovement {
+		t.Errorf("Expected ErrNotEnoughMovement, got %v", err)
+	}
+	if err := battle.Move(common.Coordinates{X: -1, Y: start.Y}); err != ErrInvalidMove {
+		t.Errorf("Expected ErrInvalidMove off the grid, got %v", err)
+	}
+
+	// Rough ground costs double
+	battle.Grid.SetTile(common.Coordinates{X: 1, Y: start.Y}, TileRough)
+	battle.Grid.SetTile(common.Coordinates{X: 1, Y: start.Y - 1}, TileObstacle)
+	battle.Grid.SetTile(common.Coordinates{X: 1, Y: start.Y + 1}, TileObstacle)
+	if err := battle.Move(common.Coordinates{X: 2, Y: start.Y}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.MovementLeft != baseMovement-3 {
+		t.Errorf("Expected %d movement left, got %d", baseMovement-3, first.MovementLeft)
+	}
+	if battle.ActiveParticipant() != first {
+		t.Error("Expected moving not to end the turn")
+	}
+	if battle.Version != 1 {
+		t.Errorf("Expected version 1, got %d", battle.Version)
+	}
+
+	// Movement is restored at the start of the participant's next turn
+	battle.SkipTurn()
+	if err := battle.Move(first.Position); err != ErrInvalidMove {
+		t.Errorf("Expected ErrInvalidMove onto an occupied tile, got %v", err)
+	}
+	battle.SkipTurn()
+	if first.MovementLeft != baseMovement {
+		t.Errorf("Expected movement %d, got %d", baseMovement, first.MovementLeft)
+	}
+	if err := battle.Move(second.Position); err != ErrInvalidMove {
+		t.Errorf("Expected ErrInvalidMove onto an occupied tile, got %v", err)
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	battle, first, second := newGridDuel()
+	second.Position = common.Coordinates{X: first.Position.X + 2, Y: first.Position.Y}
+	strike := &common.Ability{Name: "Steam Bolt", Damage: 10, Range: 4}
+
+	battle.Grid.SetTile(common.Coordinates{X: first.Position.X + 1, Y: first.Position.Y}, TileObstacle)
+	if _, err := battle.ExecuteAction(strike, second.ID); err != ErrNoLineOfSight {
+		t.Errorf("Expected ErrNoLineOfSight, got %v", err)
+	}
+
+	battle.Grid.SetTile(common.Coordinates{X: first.Position.X + 1, Y: first.Position.Y}, TileRough)
+	if _, err := battle.ExecuteAction(strike, second.ID); err != nil {
+		t.Errorf("Expected rough ground not to block the view, got %v", err)
+	}
+}
+
+func TestAreaTiles(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	battle.Grid = NewGrid(battleGridWidth, battleGridHeight)
+	battle.AddPlayer(&character.Character{ID: "player", Name: "Player", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50})
+	center := &mob.Mob{ID: "center", Name: "Center", Health: 100, MaxHealth: 100}
+	near := &mob.Mob{ID: "near", Name: "Near", Health: 100, MaxHealth: 100}
+	covered := &mob.Mob{ID: "covered", Name: "Covered", Health: 100, MaxHealth: 100}
+	battle.AddMob(center)
+	battle.AddMob(near)
+	battle.AddMob(covered)
+
+	battle.GetParticipant("center").Position = common.Coordinates{X: 4, Y: 2}
+	battle.GetParticipant("near").Position = common.Coordinates{X: 5, Y: 3}
+	battle.GetParticipant("covered").Position = common.Coordinates{X: 6, Y: 2}
+	battle.Grid.SetTile(common.Coordinates{X: 5, Y: 2}, TileObstacle)
+
+	tiles := battle.AreaTiles(common.Coordinates{X: 4, Y: 2}, 2)
+	for _, tile := range tiles {
+		if tile == (common.Coordinates{X: 5, Y: 2}) || tile == (common.Coordinates{X: 6, Y: 2}) {
+			t.Errorf("Expected the obstacle to shield %v", tile)
+		}
+	}
+
+	nova := &common.Ability{Name: "Steam Nova", Damage: 20, Range: 5, Area: 2}
+	if _, err := battle.ExecuteAction(nova, "center"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if center.Health != 80 || near.Health != 90 || covered.Health != 100 {
+		t.Errorf("Expected health 80/90/100, got %d/%d/%d", center.Health, near.Health, covered.Health)
+	}
+}
+
+func TestApproach(t *testing.T) {
+	battle, first, second := newGridDuel()
+	before := battle.Distance(first.ID, second.ID)
+
+	if err := battle.Approach(second.ID, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if distance := battle.Distance(first.ID, second.ID); distance != before-baseMovement {
+		t.Errorf("Expected distance %d, got %d", before-baseMovement, distance)
+	}
+	if first.MovementLeft != 0 {
+		t.Errorf("Expected no movement left, got %d", first.MovementLeft)
+	}
+
+	// Approaching does not go closer than the wanted distance
+	battle.SkipTurn()
+	if err := battle.Approach(first.ID, 4); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if distance := battle.Distance(first.ID, second.ID); distance != 4 {
+		t.Errorf("Expected distance 4, got %d", distance)
+	}
+}
+
+func TestFlee(t *testing.T) {
+	battle, first, second := newGridDuel()
+	first.Position = common.Coordinates{X: 3, Y: 3}
+
+	// Away from the edge an equally fast opponent usually catches up
+	escaped, err := battle.Flee()
+	if err != nil || escaped {
+		t.Fatalf("Expected a failed attempt away from the edge, got %v, %v", escaped, err)
+	}
+	if last := battle.CombatLog[len(battle.CombatLog)-1]; last.Action != "Flee" || !contains(last.Effects, OutcomeFleeFailed) {
+		t.Errorf("Expected failed flee in combat log, got %+v", last)
+	}
+	if battle.ActiveParticipant() != second {
+		t.Fatalf("Expected a failed attempt to end the turn, got %s", battle.ActiveParticipant().ID)
+	}
+	battle.SkipTurn()
+
+	// From the edge fleeing always succeeds
+	if err := battle.MoveToEdge(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !battle.AtEdge(first) {
+		t.Fatalf("Expected to reach the edge, got %v", first.Position)
+	}
+	if escaped, err := battle.Flee(); err != nil || !escaped {
+		t.Fatalf("Expected to escape, got %v, %v", escaped, err)
+	}
+
+	if !battle.IsOver() {
+		t.Fatal("Expected battle to be over")
+	}
+	if !first.Fled || first.IsActive {
+		t.Error("Expected first to have fled")
+	}
+	if len(battle.Winners) != 1 || battle.Winners[0] != second.ID {
+		t.Errorf("Expected second to win, got %v", battle.Winners)
+	}
+	if last := battle.CombatLog[len(battle.CombatLog)-1]; last.Action != "Flee" {
+		t.Errorf("Expected flee in combat log, got %+v", last)
+	}
+}
+
+func TestFleeChance(t *testing.T) {
+	battle, first, _ := newGridDuel()
+	first.Position = common.Coordinates{X: 3, Y: 3}
+
+	if chance := battle.fleeChance(first); chance != baseFleeChance {
+		t.Errorf("Expected flee chance %d, got %d", baseFleeChance, chance)
+	}
+
+	// A much faster participant gets away from anywhere
+	first.Attributes.Dexterity.Value = 40
+	if chance := battle.fleeChance(first); chance != 60 {
+		t.Errorf("Expected flee chance 60, got %d", chance)
+	}
+	if escaped, err := battle.Flee(); err != nil || !escaped {
+		t.Errorf("Expected to escape, got %v, %v", escaped, err)
+	}
+}
@@ -0,0 +1,82 @@
+package combat
+
+import (
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// MobActions carries out the steps a mob's AI decides on during its turn. The
+// game server publishes every step to the battle; the simulator just takes it.
+type MobActions interface {
+	// Move performs a move on the battle grid
+	Move(move func() error) error
+	// Act uses an ability on a target
+	Act(ability *common.Ability, targetID string) error
+	// Flee tries to flee the battle
+	Flee() error
+}
+
+// MobTurn lets the AI of the active mob take its turn: a mob hurt badly enough
+// runs for the edge of the battlefield and flees, bosses excepted, others
+// close in on their chosen target and use their best ability, healing and
+// buffing their own side. It reports whether the mob's turn is over.
+func (b *Battle) MobTurn(ai *mob.AIBehavior, actions MobActions) bool {
+	actor := b.ActiveParticipant()
+	mc, ok := actor.Combatant().(*MobCombatant)
+	if !ok {
+		return false
+	}
+	ai.Cooldowns = actor.Cooldowns
+	// Abilities gained during the battle, e.g. in a boss phase, are on the participant
+	fighter := *mc.Mob
+	fighter.Abilities = actor.Abilities
+	m := &fighter
+
+	if ai.ShouldFlee(m) {
+		ai.State = mob.Fleeing
+	}
+	// Bosses fight to the end
+	if ai.State == mob.Fleeing && !b.IsBoss(m.ID) {
+		actions.Move(b.MoveToEdge)
+		return actions.Flee() == nil
+	}
+	ai.State = mob.Aggressive
+
+	target := ai.ChooseTarget(b.battleTargets(actor, b.Opponents(actor.ID)))
+	if target == nil {
+		return false
+	}
+	ally := ai.ChooseAlly(b.battleTargets(actor, b.allies(actor.ID)))
+	ability := ai.ChooseAction(m, target, ally)
+	if ability == nil {
+		// Nothing reaches the target from here, so close in and look again
+		actions.Move(func() error {
+			return b.Approach(target.ID, ai.PreferredRange(m))
+		})
+		target.Distance = b.Distance(actor.ID, target.ID)
+		ally = ai.ChooseAlly(b.battleTargets(actor, b.allies(actor.ID)))
+		ability = ai.ChooseAction(m, target, ally)
+	}
+	if ability == nil {
+		return false
+	}
+	if mob.Supports(ability) {
+		return actions.Act(ability, ally.ID) == nil
+	}
+	return actions.Act(ability, target.ID) == nil
+}
+
+// battleTargets describes participants for the AI of actor
+func (b *Battle) battleTargets(actor *Participant, participants []*Participant) []mob.BattleTarget {
+	var targets []mob.BattleTarget
+	for _, p := range participants {
+		targets = append(targets, mob.BattleTarget{
+			ID:        p.ID,
+			Health:    p.Health,
+			MaxHealth: p.MaxHealth,
+			Distance:  b.Distance(actor.ID, p.ID),
+			Threat:    b.GetThreat(actor.ID, p.ID),
+		})
+	}
+	return targets
+}
@@ -0,0 +1,205 @@
+package combat
+
+import "github.com/redfoxius/roleplay/services/game-server/internal/common"
+
+// Reaction conditions. A reaction ability is never used on a turn; it
+// triggers out of turn when its condition is met, if the participant has the
+// steam power for it and it is not cooling down. A reaction's Range is how far
+// it reaches, 0 for any distance.
+const (
+	ReactionCounter   = "counter"   // strike back at an attacker that hit the participant
+	ReactionInterrupt = "interrupt" // strike an opponent that starts channelling and stop it
+	ReactionIntercept = "intercept" // take a hit meant for an ally
+)
+
+// Channel is an ability a participant is channelling. It resolves at the
+// start of the participant's turn once its turns have passed, unless the
+// participant is interrupted or stunned first.
+type Channel struct {
+	Ability  common.Ability
+	TargetID string
+	Turns    int // turns left until the ability resolves
+}
+
+// ComboChain is the sequence of abilities a side used in a round. Each
+// ability whose ComboAfter names the last one adds a link to the chain.
+type ComboChain struct {
+	Round int
+	Last  string // ability used last
+	Links int
+}
+
+// reaction returns the ability a participant reacts to a condition with, or
+// nil if it has none ready
+func (b *Battle) reaction(p *Participant, condition string) *common.Ability {
+	if !p.IsActive {
+		return nil
+	}
+	for i := range p.Abilities {
+		ability := &p.Abilities[i]
+		if ability.Reaction == condition && p.Cooldowns[ability.Name] == 0 && p.SteamPower >= ability.SteamCost {
+			return ability
+		}
+	}
+	return nil
+}
+
+// reaches reports whether a reaction of a participant reaches a target
+func (b *Battle) reaches(p, target *Participant, reaction *common.Ability) bool {
+	if !b.isInRange(p, target, reaction.Reach()) {
+		return false
+	}
+	return b.hasLineOfSight(p.Position, target.Position)
+}
+
+// react pays for a reaction and uses it on a target, marking the log entry
+// with the condition it reacted to
+func (b *Battle) react(p, target *Participant, reaction *common.Ability) {
+	b.spend(p, reaction)
+	b.strike(p, target, reaction)
+	entry := &b.CombatLog[len(b.CombatLog)-1]
+	entry.Effects = append(entry.Effects, reaction.Reaction)
+}
+
+// counter lets a participant that was hit strike back at the attacker
+func (b *Battle) counter(p, attacker *Participant) {
+	reaction := b.reaction(p, ReactionCounter)
+	if reaction == nil || !attacker.IsActive || b.side(p) == b.side(attacker) || !b.reaches(p, attacker, reaction) {
+		return
+	}
+	b.react(p, attacker, reaction)
+}
+
+// intercept returns the participant that takes a hit meant for the target:
+// the first of its allies with an intercept ready that reaches it, or the
+// target itself
+func (b *Battle) intercept(attacker, target *Participant) *Participant {
+	for _, p := range b.Participants {
+		if p == target || p == attacker || b.side(p) != b.side(target) {
+			continue
+		}
+		reaction := b.reaction(p, ReactionIntercept)
+		if reaction == nil || !b.reaches(p, target, reaction) {
+			continue
+		}
+
+		b.spend(p, reaction)
+		b.CombatLog = append(b.CombatLog, CombatLogEntry{
+			Round:     b.Round,
+			Turn:      b.CurrentTurn,
+			Character: p.Name,
+			Action:    reaction.Name,
+			Target:    target.Name,
+			Effects:   []string{ReactionIntercept},
+		})
+		return p
+	}
+	return target
+}
+
+// channel starts channelling an ability. Opponents with an interrupt ready
+// that reaches the attacker may stop it right away.
+func (b *Battle) channel(ability *common.Ability, attacker, target *Participant) {
+	if b.Channels == nil {
+		b.Channels = make(map[string]Channel)
+	}
+	b.Channels[attacker.ID] = Channel{Ability: *ability, TargetID: target.ID, Turns: ability.Channel}
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: attacker.Name,
+		Action:    ability.Name,
+		Target:    target.Name,
+		Effects:   []string{"channel"},
+	})
+
+	for _, p := range b.Opponents(attacker.ID) {
+		reaction := b.reaction(p, ReactionInterrupt)
+		if reaction == nil || !b.reaches(p, attacker, reaction) {
+			continue
+		}
+		b.react(p, attacker, reaction)
+		b.interrupt(attacker)
+		return
+	}
+}
+
+// interrupt stops the ability a participant is channelling
+func (b *Battle) interrupt(p *Participant) {
+	channel, exists := b.Channels[p.ID]
+	if !exists {
+		return
+	}
+	delete(b.Channels, p.ID)
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: p.Name,
+		Action:    channel.Ability.Name,
+		Effects:   []string{"interrupted"},
+	})
+}
+
+// completeChannel counts down the channel of a participant at the start of
+// its turn and resolves the ability once it is done. Stunned participants
+// lose their channel, and an ability whose target left or moved out of reach
+// fizzles.
+func (b *Battle) completeChannel(p *Participant, stunned bool) {
+	channel, exists := b.Channels[p.ID]
+	if !exists {
+		return
+	}
+	if stunned {
+		b.interrupt(p)
+		return
+	}
+	channel.Turns--
+	if channel.Turns > 0 {
+		b.Channels[p.ID] = channel
+		return
+	}
+	delete(b.Channels, p.ID)
+
+	ability := &channel.Ability
+	target := b.GetParticipant(channel.TargetID)
+	if target == nil || !target.IsActive || !b.isInRange(p, target, ability.Reach()) || !b.hasLineOfSight(p.Position, target.Position) {
+		b.CombatLog = append(b.CombatLog, CombatLogEntry{
+			Round:     b.Round,
+			Turn:      b.CurrentTurn,
+			Character: p.Name,
+			Action:    ability.Name,
+			Effects:   []string{"fizzled"},
+		})
+		return
+	}
+	b.perform(ability, p, target)
+	b.checkDefeated()
+	b.checkBossPhase()
+	b.checkBattleCompletion()
+}
+
+// combo adds an ability to its side's chain of the round and returns the
+// damage and healing multiplier it earns and the links of the chain. The
+// bonus grows with every link after the first.
+func (b *Battle) combo(ability *common.Ability, attacker *Participant) (float64, int) {
+	if b.Combos == nil {
+		b.Combos = make(map[string]ComboChain)
+	}
+	side := b.side(attacker)
+	chain := b.Combos[side]
+	if chain.Round != b.Round {
+		chain = ComboChain{Round: b.Round}
+	}
+	if ability.ComboAfter != "" && ability.ComboAfter == chain.Last {
+		chain.Links++
+	} else {
+		chain.Links = 1
+	}
+	chain.Last = ability.Name
+	b.Combos[side] = chain
+
+	if chain.Links < 2 {
+		return 1, chain.Links
+	}
+	return 1 + float64(ability.ComboBonus*(chain.Links-1))/100, chain.Links
+}
@@ -0,0 +1,189 @@
+package combat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// newTrio creates a PvP battle of first, second and third on the given teams,
+// acting in that order
+func newTrio(teams ...string) *Battle {
+	battle := NewBattle(BattleTypePvP)
+	for i, id := range []string{"first", "second", "third"} {
+		battle.AddPlayer(&character.Character{ID: id, Name: id, Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50})
+		battle.AddToTeam(id, teams[i])
+	}
+	return battle
+}
+
+// hasEffect reports whether a combat log entry lists an effect
+func hasEffect(entry CombatLogEntry, effect string) bool {
+	return contains(entry.Effects, effect)
+}
+
+func TestCounter(t *testing.T) {
+	battle, _, _ := newDuel()
+	second := battle.GetParticipant("second")
+	second.Abilities = append(second.Abilities, common.Ability{Name: "Riposte", Damage: 10, SteamCost: 10, Cooldown: 2, Reaction: ReactionCounter})
+
+	if _, err := battle.ExecuteAction(&common.Ability{Name: "Strike", Damage: 20}, "second"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	first := battle.GetParticipant("first")
+	if first.Health >= 100 {
+		t.Errorf("Expected second to strike back, got first at %d health", first.Health)
+	}
+	last := battle.CombatLog[len(battle.CombatLog)-1]
+	if last.Action != "Riposte" || last.Character != "Second" || !hasEffect(last, ReactionCounter) {
+		t.Errorf("Expected the counter in the log, got %+v", last)
+	}
+	// Second's turn has started since, regenerating 5 steam
+	if second.Cooldowns["Riposte"] == 0 || second.SteamPower != 45 {
+		t.Errorf("Expected the counter to cost steam and cool down, got %d steam and %d rounds", second.SteamPower, second.Cooldowns["Riposte"])
+	}
+
+	// A counter cooling down does not trigger
+	health := first.Health
+	battle.SkipTurn()
+	battle.ExecuteAction(&common.Ability{Name: "Strike", Damage: 20}, "second")
+	if first.Health != health {
+		t.Errorf("Expected no counter while it cools down, got first at %d health", first.Health)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	battle := newTrio("a", "b", "b")
+	third := battle.GetParticipant("third")
+	third.Abilities = append(third.Abilities, common.Ability{Name: "Bulwark", Range: 2, Reaction: ReactionIntercept})
+
+	if _, err := battle.ExecuteAction(&common.Ability{Name: "Strike", Damage: 20}, "second"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle.GetParticipant("second").Health != 100 || third.Health >= 100 {
+		t.Errorf("Expected third to take the hit for second, got %d and %d health", battle.GetParticipant("second").Health, third.Health)
+	}
+	intercept := battle.CombatLog[len(battle.CombatLog)-2]
+	if intercept.Character != "third" || intercept.Target != "second" || !hasEffect(intercept, ReactionIntercept) {
+		t.Errorf("Expected the intercept in the log, got %+v", intercept)
+	}
+	if hit := battle.CombatLog[len(battle.CombatLog)-1]; hit.Target != "third" {
+		t.Errorf("Expected the strike to hit third, got %+v", hit)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	battle, _, _ := newDuel()
+	second := battle.GetParticipant("second")
+	cannon := &common.Ability{Name: "Pressure Cannon", Damage: 30, Channel: 1}
+
+	if _, err := battle.ExecuteAction(cannon, "second"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second.Health != 100 {
+		t.Errorf("Expected the cannon to be channelled first, got second at %d health", second.Health)
+	}
+	if _, channelling := battle.Channels["first"]; !channelling {
+		t.Error("Expected first to be channelling")
+	}
+
+	battle.SkipTurn()
+	if second.Health >= 100 {
+		t.Error("Expected the cannon to fire at the start of first's turn")
+	}
+	if battle.ActiveParticipant().ID != "first" || len(battle.Channels) != 0 {
+		t.Errorf("Expected first to act after the cannon fired, got %s", battle.ActiveParticipant().ID)
+	}
+
+	// Stuns break a channel
+	battle.ExecuteAction(cannon, "second")
+	battle.ApplyEffect("first", StunEffect, "second")
+	health := second.Health
+	battle.SkipTurn()
+	if second.Health != health || len(battle.Channels) != 0 {
+		t.Errorf("Expected the stun to break the channel, got second at %d health", second.Health)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	battle, _, _ := newDuel()
+	second := battle.GetParticipant("second")
+	second.Abilities = append(second.Abilities, common.Ability{Name: "Gear Jammer", Damage: 5, Reaction: ReactionInterrupt})
+
+	if _, err := battle.ExecuteAction(&common.Ability{Name: "Pressure Cannon", Damage: 30, Channel: 1}, "second"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(battle.Channels) != 0 {
+		t.Error("Expected second to interrupt the channel")
+	}
+	if battle.GetParticipant("first").Health >= 100 {
+		t.Error("Expected the interrupt to hit first")
+	}
+	last := battle.CombatLog[len(battle.CombatLog)-1]
+	if last.Character != "First" || last.Action != "Pressure Cannon" || !hasEffect(last, "interrupted") {
+		t.Errorf("Expected the interrupted channel in the log, got %+v", last)
+	}
+
+	battle.SkipTurn()
+	if second.Health != 100 {
+		t.Errorf("Expected the interrupted cannon not to fire, got second at %d health", second.Health)
+	}
+}
+
+func TestComboChain(t *testing.T) {
+	acid := &common.Ability{Name: "Acid Splash", Damage: 10}
+	strike := &common.Ability{Name: "Strike", Damage: 20, ComboAfter: "Acid Splash", ComboBonus: 50}
+
+	plain := newTrio("a", "a", "b")
+	plain.SkipTurn()
+	base, _ := plain.ExecuteAction(strike, "third")
+
+	battle := newTrio("a", "a", "b")
+	battle.ExecuteAction(acid, "third")
+	damage, _ := battle.ExecuteAction(strike, "third")
+	if damage <= base {
+		t.Errorf("Expected the combo to deal more than %d damage, got %d", base, damage)
+	}
+	if last := battle.CombatLog[len(battle.CombatLog)-1]; !hasEffect(last, "combo x2") {
+		t.Errorf("Expected the combo in the log, got %+v", last)
+	}
+
+	// Chains do not carry over into the next round
+	battle.SkipTurn()
+	battle.ExecuteAction(acid, "third")
+	battle.SkipTurn()
+	battle.SkipTurn()
+	battle.ExecuteAction(strike, "third")
+	if last := battle.CombatLog[len(battle.CombatLog)-1]; hasEffect(last, "combo x2") {
+		t.Errorf("Expected the chain to end with the round, got %+v", last)
+	}
+}
+
+func TestReactionsNotUsedOnTurn(t *testing.T) {
+	battle, _, _ := newDuel()
+	riposte := &common.Ability{Name: "Riposte", Damage: 10, Reaction: ReactionCounter}
+	if _, err := battle.ExecuteAction(riposte, "second"); !errors.Is(err, ErrReactionAbility) {
+		t.Errorf("Expected ErrReactionAbility, got %v", err)
+	}
+}
+
+func TestReplayReactions(t *testing.T) {
+	useSeededRolls(t)
+	battle, _, _ := newDuel()
+	battle.SetSeed(3)
+	second := battle.GetParticipant("second")
+	second.Abilities = append(second.Abilities, common.Ability{Name: "Riposte", Damage: 10, Cooldown: 2, Reaction: ReactionCounter})
+
+	battle.ExecuteAction(&common.Ability{Name: "Strike", Damage: 10}, "second")
+	battle.ExecuteAction(&common.Ability{Name: "Strike", Damage: 10}, "first")
+	battle.ExecuteAction(&common.Ability{Name: "Pressure Cannon", Damage: 30, Channel: 1}, "second")
+	battle.SkipTurn()
+
+	replayed, err := battle.Record().Replay()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertSameBattle(t, battle, replayed)
+}
@@ -0,0 +1,243 @@
+package combat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// Replay event kinds
+const (
+	ReplayAction  = "action"
+	ReplayMove    = "move"
+	ReplayFlee    = "flee"
+	ReplayForfeit = "forfeit"
+	ReplaySkip    = "skip"
+	ReplayTimeout = "timeout"
+	ReplayJoin    = "join" // a participant joined mid-battle, e.g. a boss's add
+)
+
+// BattleRecord is the complete event stream of a battle. Replaying the events
+// on the initial state from the seed and rolls the battle's random number
+// generator had then gives the same outcome as the battle itself.
+type BattleRecord struct {
+	BattleID string
+	Seed     int64   // seed of the battle's random number generator
+	Rolls    int     // random numbers drawn before the first event
+	Initial  *Battle // state before the first event
+	Events   []ReplayEvent
+}
+
+// ReplayEvent is a command applied to a battle, in the order they happened
+type ReplayEvent struct {
+	Seq      int
+	Kind     string
+	Version  int    // battle version the event was applied to
+	ActorID  string // participant that acted, or that forfeited
+	Ability  *common.Ability
+	TargetID string
+	To       *common.Coordinates
+	Joined   *Participant // the participant that joined, for join events
+	At       time.Time
+}
+
+// recordHeader is the first line of a battle record in JSON Lines
+type recordHeader struct {
+	BattleID string
+	Seed     int64
+	Rolls    int
+	Initial  *Battle
+}
+
+// battleCombatant is a participant that lives in the battle only: an add that
+// joined a battle brought back in a replay, or a summon
+type battleCombatant struct {
+	participant Participant
+}
+
+// Participant returns a copy of the participant
+func (bc battleCombatant) Participant() *Participant {
+	p := bc.participant
+	return &p
+}
+
+// Refresh does nothing; the participant lives in the battle only
+func (bc battleCombatant) Refresh(p *Participant) {}
+
+// Apply does nothing; the participant lives in the battle only
+func (bc battleCombatant) Apply(p *Participant) {}
+
+// Reward does nothing; neither replays nor summons hand out rewards
+func (bc battleCombatant) Reward(experience int, money common.Currency, loot []common.Item) {}
+
+// Record returns the battle's event stream, or nil while nothing has happened
+// in the battle
+func (b *Battle) Record() *BattleRecord {
+	return b.record
+}
+
+// SetRecord restores the event stream of a battle loaded from storage
+func (b *Battle) SetRecord(record *BattleRecord) {
+	b.record = record
+}
+
+// recordEvent appends an event to the battle's record before it is applied.
+// The first event starts the record with a snapshot of the battle.
+func (b *Battle) recordEvent(event ReplayEvent) {
+	if b.replaying || b.scripted {
+		return
+	}
+	if b.record == nil {
+		b.refresh()
+		initial, err := copyBattle(b)
+		if err != nil {
+			return
+		}
+		b.record = &BattleRecord{BattleID: b.ID, Seed: b.Seed, Rolls: b.Rolls, Initial: initial}
+	}
+
+	if event.Ability != nil {
+		ability := *event.Ability
+		event.Ability = &ability
+	}
+	event.Seq = len(b.record.Events)
+	event.Version = b.Version
+	event.At = time.Now()
+	b.record.Events = append(b.record.Events, event)
+}
+
+// recordJoin records a participant joining the battle after it started
+func (b *Battle) recordJoin(p *Participant) {
+	if b.record == nil || b.replaying {
+		return
+	}
+	var joined Participant
+	if err := clone(p, &joined); err != nil {
+		return
+	}
+	b.recordEvent(ReplayEvent{Kind: ReplayJoin, ActorID: p.ID, Joined: &joined})
+}
+
+// Replay plays the recorded events back on a copy of the initial state and
+// returns the resulting battle. Rewards are not handed out again.
+func (r *BattleRecord) Replay() (*Battle, error) {
+	if r.Initial == nil {
+		return nil, fmt.Errorf("battle record %s has no initial state", r.BattleID)
+	}
+	b, err := copyBattle(r.Initial)
+	if err != nil {
+		return nil, err
+	}
+	b.Seed, b.Rolls = r.Seed, r.Rolls
+	b.replaying = true
+	b.joins = make(map[string]Participant)
+	for _, event := range r.Events {
+		var joined Participant
+		if event.Kind == ReplayJoin && event.Joined != nil && clone(event.Joined, &joined) == nil {
+			b.joins[joined.ID] = joined
+		}
+	}
+
+	for _, event := range r.Events {
+		if event.Kind == ReplayJoin {
+			continue
+		}
+		active := b.ActiveParticipant()
+		if b.Version != event.Version || active == nil || (event.Kind != ReplayForfeit && active.ID != event.ActorID) {
+			return b, fmt.Errorf("%w at event %d", ErrReplayDiverged, event.Seq)
+		}
+		if err := b.applyEvent(event); err != nil {
+			return b, fmt.Errorf("failed to replay event %d: %v", event.Seq, err)
+		}
+	}
+	return b, nil
+}
+
+// applyEvent performs a recorded event on the battle
+func (b *Battle) applyEvent(event ReplayEvent) error {
+	switch event.Kind {
+	case ReplayAction:
+		if event.Ability == nil {
+			return ErrInvalidAction
+		}
+		_, err := b.ExecuteAction(event.Ability, event.TargetID)
+		return err
+	case ReplayMove:
+		if event.To == nil {
+			return ErrInvalidMove
+		}
+		return b.Move(*event.To)
+	case ReplayFlee:
+		_, err := b.Flee()
+		return err
+	case ReplayForfeit:
+		return b.Forfeit(event.ActorID)
+	case ReplaySkip:
+		return b.SkipTurn()
+	case ReplayTimeout:
+		return b.TimeoutTurn()
+	default:
+		return fmt.Errorf("unknown replay event %q", event.Kind)
+	}
+}
+
+// WriteJSONL writes the record as JSON Lines: the battle ID and initial state
+// on the first line, followed by one line per event
+func (r *BattleRecord) WriteJSONL(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(recordHeader{BattleID: r.BattleID, Seed: r.Seed, Rolls: r.Rolls, Initial: r.Initial}); err != nil {
+		return err
+	}
+	for _, event := range r.Events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadJSONL reads a record written by WriteJSONL
+func ReadJSONL(rd io.Reader) (*BattleRecord, error) {
+	decoder := json.NewDecoder(rd)
+	var header recordHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to read battle record header: %v", err)
+	}
+	if header.Initial != nil {
+		header.Initial.Relink()
+	}
+
+	record := &BattleRecord{BattleID: header.BattleID, Seed: header.Seed, Rolls: header.Rolls, Initial: header.Initial}
+	for decoder.More() {
+		var event ReplayEvent
+		if err := decoder.Decode(&event); err != nil {
+			return nil, fmt.Errorf("failed to read battle record event %d: %v", len(record.Events), err)
+		}
+		record.Events = append(record.Events, event)
+	}
+	return record, nil
+}
+
+// copyBattle makes an independent copy of a battle's state without its
+// combatants, record and random number generator
+func copyBattle(b *Battle) (*Battle, error) {
+	var copied Battle
+	if err := clone(b, &copied); err != nil {
+		return nil, fmt.Errorf("failed to copy battle: %v", err)
+	}
+	copied.Seed, copied.Rolls = b.Seed, b.Rolls
+	copied.Relink()
+	return &copied, nil
+}
+
+// clone deep copies the exported state of src into dst
+func clone(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,163 @@
+package combat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// playRecordedDuel plays a seeded duel on a grid with moves, a skipped turn
+// and attacks, and returns the battle
+func playRecordedDuel(t *testing.T) *Battle {
+	battle, _, _ := newGridDuel()
+	battle.SetSeed(42)
+	strike := &common.Ability{Name: "Strike", Damage: 15, Range: battleGridWidth}
+
+	for i := 0; i < 10 && !battle.IsOver(); i++ {
+		p := battle.ActiveParticipant()
+		switch i {
+		case 1:
+			to := common.Coordinates{X: p.Position.X, Y: p.Position.Y + 1}
+			if !battle.passable(to) {
+				to.Y -= 2
+			}
+			if err := battle.Move(to); err != nil {
+				t.Fatalf("Unexpected error moving: %v", err)
+			}
+		case 3:
+			battle.SkipTurn()
+			continue
+		}
+		if _, err := battle.ExecuteAction(strike, battle.Opponents(p.ID)[0].ID); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	return battle
+}
+
+// assertSameBattle reports differences between a battle and its replay
+func assertSameBattle(t *testing.T, original, replayed *Battle) {
+	t.Helper()
+	if !reflect.DeepEqual(original.CombatLog, replayed.CombatLog) {
+		t.Errorf("Expected identical combat logs, got %+v and %+v", original.CombatLog, replayed.CombatLog)
+	}
+	if original.Rolls != replayed.Rolls || original.Version != replayed.Version {
+		t.Errorf("Expected %d rolls at version %d, got %d at version %d", original.Rolls, original.Version, replayed.Rolls, replayed.Version)
+	}
+	for _, p := range original.Participants {
+		r := replayed.GetParticipant(p.ID)
+		if r == nil {
+			t.Errorf("Expected %s in the replay", p.ID)
+			continue
+		}
+		if r.Health != p.Health || r.Position != p.Position {
+			t.Errorf("Expected %s at %d health on %v, got %d on %v", p.ID, p.Health, p.Position, r.Health, r.Position)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	useSeededRolls(t)
+
+	if NewBattle(BattleTypePvP).Record() != nil {
+		t.Error("Expected no record before anything happened")
+	}
+
+	battle := playRecordedDuel(t)
+	record := battle.Record()
+	if record == nil || record.Seed != 42 || record.Rolls != 0 {
+		t.Fatalf("Expected a record starting from the seed, got %+v", record)
+	}
+	if data, _ := json.Marshal(battle); bytes.Contains(data, []byte("Seed")) || bytes.Contains(data, []byte("Rolls")) {
+		t.Error("Expected the battle's random number state to stay out of its JSON")
+	}
+	kinds := map[string]int{}
+	for i, event := range record.Events {
+		if event.Seq != i {
+			t.Errorf("Expected event %d to have sequence number %d, got %d", i, i, event.Seq)
+		}
+		kinds[event.Kind]++
+	}
+	if kinds[ReplayMove] != 1 || kinds[ReplaySkip] != 1 || kinds[ReplayAction] == 0 {
+		t.Errorf("Expected a move, a skip and actions, got %v", kinds)
+	}
+
+	replayed, err := record.Replay()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertSameBattle(t, battle, replayed)
+	if replayed.Record() != nil {
+		t.Error("Expected a replay not to be recorded again")
+	}
+}
+
+func TestReplayJSONL(t *testing.T) {
+	useSeededRolls(t)
+	battle := playRecordedDuel(t)
+
+	var buf bytes.Buffer
+	if err := battle.Record().WriteJSONL(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Count(buf.String(), "\n")
+	if lines != len(battle.Record().Events)+1 {
+		t.Errorf("Expected a header and %d event lines, got %d lines", len(battle.Record().Events), lines)
+	}
+
+	record, err := ReadJSONL(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	replayed, err := record.Replay()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertSameBattle(t, battle, replayed)
+}
+
+func TestReplayDiverged(t *testing.T) {
+	useSeededRolls(t)
+	record := playRecordedDuel(t).Record()
+
+	record.Events[2].Version += 10
+	if _, err := record.Replay(); !errors.Is(err, ErrReplayDiverged) {
+		t.Errorf("Expected ErrReplayDiverged, got %v", err)
+	}
+}
+
+func TestReplayRaidAdds(t *testing.T) {
+	def := &BossDefinition{
+		ID:     "dummy",
+		Name:   "Dummy",
+		Type:   "mechanical",
+		Level:  1,
+		Health: 100,
+		Phases: []BossPhase{
+			{Name: "Calm", HealthPercent: 100},
+			{Name: "Angry", HealthPercent: 50, Adds: []BossAdd{{Name: "Cog", Type: "mechanical", Level: 1, Count: 2}}},
+		},
+	}
+	battle, boss := newRaid(def)
+	boss.Combatant().(*MobCombatant).Mob.Health = 52
+	if _, err := battle.ExecuteAction(&common.Ability{Name: "Strike", Damage: 10}, boss.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := battle.ExecuteAction(&common.Ability{Name: "Strike", Damage: 10}, boss.ID+"_add_1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	replayed, err := battle.Record().Replay()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(replayed.Participants) != len(battle.Participants) {
+		t.Errorf("Expected %d participants, got %d", len(battle.Participants), len(replayed.Participants))
+	}
+	assertSameBattle(t, battle, replayed)
+}
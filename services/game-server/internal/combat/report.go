@@ -0,0 +1,167 @@
+package combat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// BattleReport summarizes a finished or ongoing battle
+type BattleReport struct {
+	BattleID     string
+	Type         BattleType
+	State        string
+	Rounds       int
+	Duration     time.Duration // from the start of the battle to its last event
+	Winners      []string
+	Participants []ParticipantReport
+	Timeline     []CombatLogEntry
+}
+
+// ParticipantReport is what one participant did in a battle
+type ParticipantReport struct {
+	ID              string
+	Name            string
+	Type            string
+	Team            string
+	Survived        bool
+	Fled            bool
+	DamageDealt     int
+	DamageTaken     int
+	HealingDone     int
+	HealingReceived int
+	CriticalHits    int
+	Misses          int
+	Kills           int
+	Deaths          int
+	AbilitiesUsed   map[string]int
+}
+
+// Report replays the record and summarizes the battle
+func (r *BattleRecord) Report() (*BattleReport, error) {
+	b, err := r.Replay()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BattleReport{
+		BattleID: b.ID,
+		Type:     b.Type,
+		State:    b.State,
+		Rounds:   b.Round,
+		Winners:  b.Winners,
+		Timeline: b.CombatLog,
+	}
+	if len(r.Events) > 0 {
+		report.Duration = r.Events[len(r.Events)-1].At.Sub(r.Initial.StartedAt)
+	}
+	for _, p := range b.Participants {
+		stats := b.stats(p.ID)
+		report.Participants = append(report.Participants, ParticipantReport{
+			ID:              p.ID,
+			Name:            p.Name,
+			Type:            p.Type,
+			Team:            p.Team,
+			Survived:        p.IsActive,
+			Fled:            p.Fled,
+			DamageDealt:     stats.TotalDamageDealt,
+			DamageTaken:     stats.TotalDamageTaken,
+			HealingDone:     stats.TotalHealingDone,
+			HealingReceived: stats.TotalHealingReceived,
+			CriticalHits:    stats.CriticalHits,
+			Misses:          stats.Misses,
+			Kills:           stats.Kills,
+			Deaths:          stats.Deaths,
+			AbilitiesUsed:   stats.AbilitiesUsed,
+		})
+	}
+	return report, nil
+}
+
+// WriteTranscript writes a human-readable account of the battle: its
+// participants, every combat log entry round by round and a summary
+func (r *BattleReport) WriteTranscript(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Battle %s (%s, %s)\n\n", r.BattleID, r.Type, r.State)
+
+	sb.WriteString("Participants:\n")
+	names := make(map[string]string)
+	for _, p := range r.Participants {
+		names[p.ID] = p.Name
+		fmt.Fprintf(&sb, "  %s (%s", p.Name, p.Type)
+		if p.Team != "" {
+			fmt.Fprintf(&sb, ", %s", p.Team)
+		}
+		sb.WriteString(")\n")
+	}
+
+	round := 0
+	for _, entry := range r.Timeline {
+		if entry.Round != round {
+			round = entry.Round
+			fmt.Fprintf(&sb, "\nRound %d\n", round)
+		}
+		fmt.Fprintf(&sb, "  %s\n", formatLogEntry(entry))
+	}
+
+	winners := make([]string, 0, len(r.Winners))
+	for _, id := range r.Winners {
+		winners = append(winners, names[id])
+	}
+	if len(winners) > 0 {
+		fmt.Fprintf(&sb, "\nWinners: %s\n", strings.Join(winners, ", "))
+	}
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return err
+	}
+
+	io.WriteString(w, "\nSummary:\n")
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "  Name\tDamage dealt\tDamage taken\tHealing done\tKills\tDeaths\tAbilities used")
+	for _, p := range r.Participants {
+		fmt.Fprintf(table, "  %s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			p.Name, p.DamageDealt, p.DamageTaken, p.HealingDone, p.Kills, p.Deaths, formatAbilities(p.AbilitiesUsed))
+	}
+	return table.Flush()
+}
+
+// formatLogEntry describes a combat log entry in one line, e.g.
+// "First: Wrench Strike on Second, 12 damage (critical)"
+func formatLogEntry(entry CombatLogEntry) string {
+	line := entry.Character + ": " + entry.Action
+	if entry.Target != "" {
+		line += " on " + entry.Target
+	}
+	if entry.Damage > 0 {
+		line += fmt.Sprintf(", %d damage", entry.Damage)
+	}
+	if entry.Healing > 0 {
+		line += fmt.Sprintf(", %d healing", entry.Healing)
+	}
+	if len(entry.Effects) > 0 {
+		line += " (" + strings.Join(entry.Effects, ", ") + ")"
+	}
+	return line
+}
+
+// formatAbilities lists abilities by name with how often they were used, e.g.
+// "Steam Blast x2, Wrench Strike x1"
+func formatAbilities(abilities map[string]int) string {
+	if len(abilities) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(abilities))
+	for name := range abilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	uses := make([]string, len(names))
+	for i, name := range names {
+		uses[i] = fmt.Sprintf("%s x%d", name, abilities[name])
+	}
+	return strings.Join(uses, ", ")
+}
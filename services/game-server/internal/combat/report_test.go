@@ -0,0 +1,60 @@
+package combat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+func TestBattleReport(t *testing.T) {
+	battle, _, _ := newDuel()
+	strike := &common.Ability{Name: "Strike", Damage: 30}
+	for i := 0; i < 20 && !battle.IsOver(); i++ {
+		target := battle.Opponents(battle.ActiveParticipant().ID)[0]
+		battle.ExecuteAction(strike, target.ID)
+	}
+	if !battle.IsOver() {
+		t.Fatal("Expected the duel to end")
+	}
+
+	report, err := battle.Record().Report()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.BattleID != battle.ID || report.Rounds != battle.Round || len(report.Timeline) != len(battle.CombatLog) {
+		t.Errorf("Expected the report to cover %d rounds and %d log entries, got %d and %d", battle.Round, len(battle.CombatLog), report.Rounds, len(report.Timeline))
+	}
+	if len(report.Winners) != 1 || report.Winners[0] != battle.Winners[0] {
+		t.Errorf("Expected winners %v, got %v", battle.Winners, report.Winners)
+	}
+	for _, p := range report.Participants {
+		stats := battle.Stats[p.ID]
+		if p.DamageDealt != stats.TotalDamageDealt || p.DamageTaken != stats.TotalDamageTaken {
+			t.Errorf("Expected %s to deal %d and take %d damage, got %d and %d", p.ID, stats.TotalDamageDealt, stats.TotalDamageTaken, p.DamageDealt, p.DamageTaken)
+		}
+		if p.AbilitiesUsed["Strike"] != stats.AbilitiesUsed["Strike"] {
+			t.Errorf("Expected %s to use Strike %d times, got %d", p.ID, stats.AbilitiesUsed["Strike"], p.AbilitiesUsed["Strike"])
+		}
+		if p.Survived != (p.ID == battle.Winners[0]) {
+			t.Errorf("Expected only the winner to survive, got %s survived=%v", p.ID, p.Survived)
+		}
+	}
+
+	var transcript strings.Builder
+	if err := report.WriteTranscript(&transcript); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	text := transcript.String()
+	for _, want := range []string{
+		"Round 1\n",
+		"First: Strike on Second, ",
+		"Second: Strike on First, ",
+		"Winners: ",
+		"Strike x",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected transcript to contain %q, got:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package combat
+
+import "math/rand"
+
+// Attack resolution tuning, in percent
+const (
+	baseHitChance  = 90
+	minHitChance   = 60
+	maxDodgeChance = 40
+	maxCritChance  = 40
+	damageVariance = 10
+	baseFleeChance = 40
+	minFleeChance  = 10
+	maxFleeChance  = 90
+)
+
+// critMultiplier scales the damage of critical hits
+const critMultiplier = 1.5
+
+// Attack outcomes
+const (
+	OutcomeHit      = "hit"
+	OutcomeMiss     = "missed"
+	OutcomeDodge    = "dodged"
+	OutcomeCritical = "critical"
+	// OutcomeFleeFailed marks a failed attempt to flee in the combat log
+	OutcomeFleeFailed = "failed"
+)
+
+// roller produces the random numbers of a battle
+type roller interface {
+	Intn(n int) int
+}
+
+// seededRoller draws exactly one value from its source per roll, so a battle
+// can be restored by replaying the number of rolls made
+type seededRoller struct {
+	source rand.Source
+}
+
+func newSeededRoller(seed int64) roller {
+	return &seededRoller{source: rand.NewSource(seed)}
+}
+
+// Intn returns a number in [0, n)
+func (r *seededRoller) Intn(n int) int {
+	return int(r.source.Int63() % int64(n))
+}
+
+// newRoller creates the random number generator of a battle from its seed
+var newRoller = newSeededRoller
+
+// SetSeed restarts the battle's random number generator from a seed, so that
+// the same actions lead to the same outcomes
+func (b *Battle) SetSeed(seed int64) {
+	b.Seed = seed
+	b.Rolls = 0
+	b.rng = nil
+}
+
+// roll returns a random number in [0, n) from the battle's generator. A
+// generator lost in a save and load is recreated from the seed and fast
+// forwarded past the rolls already made.
+func (b *Battle) roll(n int) int {
+	if b.rng == nil {
+		b.rng = newRoller(b.Seed)
+		for i := 0; i < b.Rolls; i++ {
+			b.rng.Intn(1)
+		}
+	}
+	b.Rolls++
+	return b.rng.Intn(n)
+}
+
+// resolveAttack decides whether an attack hits, misses, is dodged or is a
+// critical hit, and returns the outcome with the damage after variance
+func (b *Battle) resolveAttack(attacker, target *Participant, damage int) (string, int) {
+	levelDiff := attacker.Level - target.Level
+	precision := b.attribute(attacker, StatMechanicalPrecision)
+	attackerDex := b.attribute(attacker, StatDexterity)
+	targetDex := b.attribute(target, StatDexterity)
+
+	hitChance := clamp(baseHitChance+(precision-targetDex)/2+2*levelDiff, minHitChance, 100)
+	if b.roll(100) >= hitChance {
+		return OutcomeMiss, 0
+	}
+
+	dodgeChance := clamp((targetDex-attackerDex)/2-levelDiff, 0, maxDodgeChance)
+	for _, effect := range b.StatusEffects[target.ID] {
+		if effect.Type == EffectDodge {
+			dodgeChance += effect.Value * max(1, effect.Stacks)
+		}
+	}
+	if b.roll(100) < dodgeChance {
+		return OutcomeDodge, 0
+	}
+
+	outcome := OutcomeHit
+	critChance := clamp(precision/4+levelDiff, 0, maxCritChance)
+	if b.roll(100) < critChance {
+		outcome = OutcomeCritical
+		damage = scale(damage, critMultiplier)
+	}
+
+	variance := 100 - damageVariance + b.roll(2*damageVariance+1)
+	return outcome, max(1, scale(damage, float64(variance)/100))
+}
+
+// fleeChance returns the chance that a participant escapes the battle away
+// from the edge of the grid: 40% plus half its Dexterity advantage over its
+// fastest opponent, between 10% and 90%
+func (b *Battle) fleeChance(p *Participant) int {
+	fastest := 0
+	for _, opponent := range b.Opponents(p.ID) {
+		fastest = max(fastest, b.attribute(opponent, StatDexterity))
+	}
+	return clamp(baseFleeChance+(b.attribute(p, StatDexterity)-fastest)/2, minFleeChance, maxFleeChance)
+}
+
+// clamp limits v to [lo, hi]
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,131 @@
+package combat
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// scriptedRoller returns the given rolls in order
+type scriptedRoller struct {
+	rolls []int
+}
+
+func (r *scriptedRoller) Intn(n int) int {
+	roll := r.rolls[0]
+	r.rolls = r.rolls[1:]
+	return roll
+}
+
+func TestAttackOutcomes(t *testing.T) {
+	strike := &common.Ability{Name: "Strike", Damage: 20}
+
+	tests := []struct {
+		name    string
+		rolls   []int
+		outcome string
+		damage  int
+	}{
+		{"miss", []int{95}, OutcomeMiss, 0},
+		{"dodge", []int{0, 5}, OutcomeDodge, 0},
+		{"critical", []int{0, 99, 0, 10}, OutcomeCritical, 30},
+		{"low variance", []int{0, 99, 99, 0}, OutcomeHit, 18},
+		{"high variance", []int{0, 99, 99, 20}, OutcomeHit, 22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			battle, _, second := newDuel()
+			battle.GetParticipant("first").Attributes.MechanicalPrecision.Value = 20
+			battle.GetParticipant("second").Attributes.Dexterity.Value = 20
+			battle.rng = &scriptedRoller{rolls: tt.rolls}
+
+			damage, err := battle.ExecuteAction(strike, second.ID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if damage != tt.damage {
+				t.Errorf("Expected damage %d, got %d", tt.damage, damage)
+			}
+			if second.Health != 100-tt.damage {
+				t.Errorf("Expected health %d, got %d", 100-tt.damage, second.Health)
+			}
+
+			entry := battle.CombatLog[len(battle.CombatLog)-1]
+			if tt.outcome == OutcomeHit {
+				if len(entry.Effects) != 0 {
+					t.Errorf("Expected plain hit, got %v", entry.Effects)
+				}
+			} else if len(entry.Effects) == 0 || entry.Effects[len(entry.Effects)-1] != tt.outcome {
+				t.Errorf("Expected %s in combat log, got %v", tt.outcome, entry.Effects)
+			}
+		})
+	}
+}
+
+func TestHitChances(t *testing.T) {
+	battle, first, second := newDuel()
+	attacker, target := battle.GetParticipant(first.ID), battle.GetParticipant(second.ID)
+
+	// A clumsy low level attacker still hits at the minimum chance
+	target.Attributes.Dexterity.Value = 100
+	target.Level = 10
+	battle.rng = &scriptedRoller{rolls: []int{minHitChance - 1, 99, 99, 10}}
+	if outcome, _ := battle.resolveAttack(attacker, target, 10); outcome != OutcomeHit {
+		t.Errorf("Expected hit at roll %d, got %s", minHitChance-1, outcome)
+	}
+	battle.rng = &scriptedRoller{rolls: []int{minHitChance}}
+	if outcome, _ := battle.resolveAttack(attacker, target, 10); outcome != OutcomeMiss {
+		t.Errorf("Expected miss at roll %d, got %s", minHitChance, outcome)
+	}
+}
+
+// runDuel plays a few rounds of a seeded duel and returns the battle
+func runDuel(seed int64, restoreAfter int) *Battle {
+	battle, _, _ := newDuel()
+	battle.SetSeed(seed)
+	strike := &common.Ability{Name: "Strike", Damage: 10}
+
+	for i := 0; i < 6 && !battle.IsOver(); i++ {
+		if i == restoreAfter {
+			// Simulate a save and load, which loses the generator but
+			// keeps the seed and rolls alongside the battle
+			data, _ := json.Marshal(battle)
+			restored := &Battle{}
+			json.Unmarshal(data, restored)
+			restored.Seed, restored.Rolls = battle.Seed, battle.Rolls
+			restored.Relink()
+			battle = restored
+		}
+		target := battle.Opponents(battle.ActiveParticipant().ID)[0]
+		battle.ExecuteAction(strike, target.ID)
+	}
+	return battle
+}
+
+// useSeededRolls makes new battles roll from their seed for the rest of the test
+func useSeededRolls(t *testing.T) {
+	steady := newRoller
+	newRoller = newSeededRoller
+	t.Cleanup(func() { newRoller = steady })
+}
+
+func TestSeededBattlesReplay(t *testing.T) {
+	useSeededRolls(t)
+
+	original := runDuel(42, -1)
+	replayed := runDuel(42, -1)
+	if !reflect.DeepEqual(original.CombatLog, replayed.CombatLog) {
+		t.Errorf("Expected identical combat logs, got %+v and %+v", original.CombatLog, replayed.CombatLog)
+	}
+
+	restored := runDuel(42, 3)
+	if !reflect.DeepEqual(original.CombatLog, restored.CombatLog) {
+		t.Errorf("Expected restored battle to continue the same rolls, got %+v and %+v", original.CombatLog, restored.CombatLog)
+	}
+	if restored.Rolls != original.Rolls {
+		t.Errorf("Expected %d rolls, got %d", original.Rolls, restored.Rolls)
+	}
+}
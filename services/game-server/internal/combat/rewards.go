@@ -0,0 +1,203 @@
+package combat
+
+import (
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+const (
+	// pvpVictoryExperience is the experience each PvP winner receives
+	pvpVictoryExperience = 200
+	// levelExperienceStep is the percentage of experience gained or lost per
+	// level a mob is above or below the character
+	levelExperienceStep = 10
+	// minLevelExperience and maxLevelExperience bound the level scaling, in percent
+	minLevelExperience = 10
+	maxLevelExperience = 150
+)
+
+// distributeRewards ends the battle and rewards the winning side. PvP winners
+// receive a fixed amount of experience. Otherwise every defeated mob's
+// experience is shared between the winning characters by contribution and
+// scaled by level difference, its money is split evenly and its loot is
+// rolled and handed to random winners.
+func (b *Battle) distributeRewards() {
+	b.EndedAt = time.Now()
+
+	var players []*Participant
+	for _, p := range b.Participants {
+		if p.IsActive && p.OwnerID == "" {
+			b.Winners = append(b.Winners, p.ID)
+			if p.Type == "player" {
+				players = append(players, p)
+			}
+		}
+	}
+	b.recordResults()
+	if len(players) == 0 {
+		return
+	}
+
+	experience := make(map[string]int)
+	money := common.NewCurrency(0, 0, 0, 0)
+	loot := make(map[string][]common.Item)
+	if b.Type == BattleTypePvP {
+		for _, p := range players {
+			experience[p.ID] = pvpVictoryExperience
+		}
+	} else {
+		shares := b.contributionShares(players)
+		for _, m := range b.Participants {
+			if m.Type != "mob" || m.IsActive || m.Fled {
+				continue
+			}
+			for _, p := range players {
+				experience[p.ID] += int(float64(m.Experience) * shares[p.ID] * float64(levelScale(m.Level-p.Level)) / 100)
+			}
+			money.Add(m.Money)
+			for _, item := range b.rollLoot(m.LootTable) {
+				winner := players[b.roll(len(players))]
+				loot[winner.ID] = append(loot[winner.ID], item)
+			}
+		}
+	}
+
+	moneyShare := splitCurrency(money, len(players))
+	for _, p := range players {
+		p.Experience += experience[p.ID]
+		p.Money.Add(moneyShare)
+		p.Loot = append(p.Loot, loot[p.ID]...)
+
+		stats := b.stats(p.ID)
+		stats.RecordExperience(experience[p.ID])
+		for _, item := range loot[p.ID] {
+			stats.RecordLoot(item.Name, 1)
+		}
+		if p.combatant != nil {
+			p.combatant.Reward(experience[p.ID], moneyShare, loot[p.ID])
+		}
+	}
+}
+
+// recordResults records the outcome and duration of the battle in every
+// character's statistics
+func (b *Battle) recordResults() {
+	for _, p := range b.Participants {
+		if p.Type != "player" {
+			continue
+		}
+		stats := b.stats(p.ID)
+		stats.RecordCombatDuration(b.EndedAt.Sub(b.StartedAt))
+		if p.IsActive {
+			stats.RecordVictory()
+		} else {
+			stats.RecordDefeat()
+		}
+	}
+}
+
+// contributionShares decides which part of the experience each winner
+// receives: half is split evenly and half by the damage and healing each
+// winner contributed
+func (b *Battle) contributionShares(players []*Participant) map[string]float64 {
+	contributions := make(map[string]int)
+	total := 0
+	for _, p := range players {
+		stats := b.stats(p.ID)
+		contributions[p.ID] = stats.TotalDamageDealt + stats.TotalHealingDone
+		total += contributions[p.ID]
+	}
+
+	shares := make(map[string]float64)
+	even := 1 / float64(len(players))
+	for _, p := range players {
+		if total == 0 {
+			shares[p.ID] = even
+			continue
+		}
+		shares[p.ID] = (even + float64(contributions[p.ID])/float64(total)) / 2
+	}
+	return shares
+}
+
+// levelScale returns the percentage of a mob's experience a character
+// receives when the mob is levelDiff levels above it
+func levelScale(levelDiff int) int {
+	return clamp(100+levelExperienceStep*levelDiff, minLevelExperience, maxLevelExperience)
+}
+
+// rollLoot rolls every entry of a loot table with the battle's random number
+// generator and returns the items that dropped
+func (b *Battle) rollLoot(table common.LootTable) []common.Item {
+	var items []common.Item
+	for _, entry := range table.Items {
+		if b.roll(100) >= int(entry.Chance*100) {
+			continue
+		}
+		for i := 0; i < max(1, entry.Quantity); i++ {
+			items = append(items, common.Item{ID: entry.ID, Name: entry.Name})
+		}
+	}
+	return items
+}
+
+// splitCurrency divides money evenly into n shares. What cannot be split in
+// a denomination is carried down into the next smaller one.
+func splitCurrency(money common.Currency, n int) common.Currency {
+	var share common.Currency
+	share.Platinum = money.Platinum / n
+	gold := money.Gold + money.Platinum%n*100
+	share.Gold = gold / n
+	silver := money.Silver + gold%n*100
+	share.Silver = silver / n
+	share.Copper = (money.Copper + silver%n*100) / n
+	return share
+}
+
+// stats returns a participant's statistics for this battle
+func (b *Battle) stats(id string) *CombatStats {
+	if b.Stats == nil {
+		b.Stats = make(map[string]*CombatStats)
+	}
+	stats, exists := b.Stats[id]
+	if !exists {
+		stats = NewCombatStats()
+		b.Stats[id] = stats
+	}
+	return stats
+}
+
+// recordDamage records damage dealt by a source to a target, and the kill if
+// the damage defeated the target. The source may be nil. What summons deal
+// counts for their owner.
+func (b *Battle) recordDamage(source, target *Participant, damage int) {
+	if damage <= 0 {
+		return
+	}
+	source = b.owner(source)
+	b.stats(target.ID).RecordDamageTaken(damage)
+	defeated := target.Health <= 0 && target.Health+damage > 0
+	if defeated {
+		b.stats(target.ID).RecordDeath()
+	}
+	if source != nil {
+		b.stats(source.ID).RecordDamageDealt(damage)
+		if defeated {
+			b.stats(source.ID).RecordKill()
+		}
+	}
+}
+
+// recordHealing records healing done by a source to a target. The source may
+// be nil. What summons heal counts for their owner.
+func (b *Battle) recordHealing(source, target *Participant, healing int) {
+	if healing <= 0 {
+		return
+	}
+	source = b.owner(source)
+	b.stats(target.ID).RecordHealingReceived(healing)
+	if source != nil {
+		b.stats(source.ID).RecordHealingDone(healing)
+	}
+}
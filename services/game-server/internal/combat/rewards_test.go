@@ -0,0 +1,156 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// newRewardBattle creates a PvE battle with two level 5 characters against a
+// mob worth 100 experience
+func newRewardBattle(mobLevel int) (*Battle, *character.Character, *character.Character, *mob.Mob) {
+	battle := NewBattle(BattleTypePvE)
+	first := &character.Character{ID: "first", Name: "First", Level: 5, Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	second := &character.Character{ID: "second", Name: "Second", Level: 5, Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	battle.AddPlayer(first)
+	battle.AddPlayer(second)
+
+	m := &mob.Mob{
+		ID:         "mob",
+		Name:       "Test Mob",
+		Type:       string(mob.Mechanical),
+		Level:      mobLevel,
+		Health:     50,
+		MaxHealth:  50,
+		Experience: 100,
+		MoneyDrop:  common.NewCurrency(0, 10, 0, 0),
+	}
+	battle.AddMob(m)
+	return battle, first, second, m
+}
+
+// defeat knocks out a participant and completes the battle if it is decided
+func defeat(battle *Battle, id string) {
+	battle.GetParticipant(id).Health = 0
+	battle.checkDefeated()
+	battle.checkBattleCompletion()
+}
+
+func TestLevelScale(t *testing.T) {
+	tests := []struct {
+		levelDiff int
+		expected  int
+	}{
+		{0, 100},
+		{2, 120},
+		{-3, 70},
+		{20, maxLevelExperience},
+		{-20, minLevelExperience},
+	}
+	for _, tt := range tests {
+		if scale := levelScale(tt.levelDiff); scale != tt.expected {
+			t.Errorf("Expected scale %d for level difference %d, got %d", tt.expected, tt.levelDiff, scale)
+		}
+	}
+}
+
+func TestContributionRewards(t *testing.T) {
+	battle, first, second, m := newRewardBattle(7)
+
+	// Only the first character deals damage, so it earns the larger share
+	battle.stats(first.ID).RecordDamageDealt(50)
+	defeat(battle, m.ID)
+
+	if !battle.IsOver() {
+		t.Fatalf("Expected battle to be over")
+	}
+	// 100 experience scaled to 120% for two levels, split 75% / 25%
+	if first.Experience != 90 {
+		t.Errorf("Expected first experience 90, got %d", first.Experience)
+	}
+	if second.Experience != 30 {
+		t.Errorf("Expected second experience 30, got %d", second.Experience)
+	}
+	if first.GetMoney().Silver != 5 || second.GetMoney().Silver != 5 {
+		t.Errorf("Expected 5 silver each, got %d and %d", first.GetMoney().Silver, second.GetMoney().Silver)
+	}
+
+	if stats := battle.Stats[first.ID]; stats.ExperienceGained != 90 || stats.Victories != 1 || stats.CombatCount != 1 {
+		t.Errorf("Expected stats to record 90 experience and one victory, got %+v", stats)
+	}
+}
+
+func TestLootDrops(t *testing.T) {
+	battle, first, second, m := newRewardBattle(5)
+	battle.GetParticipant(m.ID).LootTable = common.LootTable{Items: []common.LootItem{
+		{ID: "brass_gear", Name: "Brass Gear", Quantity: 2, Chance: 1.0},
+		{ID: "clockwork_heart", Name: "Clockwork Heart", Quantity: 1, Chance: 0},
+	}}
+
+	defeat(battle, m.ID)
+
+	items := append(append([]common.Item{}, first.Inventory...), second.Inventory...)
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items to drop, got %d", len(items))
+	}
+	for _, item := range items {
+		if item.ID != "brass_gear" {
+			t.Errorf("Expected only brass gears to drop, got %s", item.ID)
+		}
+	}
+	looted := len(battle.GetParticipant(first.ID).Loot) + len(battle.GetParticipant(second.ID).Loot)
+	if looted != 2 {
+		t.Errorf("Expected participants to record 2 looted items, got %d", looted)
+	}
+	collected := battle.Stats[first.ID].LootCollected["Brass Gear"] + battle.Stats[second.ID].LootCollected["Brass Gear"]
+	if collected != 2 {
+		t.Errorf("Expected stats to record 2 brass gears, got %d", collected)
+	}
+}
+
+func TestBattleStatsRecorded(t *testing.T) {
+	battle, first, _, m := newRewardBattle(5)
+	ability := &common.Ability{Name: "Test Attack", Type: "damage", Damage: 100, SteamCost: 10, Range: 2}
+
+	if _, err := battle.ExecuteAction(ability, m.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stats := battle.Stats[first.ID]
+	if stats.Kills != 1 {
+		t.Errorf("Expected 1 kill, got %d", stats.Kills)
+	}
+	if stats.TotalDamageDealt <= 0 {
+		t.Errorf("Expected damage dealt to be recorded, got %d", stats.TotalDamageDealt)
+	}
+	if stats.AbilitiesUsed["Test Attack"] != 1 {
+		t.Errorf("Expected ability use to be recorded, got %d", stats.AbilitiesUsed["Test Attack"])
+	}
+	if battle.Stats[m.ID].Deaths != 1 {
+		t.Errorf("Expected mob death to be recorded, got %d", battle.Stats[m.ID].Deaths)
+	}
+}
+
+func TestMergeCombatStats(t *testing.T) {
+	lifetime := NewCombatStats()
+	lifetime.RecordKill()
+	lifetime.RecordAbilityUse("Steam Blast")
+
+	battle := NewCombatStats()
+	battle.RecordKill()
+	battle.RecordAbilityUse("Steam Blast")
+	battle.RecordLoot("Brass Gear", 2)
+	lifetime.Merge(battle)
+
+	if lifetime.Kills != 2 {
+		t.Errorf("Expected 2 kills, got %d", lifetime.Kills)
+	}
+	if lifetime.AbilitiesUsed["Steam Blast"] != 2 {
+		t.Errorf("Expected 2 ability uses, got %d", lifetime.AbilitiesUsed["Steam Blast"])
+	}
+	if lifetime.LootCollected["Brass Gear"] != 2 {
+		t.Errorf("Expected 2 brass gears, got %d", lifetime.LootCollected["Brass Gear"])
+	}
+}
@@ -112,6 +112,42 @@ func (cs *CombatStats) RecordExperience(amount int) {
 	cs.ExperienceGained += amount
 }
 
+// Merge adds the statistics of other, such as a single battle, to these
+func (cs *CombatStats) Merge(other *CombatStats) {
+	if other == nil {
+		return
+	}
+	if cs.AbilitiesUsed == nil {
+		cs.AbilitiesUsed = make(map[string]int)
+	}
+	if cs.LootCollected == nil {
+		cs.LootCollected = make(map[string]int)
+	}
+	cs.TotalDamageDealt += other.TotalDamageDealt
+	cs.TotalDamageTaken += other.TotalDamageTaken
+	cs.TotalHealingDone += other.TotalHealingDone
+	cs.TotalHealingReceived += other.TotalHealingReceived
+	cs.TotalSteamPowerUsed += other.TotalSteamPowerUsed
+	for name, count := range other.AbilitiesUsed {
+		cs.AbilitiesUsed[name] += count
+	}
+	cs.CriticalHits += other.CriticalHits
+	cs.Misses += other.Misses
+	cs.Kills += other.Kills
+	cs.Deaths += other.Deaths
+	if other.LongestCombat > cs.LongestCombat {
+		cs.LongestCombat = other.LongestCombat
+	}
+	cs.TotalCombatTime += other.TotalCombatTime
+	cs.CombatCount += other.CombatCount
+	cs.Victories += other.Victories
+	cs.Defeats += other.Defeats
+	for name, quantity := range other.LootCollected {
+		cs.LootCollected[name] += quantity
+	}
+	cs.ExperienceGained += other.ExperienceGained
+}
+
 // GetAverageDamageDealt returns the average damage dealt per combat
 func (cs *CombatStats) GetAverageDamageDealt() float64 {
 	if cs.CombatCount == 0 {
@@ -0,0 +1,215 @@
+package combat
+
+// StatusEffect represents a temporary effect on a participant
+type StatusEffect struct {
+	Name        string
+	Type        string // buff, debuff, dot, hot, stun, dodge, shield
+	Stat        string // stat modified by buffs and debuffs
+	Duration    int    // turns of the affected participant left
+	Value       int    // damage or healing per turn, or stat modifier, per stack
+	Stacks      int
+	Stacking    StackingRule
+	MaxStacks   int
+	SourceID    string // participant that applied the effect
+	Description string
+}
+
+// ApplyEffect puts an effect on a participant, following the effect's stacking
+// rule if the participant already has it. It reports false if the participant
+// is immune or the effect was ignored.
+func (b *Battle) ApplyEffect(participantID string, effect *Effect, sourceID string) bool {
+	participant := b.GetParticipant(participantID)
+	if participant == nil {
+		return false
+	}
+	if participant.isImmune(effect) {
+		b.CombatLog = append(b.CombatLog, CombatLogEntry{
+			Round:     b.Round,
+			Turn:      b.CurrentTurn,
+			Character: b.participantName(sourceID),
+			Action:    effect.Name,
+			Target:    participant.Name,
+			Effects:   []string{"immune"},
+		})
+		return false
+	}
+
+	effects := b.StatusEffects[participantID]
+	for i := range effects {
+		if effects[i].Name != effect.Name {
+			continue
+		}
+		switch effect.Stacking {
+		case StackIgnore:
+			return false
+		case StackAdd:
+			if effect.MaxStacks == 0 || effects[i].Stacks < effect.MaxStacks {
+				effects[i].Stacks++
+			}
+		}
+		effects[i].Duration = effect.Duration
+		effects[i].Value = effect.Value
+		effects[i].SourceID = sourceID
+		return true
+	}
+
+	b.StatusEffects[participantID] = append(effects, effect.StatusEffect(sourceID))
+	return true
+}
+
+// Cleanse removes all harmful effects from a participant and returns them
+func (b *Battle) Cleanse(participantID string) []StatusEffect {
+	var kept, removed []StatusEffect
+	for _, effect := range b.StatusEffects[participantID] {
+		if effect.IsHarmful() {
+			removed = append(removed, effect)
+		} else {
+			kept = append(kept, effect)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	b.StatusEffects[participantID] = kept
+
+	names := make([]string, 0, len(removed))
+	for _, effect := range removed {
+		names = append(names, effect.Name)
+	}
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: b.participantName(participantID),
+		Action:    "Cleanse",
+		Target:    b.participantName(participantID),
+		Effects:   names,
+	})
+	return removed
+}
+
+// GetStatusEffects returns all status effects on a participant
+func (b *Battle) GetStatusEffects(participantID string) []StatusEffect {
+	return b.StatusEffects[participantID]
+}
+
+// IsHarmful reports whether the effect hurts the participant it is on
+func (e StatusEffect) IsHarmful() bool {
+	return e.Type == EffectDebuff || e.Type == EffectDoT || e.Type == EffectStun
+}
+
+// startTurn prepares the turn of a participant: movement is restored, steam
+// power regenerates, cooldowns count down and the effects on the participant tick. Damage and
+// healing over time are applied and logged, durations count down and expired
+// effects are removed. It reports whether the participant is stunned and
+// loses the turn.
+func (b *Battle) startTurn(p *Participant) bool {
+	p.MovementLeft = b.movement(p)
+	b.regenerateSteam(p)
+	for name, rounds := range p.Cooldowns {
+		if rounds <= 1 {
+			delete(p.Cooldowns, name)
+		} else {
+			p.Cooldowns[name] = rounds - 1
+		}
+	}
+
+	var stunned bool
+	var remaining []StatusEffect
+	for _, effect := range b.StatusEffects[p.ID] {
+		amount := effect.Value * max(1, effect.Stacks)
+		entry := CombatLogEntry{
+			Round:     b.Round,
+			Turn:      b.CurrentTurn,
+			Character: b.participantName(effect.SourceID),
+			Action:    effect.Name,
+			Target:    p.Name,
+			Effects:   []string{effect.Type},
+		}
+
+		switch {
+		case effect.Type == EffectDoT:
+			p.Health -= amount
+			entry.Damage = amount
+			b.addThreat(p, b.GetParticipant(effect.SourceID), amount)
+			b.recordDamage(b.GetParticipant(effect.SourceID), p, amount)
+		case effect.Type == EffectHoT:
+			healing := min(amount, p.MaxHealth-p.Health)
+			p.Health += healing
+			entry.Healing = healing
+			b.healingThreat(b.GetParticipant(effect.SourceID), healing)
+			b.recordHealing(b.GetParticipant(effect.SourceID), p, healing)
+		case effect.Type == EffectStun:
+			stunned = true
+		case effect.Stat == StatSteamRegen:
+			p.SteamPower = max(0, min(p.SteamPower+amount, p.MaxSteamPower))
+		}
+		if entry.Damage > 0 || entry.Healing > 0 {
+			b.CombatLog = append(b.CombatLog, entry)
+		}
+
+		effect.Duration--
+		if effect.Duration > 0 {
+			remaining = append(remaining, effect)
+		}
+	}
+	b.StatusEffects[p.ID] = remaining
+
+	if p.Health <= 0 {
+		p.Health = 0
+		p.IsActive = false
+	}
+	return stunned
+}
+
+// regenerateSteam restores the steam power a participant regains each turn,
+// which grows with its Steam Power attribute and the terrain's steam bonus
+func (b *Battle) regenerateSteam(p *Participant) {
+	regen := baseSteamRegen + b.attribute(p, StatSteamPower)/5 + b.SteamPowerBonus/5
+	if regen > 0 {
+		p.SteamPower = max(p.SteamPower, min(p.SteamPower+regen, p.MaxSteamPower))
+	}
+}
+
+// attribute returns a participant's attribute including buffs and debuffs
+func (b *Battle) attribute(p *Participant, stat string) int {
+	var value int
+	switch stat {
+	case StatStrength:
+		value = p.Attributes.Strength.Value
+	case StatDexterity:
+		value = p.Attributes.Dexterity.Value
+	case StatIntelligence:
+		value = p.Attributes.Intelligence.Value
+	case StatConstitution:
+		value = p.Attributes.Constitution.Value
+	case StatSteamPower:
+		value = p.Attributes.SteamPower.Value
+	case StatMechanicalPrecision:
+		value = p.Attributes.MechanicalPrecision.Value
+	}
+
+	for _, effect := range b.StatusEffects[p.ID] {
+		if effect.Stat == stat {
+			value += effect.Value * max(1, effect.Stacks)
+		}
+	}
+	return max(0, value)
+}
+
+// isImmune reports whether an effect cannot be applied to the participant
+func (p *Participant) isImmune(effect *Effect) bool {
+	for _, immunity := range p.Immunities {
+		if immunity == effect.Name || immunity == effect.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// participantName returns the name of a participant, or the ID if it is not in the battle
+func (b *Battle) participantName(id string) string {
+	if p := b.GetParticipant(id); p != nil {
+		return p.Name
+	}
+	return id
+}
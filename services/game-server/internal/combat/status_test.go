@@ -0,0 +1,154 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// newDuel creates a PvP battle between two identical players; "first" acts first
+func newDuel() (*Battle, *character.Character, *character.Character) {
+	battle := NewBattle(BattleTypePvP)
+	first := &character.Character{ID: "first", Name: "First", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	second := &character.Character{ID: "second", Name: "Second", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	battle.AddPlayer(first)
+	battle.AddPlayer(second)
+	return battle, first, second
+}
+
+func TestEffectStacking(t *testing.T) {
+	battle, _, _ := newDuel()
+
+	// Refresh resets the duration without adding stacks
+	burn := *SteamBurnEffect
+	battle.ApplyEffect("second", &burn, "first")
+	battle.StatusEffects["second"][0].Duration = 1
+	battle.ApplyEffect("second", &burn, "first")
+	effects := battle.GetStatusEffects("second")
+	if len(effects) != 1 || effects[0].Duration != 2 || effects[0].Stacks != 1 {
+		t.Errorf("Expected refreshed burn with 1 stack and duration 2, got %+v", effects)
+	}
+
+	// Stack adds stacks up to the limit
+	for i := 0; i < 5; i++ {
+		battle.ApplyEffect("second", PoisonEffect, "first")
+	}
+	for _, effect := range battle.GetStatusEffects("second") {
+		if effect.Name == "Poison" && effect.Stacks != PoisonEffect.MaxStacks {
+			t.Errorf("Expected %d poison stacks, got %d", PoisonEffect.MaxStacks, effect.Stacks)
+		}
+	}
+
+	// Ignore keeps the existing effect
+	battle.ApplyEffect("second", SteamWeaknessEffect, "first")
+	if battle.ApplyEffect("second", SteamWeaknessEffect, "first") {
+		t.Error("Expected second Steam Weakness to be ignored")
+	}
+}
+
+func TestDamageOverTimeTicks(t *testing.T) {
+	battle, _, second := newDuel()
+	battle.ApplyEffect("second", PoisonEffect, "first")
+	battle.ApplyEffect("second", PoisonEffect, "first")
+
+	if err := battle.SkipTurn(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Two stacks of 5 damage tick at the start of the poisoned participant's turn
+	if second.Health != 90 {
+		t.Errorf("Expected health 90, got %d", second.Health)
+	}
+	last := battle.CombatLog[len(battle.CombatLog)-1]
+	if last.Action != "Poison" || last.Damage != 10 || last.Character != "First" || last.Target != "Second" {
+		t.Errorf("Expected poison tick in combat log, got %+v", last)
+	}
+	if battle.GetStatusEffects("second")[0].Duration != 2 {
+		t.Errorf("Expected duration 2, got %d", battle.GetStatusEffects("second")[0].Duration)
+	}
+}
+
+func TestDamageOverTimeEndsBattle(t *testing.T) {
+	battle, first, second := newDuel()
+	second.Health = 3
+	battle.ApplyEffect("second", SteamBurnEffect, "first")
+
+	battle.SkipTurn()
+
+	if !battle.IsOver() {
+		t.Fatal("Expected battle to be over")
+	}
+	if len(battle.Winners) != 1 || battle.Winners[0] != first.ID {
+		t.Errorf("Expected first to win, got %v", battle.Winners)
+	}
+	if second.Health != 0 {
+		t.Errorf("Expected health 0, got %d", second.Health)
+	}
+}
+
+func TestHealingOverTimeIsCapped(t *testing.T) {
+	battle, _, second := newDuel()
+	second.Health = 95
+	battle.ApplyEffect("second", HealingOverTimeEffect, "second")
+
+	battle.SkipTurn()
+
+	if second.Health != 100 {
+		t.Errorf("Expected health 100, got %d", second.Health)
+	}
+	if last := battle.CombatLog[len(battle.CombatLog)-1]; last.Healing != 5 {
+		t.Errorf("Expected 5 healing logged, got %d", last.Healing)
+	}
+}
+
+func TestStatModifiers(t *testing.T) {
+	battle, _, second := newDuel()
+	battle.ApplyEffect("first", StrengthBuffEffect, "first")
+	battle.ApplyEffect("second", WeaknessEffect, "first")
+
+	ability := &common.Ability{Name: "Punch", Damage: 10}
+	damage, err := battle.ExecuteAction(ability, second.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if damage != 12 { // 10 + 5/2
+		t.Errorf("Expected damage 12, got %d", damage)
+	}
+
+	// A faster debuffed participant loses the initiative at the next round
+	battle.GetParticipant("first").Attributes.SteamPower.Value = 8
+	battle.GetParticipant("second").Attributes.Dexterity.Value = 5
+	battle.ApplyEffect("first", SteamWeaknessEffect, "second")
+	battle.SkipTurn()
+	if battle.TurnOrder[0].ID != "second" {
+		t.Errorf("Expected second to act first after the debuff, got %s", battle.TurnOrder[0].ID)
+	}
+}
+
+func TestImmunityAndCleanse(t *testing.T) {
+	battle := NewBattle(BattleTypePvE)
+	player := &character.Character{ID: "player", Name: "Player", Health: 100, MaxHealth: 100}
+	battle.AddPlayer(player)
+	battle.AddMob(&mob.Mob{ID: "golem", Name: "Golem", Type: string(mob.Mechanical), Health: 100, MaxHealth: 100})
+
+	if battle.ApplyEffect("golem", PoisonEffect, "player") {
+		t.Error("Expected mechanical mob to be immune to poison")
+	}
+	if last := battle.CombatLog[len(battle.CombatLog)-1]; len(last.Effects) != 1 || last.Effects[0] != "immune" {
+		t.Errorf("Expected immunity in combat log, got %+v", last)
+	}
+
+	battle.ApplyEffect("player", PoisonEffect, "golem")
+	battle.ApplyEffect("player", StrengthBuffEffect, "player")
+	cleanse := &common.Ability{Name: "Purge", Type: "cleanse"}
+	if _, err := battle.ExecuteAction(cleanse, "player"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	effects := battle.GetStatusEffects("player")
+	if len(effects) != 1 || effects[0].Name != "Strength Buff" {
+		t.Errorf("Expected only the buff to remain, got %+v", effects)
+	}
+}
@@ -0,0 +1,334 @@
+package combat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// Participant types of summons and deployables
+const (
+	ParticipantSummon     = "summon"     // a helper that moves and acts on its own
+	ParticipantDeployable = "deployable" // an object that occupies its tile and cannot move
+)
+
+// SummonDefinition describes a participant an ability brings into the battle
+// on its caster's side. Summons act on their own turn by their script until
+// their rounds run out or their owner leaves the fight.
+type SummonDefinition struct {
+	ID             string
+	Name           string
+	Type           string // ParticipantSummon or ParticipantDeployable
+	Health         int
+	HealthPerLevel int // health gained per level of the caster
+	Rounds         int // rounds the summon lasts
+	Script         string
+	Ability        common.Ability // used by the script
+	PerLevel       int            // damage or healing the ability gains per level of the caster
+	Immunities     []string
+}
+
+// SummonScript runs the turn of a summon or deployable
+type SummonScript func(b *Battle, summon *Participant)
+
+// summonScripts maps summon scripts to their behaviour
+var summonScripts = map[string]SummonScript{
+	"repair_allies": repairAllies,
+	"shoot_nearest": shootNearest,
+	"scald_around":  scaldAround,
+}
+
+// summons holds the known summon definitions by ID
+var summons = map[string]*SummonDefinition{
+	"repair_bot": {
+		ID:             "repair_bot",
+		Name:           "Repair Bot",
+		Type:           ParticipantSummon,
+		Health:         30,
+		HealthPerLevel: 4,
+		Rounds:         4,
+		Script:         "repair_allies",
+		Ability:        common.Ability{Name: "Repair", Type: "healing", Healing: 8, Range: 2},
+		PerLevel:       1,
+		Immunities:     []string{PoisonEffect.Name},
+	},
+	"steam_turret": {
+		ID:             "steam_turret",
+		Name:           "Steam Turret",
+		Type:           ParticipantDeployable,
+		Health:         40,
+		HealthPerLevel: 5,
+		Rounds:         3,
+		Script:         "shoot_nearest",
+		Ability:        common.Ability{Name: "Turret Shot", Type: "mechanical", Damage: 10, Range: 5},
+		PerLevel:       1,
+		Immunities:     []string{EffectStun, PoisonEffect.Name},
+	},
+	"steam_vent": {
+		ID:         "steam_vent",
+		Name:       "Steam Vent",
+		Type:       ParticipantDeployable,
+		Health:     25,
+		Rounds:     3,
+		Script:     "scald_around",
+		Ability:    common.Ability{Name: "Scald", Type: "damage", Damage: 8, Range: 1},
+		PerLevel:   1,
+		Immunities: []string{EffectStun, PoisonEffect.Name},
+	},
+}
+
+func init() {
+	// Abilities deploy the summon with the same ID as their Effect
+	for id := range summons {
+		RegisterAbilityEffect(id, AbilityEffect{Apply: deploy})
+	}
+}
+
+// RegisterSummonScript adds or replaces the behaviour of a summon script
+func RegisterSummonScript(id string, script SummonScript) {
+	summonScripts[id] = script
+}
+
+// RegisterSummon adds or replaces a summon definition. Abilities with the
+// definition's ID as their Effect deploy it.
+func RegisterSummon(def *SummonDefinition) error {
+	if def.ID == "" || def.Name == "" {
+		return fmt.Errorf("summon definition needs an ID and a name")
+	}
+	if def.Type != ParticipantSummon && def.Type != ParticipantDeployable {
+		return fmt.Errorf("summon %s has unknown type %q", def.ID, def.Type)
+	}
+	if def.Health <= 0 || def.Rounds <= 0 {
+		return fmt.Errorf("summon %s needs health and rounds", def.ID)
+	}
+	if _, exists := summonScripts[def.Script]; !exists {
+		return fmt.Errorf("summon %s has unknown script %q", def.ID, def.Script)
+	}
+	summons[def.ID] = def
+	RegisterAbilityEffect(def.ID, AbilityEffect{Apply: deploy})
+	return nil
+}
+
+// GetSummon returns the summon definition with the given ID
+func GetSummon(id string) (*SummonDefinition, bool) {
+	def, exists := summons[id]
+	return def, exists
+}
+
+// Summons returns the active summons and deployables of a participant
+func (b *Battle) Summons(ownerID string) []*Participant {
+	var owned []*Participant
+	for _, p := range b.Participants {
+		if p.IsActive && p.OwnerID == ownerID {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+// deploy brings the summon named by the ability's Effect into the battle next
+// to the caster, replacing the caster's previous one of the same kind
+func deploy(b *Battle, ability *common.Ability, attacker, target *Participant, damage int) {
+	def, exists := GetSummon(ability.Effect)
+	if !exists || attacker.OwnerID != "" {
+		return
+	}
+
+	prefix := fmt.Sprintf("%s_%s_", attacker.ID, def.ID)
+	for _, p := range b.Summons(attacker.ID) {
+		if strings.HasPrefix(p.ID, prefix) {
+			b.dismiss(p)
+		}
+	}
+	id := fmt.Sprintf("%s%d", prefix, len(b.Participants))
+
+	spell := def.Ability
+	if spell.Damage > 0 {
+		spell.Damage += def.PerLevel * attacker.Level
+	}
+	if spell.Healing > 0 {
+		spell.Healing += def.PerLevel * attacker.Level
+	}
+	health := def.Health + def.HealthPerLevel*attacker.Level
+	summoned := Participant{
+		ID:         id,
+		Name:       def.Name,
+		Type:       def.Type,
+		Level:      attacker.Level,
+		Team:       attacker.Team,
+		OwnerID:    attacker.ID,
+		Lifetime:   def.Rounds,
+		Script:     def.Script,
+		Health:     health,
+		MaxHealth:  health,
+		Attributes: attacker.Attributes,
+		Abilities:  []common.Ability{spell},
+		Immunities: append([]string(nil), def.Immunities...),
+		IsActive:   true,
+		Money:      common.NewCurrency(0, 0, 0, 0),
+	}
+
+	// The turn stays with the caster
+	active := b.ActiveParticipant()
+	position := b.deployTile(attacker, target)
+	p := b.AddCombatant(battleCombatant{participant: summoned})
+	p.Position = position
+	for i, other := range b.TurnOrder {
+		if other == active {
+			b.CurrentTurn = i
+		}
+	}
+
+	action := "Summon"
+	if def.Type == ParticipantDeployable {
+		action = "Deploy"
+	}
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: attacker.Name,
+		Action:    action,
+		Target:    p.Name,
+		Effects:   []string{action},
+	})
+}
+
+// deployTile returns the free tile nearest the caster to put its summon on,
+// preferring tiles closer to the target. On an open field summons stand with
+// their caster.
+func (b *Battle) deployTile(caster, target *Participant) common.Coordinates {
+	if b.Grid == nil {
+		return caster.Position
+	}
+	best, found := caster.Position, false
+	bestDistance, bestTarget := 0, 0
+	for y := 0; y < b.Grid.Height; y++ {
+		for x := 0; x < b.Grid.Width; x++ {
+			c := common.Coordinates{X: x, Y: y}
+			if !b.passable(c) {
+				continue
+			}
+			distance, toTarget := distanceBetween(caster.Position, c), distanceBetween(target.Position, c)
+			if !found || distance < bestDistance || (distance == bestDistance && toTarget < bestTarget) {
+				best, bestDistance, bestTarget, found = c, distance, toTarget, true
+			}
+		}
+	}
+	return best
+}
+
+// summonTurn runs the scripted turn of a summon or deployable. Its moves are
+// part of the turn that brought it about and are not recorded on their own.
+func (b *Battle) summonTurn(p *Participant) {
+	if script, exists := summonScripts[p.Script]; exists {
+		b.scripted = true
+		script(b, p)
+		b.scripted = false
+	}
+	b.checkDefeated()
+	b.checkBossPhase()
+}
+
+// wearOff counts down the rounds a summon or deployable has left and removes
+// it once they run out
+func (b *Battle) wearOff(p *Participant) {
+	p.Lifetime--
+	if p.Lifetime > 0 || !p.IsActive {
+		return
+	}
+	p.IsActive = false
+	b.logSummon(p, "Expire")
+}
+
+// dismissSummons removes the summons and deployables whose owner left the fight
+func (b *Battle) dismissSummons() {
+	for _, p := range b.Participants {
+		if p.OwnerID == "" || !p.IsActive {
+			continue
+		}
+		if owner := b.GetParticipant(p.OwnerID); owner == nil || !owner.IsActive {
+			b.dismiss(p)
+		}
+	}
+}
+
+// dismiss removes a summon or deployable from the fight
+func (b *Battle) dismiss(p *Participant) {
+	p.IsActive = false
+	b.logSummon(p, "Dismiss")
+}
+
+// logSummon writes a summon leaving the fight to the combat log
+func (b *Battle) logSummon(p *Participant, action string) {
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: p.Name,
+		Action:    action,
+		Effects:   []string{action},
+	})
+}
+
+// owner returns the participant a summon fights for, or the participant itself
+func (b *Battle) owner(p *Participant) *Participant {
+	if p != nil && p.OwnerID != "" {
+		if owner := b.GetParticipant(p.OwnerID); owner != nil {
+			return owner
+		}
+	}
+	return p
+}
+
+// repairAllies heals the most hurt ally in reach, moving towards it first.
+// Without anybody to heal the summon stays with its owner.
+func repairAllies(b *Battle, summon *Participant) {
+	ability := &summon.Abilities[0]
+	var target *Participant
+	for _, p := range b.Participants {
+		if !p.IsActive || p.Health >= p.MaxHealth || b.side(p) != b.side(summon) {
+			continue
+		}
+		if target == nil || p.MaxHealth-p.Health > target.MaxHealth-target.Health {
+			target = p
+		}
+	}
+	if target == nil {
+		b.Approach(summon.OwnerID, 1)
+		return
+	}
+
+	if !b.isInRange(summon, target, ability.Reach()) {
+		b.Approach(target.ID, ability.Reach())
+	}
+	if b.isInRange(summon, target, ability.Reach()) && b.hasLineOfSight(summon.Position, target.Position) {
+		b.strike(summon, target, ability)
+	}
+}
+
+// shootNearest uses the ability on the nearest opponent in range and sight
+func shootNearest(b *Battle, summon *Participant) {
+	ability := &summon.Abilities[0]
+	var target *Participant
+	for _, p := range b.Opponents(summon.ID) {
+		if !b.isInRange(summon, p, ability.Reach()) || !b.hasLineOfSight(summon.Position, p.Position) {
+			continue
+		}
+		if target == nil || distanceBetween(summon.Position, p.Position) < distanceBetween(summon.Position, target.Position) {
+			target = p
+		}
+	}
+	if target != nil {
+		b.strike(summon, target, ability)
+	}
+}
+
+// scaldAround uses the ability on every opponent in range
+func scaldAround(b *Battle, summon *Participant) {
+	ability := &summon.Abilities[0]
+	for _, p := range b.Opponents(summon.ID) {
+		if b.isInRange(summon, p, ability.Reach()) {
+			b.strike(summon, p, ability)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+// deployFirst lets the first participant use an ability deploying the given
+// summon on the second and returns the summon
+func deployFirst(t *testing.T, battle *Battle, summonID string) *Participant {
+	t.Helper()
+	if _, err := battle.ExecuteAction(&common.Ability{Name: "Deploy", Type: "summon", Effect: summonID}, "second"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	owned := battle.Summons("first")
+	if len(owned) != 1 {
+		t.Fatalf("Expected one summon, got %d", len(owned))
+	}
+	return owned[0]
+}
+
+func TestDeployTurret(t *testing.T) {
+	battle, first, second := newGridDuel()
+	turret := deployFirst(t, battle, "steam_turret")
+
+	if turret.Type != ParticipantDeployable || turret.OwnerID != "first" || turret.Lifetime != 3 {
+		t.Errorf("Expected a deployable of first lasting 3 rounds, got %+v", turret)
+	}
+	if distanceBetween(turret.Position, first.Position) != 1 || battle.occupant(turret.Position) != turret {
+		t.Errorf("Expected the turret to occupy a tile next to first, got %v", turret.Position)
+	}
+	if battle.side(turret) != battle.side(first) || len(battle.Opponents("second")) != 2 {
+		t.Error("Expected the turret to fight for first")
+	}
+	if battle.ActiveParticipant() != second {
+		t.Errorf("Expected second to act after first, got %s", battle.ActiveParticipant().ID)
+	}
+	if battle.movement(turret) != 0 {
+		t.Errorf("Expected the turret not to move, got %d", battle.movement(turret))
+	}
+
+	// The turret shoots on its own turn, right after second's
+	second.Position = common.Coordinates{X: turret.Position.X + 3, Y: turret.Position.Y}
+	health := second.Health
+	battle.SkipTurn()
+	if second.Health >= health {
+		t.Errorf("Expected the turret to shoot second, got %d health", second.Health)
+	}
+	if battle.ActiveParticipant() != first {
+		t.Errorf("Expected first to act after the turret, got %s", battle.ActiveParticipant().ID)
+	}
+	if battle.Stats["first"].TotalDamageDealt != health-second.Health {
+		t.Errorf("Expected the turret's damage to count for first, got %d", battle.Stats["first"].TotalDamageDealt)
+	}
+}
+
+func TestSummonLifetime(t *testing.T) {
+	battle, _, _ := newGridDuel()
+	vent := deployFirst(t, battle, "steam_vent")
+
+	for i := 0; i < 3; i++ {
+		if !vent.IsActive {
+			t.Fatalf("Expected the vent to last 3 rounds, expired after %d", i)
+		}
+		battle.SkipTurn()
+		battle.SkipTurn()
+	}
+	if vent.IsActive {
+		t.Error("Expected the vent to expire")
+	}
+	if last := battle.CombatLog[len(battle.CombatLog)-1]; last.Action != "Expire" || last.Character != "Steam Vent" {
+		t.Errorf("Expected the vent to expire in the log, got %+v", last)
+	}
+	if occupant := battle.occupant(vent.Position); occupant != nil {
+		t.Errorf("Expected the vent's tile to be free, got %s", occupant.ID)
+	}
+}
+
+func TestRepairBot(t *testing.T) {
+	battle, first, _ := newDuel()
+	first.Health = 50
+	bot := deployFirst(t, battle, "repair_bot")
+
+	if bot.Type != ParticipantSummon || bot.MaxHealth != 30 {
+		t.Errorf("Expected a level 0 repair bot with 30 health, got %+v", bot)
+	}
+	battle.SkipTurn()
+	if first.Health != 58 {
+		t.Errorf("Expected the bot to repair first to 58 health, got %d", first.Health)
+	}
+	if battle.Stats["first"].TotalHealingDone != 8 {
+		t.Errorf("Expected the bot's healing to count for first, got %d", battle.Stats["first"].TotalHealingDone)
+	}
+
+	// Deploying again replaces the bot
+	replacement := deployFirst(t, battle, "repair_bot")
+	if bot.IsActive || replacement == bot {
+		t.Error("Expected the new bot to replace the old one")
+	}
+}
+
+func TestSummonsLeaveWithOwner(t *testing.T) {
+	battle, _, _ := newDuel()
+	bot := deployFirst(t, battle, "repair_bot")
+
+	battle.Forfeit("first")
+	if bot.IsActive {
+		t.Error("Expected the bot to be dismissed with its owner")
+	}
+	if !battle.IsOver() || len(battle.Winners) != 1 || battle.Winners[0] != "second" {
+		t.Errorf("Expected second to win, got %v", battle.Winners)
+	}
+
+	battle, _, _ = newDuel()
+	deployFirst(t, battle, "repair_bot")
+	battle.Forfeit("second")
+	if len(battle.Winners) != 1 || battle.Winners[0] != "first" {
+		t.Errorf("Expected only first to be a winner, got %v", battle.Winners)
+	}
+}
+
+func TestReplaySummons(t *testing.T) {
+	useSeededRolls(t)
+	battle, _, second := newGridDuel()
+	battle.SetSeed(7)
+	turret := deployFirst(t, battle, "steam_turret")
+	repair := &common.Ability{Name: "Deploy", Type: "summon", Effect: "repair_bot"}
+
+	// Second walks into the turret's range
+	if err := battle.Move(common.Coordinates{X: second.Position.X - 3, Y: second.Position.Y}); err != nil {
+		t.Fatalf("Unexpected error moving: %v", err)
+	}
+	battle.SkipTurn()
+	if second.Health == second.MaxHealth {
+		t.Errorf("Expected the turret at %v to shoot second at %v", turret.Position, second.Position)
+	}
+	if _, err := battle.ExecuteAction(repair, "second"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	battle.SkipTurn()
+
+	replayed, err := battle.Record().Replay()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(replayed.Participants) != len(battle.Participants) {
+		t.Errorf("Expected %d participants, got %d", len(battle.Participants), len(replayed.Participants))
+	}
+	assertSameBattle(t, battle, replayed)
+}
+
+func TestRegisterSummon(t *testing.T) {
+	if err := RegisterSummon(&SummonDefinition{ID: "bad", Name: "Bad", Type: ParticipantSummon, Health: 10, Rounds: 1, Script: "dance"}); err == nil {
+		t.Error("Expected an unknown script to be rejected")
+	}
+	if err := RegisterSummon(&SummonDefinition{ID: "bad", Name: "Bad", Type: "mob", Health: 10, Rounds: 1, Script: "shoot_nearest"}); err == nil {
+		t.Error("Expected an unknown type to be rejected")
+	}
+	if _, exists := GetAbilityEffect("repair_bot"); !exists {
+		t.Error("Expected the repair bot to be deployed by an ability effect")
+	}
+}
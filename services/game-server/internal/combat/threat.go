@@ -0,0 +1,79 @@
+package combat
+
+import (
+	"sort"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+const (
+	// healingThreatDivisor spreads half of all healing as threat
+	healingThreatDivisor = 2
+	// tauntThreat is how far ahead of everyone else a taunt puts the taunter
+	tauntThreat = 10
+)
+
+// ThreatEntry is a line of a mob's threat table
+type ThreatEntry struct {
+	ParticipantID string
+	Threat        int
+}
+
+// GetThreat returns the threat a participant has drawn from a mob
+func (b *Battle) GetThreat(mobID, participantID string) int {
+	return b.Threat[mobID][participantID]
+}
+
+// ThreatTable returns a mob's threat table, highest threat first
+func (b *Battle) ThreatTable(mobID string) []ThreatEntry {
+	var table []ThreatEntry
+	for id, threat := range b.Threat[mobID] {
+		table = append(table, ThreatEntry{ParticipantID: id, Threat: threat})
+	}
+	sort.Slice(table, func(i, j int) bool {
+		if table[i].Threat != table[j].Threat {
+			return table[i].Threat > table[j].Threat
+		}
+		return table[i].ParticipantID < table[j].ParticipantID
+	})
+	return table
+}
+
+// addThreat adds threat towards a source to a mob's threat table. Only mobs
+// keep threat tables, and only for their opponents.
+func (b *Battle) addThreat(m, source *Participant, amount int) {
+	if m == nil || source == nil || m.Type != "mob" || amount <= 0 || b.side(m) == b.side(source) {
+		return
+	}
+	if b.Threat == nil {
+		b.Threat = make(map[string]map[string]int)
+	}
+	if b.Threat[m.ID] == nil {
+		b.Threat[m.ID] = make(map[string]int)
+	}
+	b.Threat[m.ID][source.ID] += amount
+}
+
+// healingThreat spreads the threat of healing over every mob fighting the healer
+func (b *Battle) healingThreat(healer *Participant, healing int) {
+	if healer == nil {
+		return
+	}
+	for _, m := range b.Opponents(healer.ID) {
+		b.addThreat(m, healer, healing/healingThreatDivisor)
+	}
+}
+
+// taunt puts the attacker ahead of everyone else on the threat table of every
+// mob fighting it
+func taunt(b *Battle, ability *common.Ability, attacker, target *Participant, damage int) {
+	for _, m := range b.Opponents(attacker.ID) {
+		top := 0
+		for id, threat := range b.Threat[m.ID] {
+			if id != attacker.ID {
+				top = max(top, threat)
+			}
+		}
+		b.addThreat(m, attacker, top+tauntThreat-b.GetThreat(m.ID, attacker.ID))
+	}
+}
@@ -0,0 +1,84 @@
+package combat
+
+import (
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// newThreatBattle creates a PvE battle of a tank and a healer against two mobs;
+// the tank acts first and the healer second
+func newThreatBattle() *Battle {
+	battle := NewBattle(BattleTypePvE)
+	tank := &character.Character{ID: "tank", Name: "Tank", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	tank.Stats.Dexterity = 30
+	healer := &character.Character{ID: "healer", Name: "Healer", Health: 100, MaxHealth: 100, SteamPower: 50, MaxSteamPower: 50}
+	healer.Stats.Dexterity = 20
+	battle.AddPlayer(tank)
+	battle.AddPlayer(healer)
+	battle.AddMob(&mob.Mob{ID: "rat", Name: "Rat", Health: 100, MaxHealth: 100})
+	battle.AddMob(&mob.Mob{ID: "golem", Name: "Golem", Health: 100, MaxHealth: 100})
+	return battle
+}
+
+func TestThreatFromDamageAndHealing(t *testing.T) {
+	battle := newThreatBattle()
+
+	strike := &common.Ability{Name: "Strike", Damage: 20}
+	damage, err := battle.ExecuteAction(strike, "rat")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if threat := battle.GetThreat("rat", "tank"); threat != damage {
+		t.Errorf("Expected threat %d from damage, got %d", damage, threat)
+	}
+	if threat := battle.GetThreat("golem", "tank"); threat != 0 {
+		t.Errorf("Expected no threat on the golem, got %d", threat)
+	}
+
+	// Healing draws half its amount as threat from every mob
+	battle.GetParticipant("tank").Combatant().(*CharacterCombatant).Character.Health = 50
+	mend := &common.Ability{Name: "Mend", Type: "heal", Healing: 20}
+	healing, err := battle.ExecuteAction(mend, "tank")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, id := range []string{"rat", "golem"} {
+		if threat := battle.GetThreat(id, "healer"); threat != healing/2 {
+			t.Errorf("Expected threat %d on %s from healing, got %d", healing/2, id, threat)
+		}
+	}
+
+	// Mobs never draw threat from each other
+	battle.addThreat(battle.GetParticipant("rat"), battle.GetParticipant("golem"), 10)
+	if threat := battle.GetThreat("rat", "golem"); threat != 0 {
+		t.Errorf("Expected no threat between mobs, got %d", threat)
+	}
+
+	table := battle.ThreatTable("rat")
+	if len(table) != 2 || table[0].ParticipantID != "tank" || table[1].ParticipantID != "healer" {
+		t.Errorf("Expected tank above healer on the threat table, got %+v", table)
+	}
+}
+
+func TestTaunt(t *testing.T) {
+	battle := newThreatBattle()
+	battle.addThreat(battle.GetParticipant("rat"), battle.GetParticipant("healer"), 40)
+
+	armor := &common.Ability{Name: "Clockwork Armor", Type: "buff", Effect: "armor_increase"}
+	if _, err := battle.ExecuteAction(armor, "tank"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if threat := battle.GetThreat("rat", "tank"); threat != 40+tauntThreat {
+		t.Errorf("Expected threat %d after the taunt, got %d", 40+tauntThreat, threat)
+	}
+	if threat := battle.GetThreat("golem", "tank"); threat != tauntThreat {
+		t.Errorf("Expected threat %d on the golem, got %d", tauntThreat, threat)
+	}
+	if effects := battle.GetStatusEffects("tank"); len(effects) != 1 || effects[0].Name != FortifiedEffect.Name {
+		t.Errorf("Expected the tank to be fortified, got %+v", effects)
+	}
+}
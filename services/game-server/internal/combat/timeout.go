@@ -0,0 +1,50 @@
+package combat
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultTurnTimeout is how long a participant has to act on its turn
+	DefaultTurnTimeout = 60 * time.Second
+	// maxTurnTimeouts is how many turns in a row a participant may let time
+	// out before it forfeits the battle
+	maxTurnTimeouts = 3
+)
+
+// TurnExpired reports whether the active participant has run out of time to
+// act on its turn
+func (b *Battle) TurnExpired(now time.Time) bool {
+	return b.State == BattleActive && b.TurnTimeout > 0 && now.Sub(b.TurnStartedAt) >= b.TurnTimeout
+}
+
+// TimeoutTurn skips the turn of an active participant that ran out of time.
+// A participant whose turns time out maxTurnTimeouts times in a row forfeits
+// the battle.
+func (b *Battle) TimeoutTurn() error {
+	if b.State != BattleActive {
+		return fmt.Errorf("battle is not active")
+	}
+
+	participant := b.ActiveParticipant()
+	b.recordEvent(ReplayEvent{Kind: ReplayTimeout, ActorID: participant.ID})
+	if b.Timeouts == nil {
+		b.Timeouts = make(map[string]int)
+	}
+	b.Timeouts[participant.ID]++
+	b.CombatLog = append(b.CombatLog, CombatLogEntry{
+		Round:     b.Round,
+		Turn:      b.CurrentTurn,
+		Character: participant.Name,
+		Action:    "Timed Out",
+		Effects:   make([]string, 0),
+	})
+
+	if b.Timeouts[participant.ID] >= maxTurnTimeouts {
+		b.forfeit(participant)
+	} else {
+		b.skipTurn()
+	}
+	return nil
+}
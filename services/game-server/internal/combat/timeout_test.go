@@ -0,0 +1,64 @@
+package combat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+func TestTurnTimeout(t *testing.T) {
+	battle, first, second := newGridDuel()
+
+	if battle.TurnExpired(battle.TurnStartedAt.Add(DefaultTurnTimeout - time.Second)) {
+		t.Error("Expected turn not to have expired yet")
+	}
+	if !battle.TurnExpired(battle.TurnStartedAt.Add(DefaultTurnTimeout)) {
+		t.Fatal("Expected turn to have expired")
+	}
+
+	// A timed out turn is skipped
+	if err := battle.TimeoutTurn(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle.ActiveParticipant() != second {
+		t.Errorf("Expected second's turn, got %s", battle.ActiveParticipant().ID)
+	}
+	if battle.Timeouts[first.ID] != 1 {
+		t.Errorf("Expected 1 timeout, got %d", battle.Timeouts[first.ID])
+	}
+
+	// Acting resets the count
+	battle.SkipTurn()
+	battle.TimeoutTurn()
+	battle.SkipTurn()
+	if err := battle.Move(common.Coordinates{X: first.Position.X + 1, Y: first.Position.Y}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle.Timeouts[first.ID] != 0 {
+		t.Errorf("Expected timeouts to reset after acting, got %d", battle.Timeouts[first.ID])
+	}
+
+	// Timing out too often in a row forfeits the battle
+	for i := 0; i < maxTurnTimeouts; i++ {
+		if battle.ActiveParticipant() != first {
+			battle.SkipTurn()
+		}
+		battle.TimeoutTurn()
+	}
+	if !battle.IsOver() || first.IsActive {
+		t.Fatal("Expected first to forfeit")
+	}
+	if len(battle.Winners) != 1 || battle.Winners[0] != second.ID {
+		t.Errorf("Expected second to win, got %v", battle.Winners)
+	}
+}
+
+func TestNoTurnTimeout(t *testing.T) {
+	battle, _, _ := newGridDuel()
+	battle.TurnTimeout = 0
+
+	if battle.TurnExpired(battle.TurnStartedAt.Add(24 * time.Hour)) {
+		t.Error("Expected turns without a timeout never to expire")
+	}
+}
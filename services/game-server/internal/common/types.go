@@ -49,6 +49,9 @@ type Item struct {
 	Slot        string
 	Stats       Stats
 	Value       int
+	Resistances map[string]int // damage type -> percent resisted, for equipment
+	Armor       int
+	Penetration int
 }
 
 // Ability represents a character/mob ability
@@ -59,9 +62,20 @@ type Ability struct {
 	Damage      int
 	Healing     int
 	SteamCost   int
-	Range       int
+	Range       int // tiles the ability reaches, 0 for melee
 	Area        int
 	Cooldown    int
+	Effect      string // identifier of an extra combat mechanic, e.g. "stun" or "poison"
+	Reaction    string // condition a reaction triggers on out of turn, e.g. "counter"; empty for abilities used on a turn
+	Channel     int    // turns the ability is channelled before it resolves, 0 for instant
+	ComboAfter  string // ability that, used by an ally earlier in the round, this one chains from
+	ComboBonus  int    // percent more damage and healing per link of a combo chain
+}
+
+// Reach returns the distance in tiles the ability reaches; abilities without
+// a range are used in melee
+func (a *Ability) Reach() int {
+	return max(a.Range, 1)
 }
 
 // LootTable represents a table of possible loot drops
@@ -20,6 +20,7 @@ type Config struct {
 	WorldSize          int
 	TickRate           int
 	SaveInterval       time.Duration
+	ShutdownTimeout    time.Duration
 	MaxPartySize       int
 	MaxInventorySize   int
 	MaxChatHistory     int
@@ -39,6 +40,7 @@ func Load() (*Config, error) {
 		WorldSize:          getEnvInt("WORLD_SIZE", 1000),
 		TickRate:           getEnvInt("TICK_RATE", 60),
 		SaveInterval:       getEnvDuration("SAVE_INTERVAL", 5*time.Minute),
+		ShutdownTimeout:    getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
 		MaxPartySize:       getEnvInt("MAX_PARTY_SIZE", 5),
 		MaxInventorySize:   getEnvInt("MAX_INVENTORY_SIZE", 50),
 		MaxChatHistory:     getEnvInt("MAX_CHAT_HISTORY", 100),
@@ -90,6 +92,10 @@ func (c *Config) validate() error {
 		return fmt.Errorf("SAVE_INTERVAL must be positive")
 	}
 
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must be positive")
+	}
+
 	if c.MaxPartySize <= 0 {
 		return fmt.Errorf("MAX_PARTY_SIZE must be positive")
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -40,6 +41,14 @@ func TestLoad(t *testing.T) {
 		t.Errorf("Load() TickRate = %v, want %v", cfg.TickRate, 60)
 	}
 
+	if cfg.SaveInterval != 5*time.Minute {
+		t.Errorf("Load() SaveInterval = %v, want %v", cfg.SaveInterval, 5*time.Minute)
+	}
+
+	if cfg.ShutdownTimeout != 30*time.Second {
+		t.Errorf("Load() ShutdownTimeout = %v, want %v", cfg.ShutdownTimeout, 30*time.Second)
+	}
+
 	// Test custom values
 	os.Setenv("PORT", "9090")
 	os.Setenv("REDIS_URL", "localhost:6379")
@@ -100,9 +109,32 @@ func TestValidate(t *testing.T) {
 				MaxPlayers:         100,
 				WorldSize:          1000,
 				TickRate:           20,
+				SaveInterval:       5 * time.Minute,
+				ShutdownTimeout:    30 * time.Second,
+				MaxPartySize:       5,
+				MaxInventorySize:   50,
+				MaxChatHistory:     100,
+				MaxQuestLog:        20,
+				MaxFriends:         100,
 			},
 			wantErr: false,
 		},
+		{
+			name: "invalid shutdown timeout",
+			cfg: &Config{
+				Port:               "8080",
+				RedisURL:           "redis:6379",
+				AuthServiceURL:     "http://auth-service:8081",
+				ChatServiceURL:     "http://chat-service:8082",
+				CorsAllowedOrigins: []string{"http://localhost:3000"},
+				MaxPlayers:         100,
+				WorldSize:          1000,
+				TickRate:           20,
+				SaveInterval:       5 * time.Minute,
+				ShutdownTimeout:    0,
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing port",
 			cfg: &Config{
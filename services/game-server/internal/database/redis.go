@@ -51,6 +51,34 @@ func (db *RedisDB) Set(key string, value interface{}, expiration time.Duration)
 	return db.client.Set(db.ctx, key, data, expiration).Err()
 }
 
+// Entry is a key and value to store with an expiration time
+type Entry struct {
+	Key        string
+	Value      interface{}
+	Expiration time.Duration
+}
+
+// SetAll stores all entries in a single MULTI/EXEC transaction, so either
+// every entry is written or none is
+func (db *RedisDB) SetAll(entries []Entry) error {
+	data := make([][]byte, len(entries))
+	for i, entry := range entries {
+		encoded, err := json.Marshal(entry.Value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value for %s: %v", entry.Key, err)
+		}
+		data[i] = encoded
+	}
+
+	_, err := db.client.TxPipelined(db.ctx, func(pipe redis.Pipeliner) error {
+		for i, entry := range entries {
+			pipe.Set(db.ctx, entry.Key, data[i], entry.Expiration)
+		}
+		return nil
+	})
+	return err
+}
+
 // Get retrieves a value from Redis
 func (db *RedisDB) Get(key string, value interface{}) error {
 	data, err := db.client.Get(db.ctx, key).Bytes()
@@ -137,3 +165,34 @@ func (db *RedisDB) SMembers(key string) ([]string, error) {
 func (db *RedisDB) SRem(key string, members ...interface{}) error {
 	return db.client.SRem(db.ctx, key, members...).Err()
 }
+
+// ScoredMember is a member of a sorted set and its score
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// ZAdd adds a member to a sorted set or updates its score
+func (db *RedisDB) ZAdd(key string, score float64, member string) error {
+	return db.client.ZAdd(db.ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+// ZRevRangeWithScores gets the members of a sorted set from start to stop,
+// highest score first
+func (db *RedisDB) ZRevRangeWithScores(key string, start, stop int64) ([]ScoredMember, error) {
+	result, err := db.client.ZRevRangeWithScores(db.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ScoredMember, len(result))
+	for i, z := range result {
+		member, _ := z.Member.(string)
+		members[i] = ScoredMember{Member: member, Score: z.Score}
+	}
+	return members, nil
+}
+
+// ZRem removes members from a sorted set
+func (db *RedisDB) ZRem(key string, members ...interface{}) error {
+	return db.client.ZRem(db.ctx, key, members...).Err()
+}
@@ -1,20 +1,33 @@
 package database
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redfoxius/roleplay/services/game-server/internal/character"
 	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/matchmaking"
 	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
 )
 
 const (
 	// Key prefixes
 	characterPrefix = "character:"
+	namePrefix      = "charname:"
 	mobPrefix       = "mob:"
-	combatPrefix    = "combat:"
+	battlePrefix    = "battle:"
 	spawnPrefix     = "spawn:"
+	statsPrefix     = "combatstats:"
+	recordPrefix    = "battlerecord:"
+	ratingPrefix    = "rating:"      // hash per bracket: character ID -> rating
+	boardPrefix     = "leaderboard:" // sorted set per season and bracket: character ID by rating
+	seasonKey       = "season"
+
+	// recordRetention is how long the event stream of a finished battle is
+	// kept for reports and replays
+	recordRetention = 7 * 24 * time.Hour
 )
 
 // Repository handles data persistence for the game
@@ -30,7 +43,7 @@ func NewRepository(db *RedisDB) *Repository {
 // SaveCharacter saves a character to Redis
 func (r *Repository) SaveCharacter(char *character.Character) error {
 	key := characterPrefix + char.ID
-	return r.db.Set(key, char, 24*time.Hour)
+	return r.db.Set(key, char, 0) // No expiration for characters
 }
 
 // GetCharacter retrieves a character from Redis
@@ -49,6 +62,46 @@ func (r *Repository) DeleteCharacter(id string) error {
 	return r.db.Delete(key)
 }
 
+// ReserveCharacterName atomically claims a normalized character name for a
+// character. It reports false if the name already belongs to another character.
+func (r *Repository) ReserveCharacterName(name, characterID string) (bool, error) {
+	key := namePrefix + name
+	ok, err := r.db.SetNX(key, characterID, 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve name: %v", err)
+	}
+	if ok {
+		return true, nil
+	}
+
+	var owner string
+	if err := r.db.Get(key, &owner); err != nil {
+		return false, fmt.Errorf("failed to get name owner: %v", err)
+	}
+	return owner == characterID, nil
+}
+
+// ReleaseCharacterName frees a normalized character name if it belongs to the character
+func (r *Repository) ReleaseCharacterName(name, characterID string) error {
+	key := namePrefix + name
+	exists, err := r.db.Exists(key)
+	if err != nil {
+		return fmt.Errorf("failed to check name: %v", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	var owner string
+	if err := r.db.Get(key, &owner); err != nil {
+		return fmt.Errorf("failed to get name owner: %v", err)
+	}
+	if owner != characterID {
+		return nil
+	}
+	return r.db.Delete(key)
+}
+
 // SaveMob saves a mob to Redis
 func (r *Repository) SaveMob(m *mob.Mob) error {
 	key := mobPrefix + m.ID
@@ -71,25 +124,91 @@ func (r *Repository) DeleteMob(id string) error {
 	return r.db.Delete(key)
 }
 
-// SaveCombatState saves a combat state to Redis
-func (r *Repository) SaveCombatState(state *combat.CombatState) error {
-	key := combatPrefix + state.ID
-	return r.db.Set(key, state, 1*time.Hour)
+// SaveBattle saves a battle and its event stream to Redis. Active battles
+// never expire; finished battles are kept for an hour and their event streams
+// for a week.
+func (r *Repository) SaveBattle(battle *combat.Battle) error {
+	if err := r.db.SetAll(battleEntries(battle)); err != nil {
+		return fmt.Errorf("failed to save battle: %v", err)
+	}
+	return nil
+}
+
+// storedBattle is a battle as kept in Redis, together with the random number
+// state clients never see
+type storedBattle struct {
+	*combat.Battle
+	Seed  int64
+	Rolls int
+}
+
+// battleEntries returns the entries that store a battle and its record
+func battleEntries(battle *combat.Battle) []Entry {
+	stored := storedBattle{Battle: battle, Seed: battle.Seed, Rolls: battle.Rolls}
+	entries := []Entry{{Key: battlePrefix + battle.ID, Value: stored, Expiration: battleExpiration(battle)}}
+	if record := battle.Record(); record != nil {
+		var expiration time.Duration
+		if battle.IsOver() {
+			expiration = recordRetention
+		}
+		entries = append(entries, Entry{Key: recordPrefix + battle.ID, Value: record, Expiration: expiration})
+	}
+	return entries
+}
+
+// battleExpiration returns how long a battle is kept in Redis
+func battleExpiration(battle *combat.Battle) time.Duration {
+	if battle.IsOver() {
+		return 1 * time.Hour
+	}
+	return 0
+}
+
+// SaveBattleResult saves a finished battle together with the characters it
+// rewarded and their lifetime combat statistics in one transaction
+func (r *Repository) SaveBattleResult(battle *combat.Battle, chars []*character.Character, stats map[string]*combat.CombatStats) error {
+	entries := battleEntries(battle)
+	for _, char := range chars {
+		entries = append(entries, Entry{Key: characterPrefix + char.ID, Value: char})
+	}
+	for id, cs := range stats {
+		entries = append(entries, Entry{Key: statsPrefix + id, Value: cs})
+	}
+	if err := r.db.SetAll(entries); err != nil {
+		return fmt.Errorf("failed to save battle result: %v", err)
+	}
+	return nil
 }
 
-// GetCombatState retrieves a combat state from Redis
-func (r *Repository) GetCombatState(id string) (*combat.CombatState, error) {
-	var state combat.CombatState
-	key := combatPrefix + id
-	if err := r.db.Get(key, &state); err != nil {
-		return nil, fmt.Errorf("failed to get combat state: %v", err)
+// GetBattle retrieves a battle from Redis
+func (r *Repository) GetBattle(id string) (*combat.Battle, error) {
+	var battle combat.Battle
+	key := battlePrefix + id
+	stored := storedBattle{Battle: &battle}
+	if err := r.db.Get(key, &stored); err != nil {
+		return nil, fmt.Errorf("failed to get battle: %v", err)
 	}
-	return &state, nil
+	battle.Seed, battle.Rolls = stored.Seed, stored.Rolls
+	battle.Relink()
+	return &battle, nil
 }
 
-// DeleteCombatState removes a combat state from Redis
-func (r *Repository) DeleteCombatState(id string) error {
-	key := combatPrefix + id
+// GetBattleRecord retrieves the event stream of a battle from Redis
+func (r *Repository) GetBattleRecord(id string) (*combat.BattleRecord, error) {
+	var record combat.BattleRecord
+	key := recordPrefix + id
+	if err := r.db.Get(key, &record); err != nil {
+		return nil, fmt.Errorf("failed to get battle record: %v", err)
+	}
+	if record.Initial != nil {
+		record.Initial.Relink()
+	}
+	return &record, nil
+}
+
+// DeleteBattle removes a battle from Redis
+func (r *Repository) DeleteBattle(id string) error {
+	key := battlePrefix + id
 	return r.db.Delete(key)
 }
 
@@ -134,6 +253,28 @@ func (r *Repository) GetAllCharacters() ([]*character.Character, error) {
 	return characters, nil
 }
 
+// GetAllBattles retrieves all battles from Redis
+func (r *Repository) GetAllBattles() ([]*combat.Battle, error) {
+	keys, err := r.db.Keys(battlePrefix + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get battle keys: %v", err)
+	}
+
+	battles := make([]*combat.Battle, 0, len(keys))
+	for _, key := range keys {
+		var battle combat.Battle
+		stored := storedBattle{Battle: &battle}
+		if err := r.db.Get(key, &stored); err != nil {
+			return nil, fmt.Errorf("failed to get battle %s: %v", key, err)
+		}
+		battle.Seed, battle.Rolls = stored.Seed, stored.Rolls
+		battle.Relink()
+		battles = append(battles, &battle)
+	}
+
+	return battles, nil
+}
+
 // GetAllMobs retrieves all mobs from Redis
 func (r *Repository) GetAllMobs() ([]*mob.Mob, error) {
 	keys, err := r.db.Keys(mobPrefix + "*")
@@ -153,6 +294,42 @@ func (r *Repository) GetAllMobs() ([]*mob.Mob, error) {
 	return mobs, nil
 }
 
+// SaveCombatStats saves a character's lifetime combat statistics to Redis
+func (r *Repository) SaveCombatStats(characterID string, stats *combat.CombatStats) error {
+	key := statsPrefix + characterID
+	return r.db.Set(key, stats, 0) // No expiration for combat statistics
+}
+
+// GetCombatStats retrieves a character's lifetime combat statistics from Redis
+func (r *Repository) GetCombatStats(characterID string) (*combat.CombatStats, error) {
+	var stats combat.CombatStats
+	key := statsPrefix + characterID
+	if err := r.db.Get(key, &stats); err != nil {
+		return nil, fmt.Errorf("failed to get combat stats: %v", err)
+	}
+	return &stats, nil
+}
+
+// GetAllCombatStats retrieves every character's combat statistics from Redis,
+// keyed by character ID
+func (r *Repository) GetAllCombatStats() (map[string]*combat.CombatStats, error) {
+	keys, err := r.db.Keys(statsPrefix + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get combat stats keys: %v", err)
+	}
+
+	stats := make(map[string]*combat.CombatStats, len(keys))
+	for _, key := range keys {
+		var cs combat.CombatStats
+		if err := r.db.Get(key, &cs); err != nil {
+			return nil, fmt.Errorf("failed to get combat stats %s: %v", key, err)
+		}
+		stats[strings.TrimPrefix(key, statsPrefix)] = &cs
+	}
+
+	return stats, nil
+}
+
 // GetAllSpawnPoints retrieves all spawn points from Redis
 func (r *Repository) GetAllSpawnPoints() ([]*character.SpawnPoint, error) {
 	keys, err := r.db.Keys(spawnPrefix + "*")
@@ -171,3 +348,89 @@ func (r *Repository) GetAllSpawnPoints() ([]*character.SpawnPoint, error) {
 
 	return spawnPoints, nil
 }
+
+// SaveRating saves a character's rating in a bracket and its place on the
+// leaderboard of the rating's season
+func (r *Repository) SaveRating(bracket matchmaking.Bracket, characterID string, rating *matchmaking.Rating) error {
+	if err := r.db.HSet(ratingPrefix+string(bracket), characterID, rating); err != nil {
+		return fmt.Errorf("failed to save rating: %v", err)
+	}
+	if err := r.db.ZAdd(leaderboardKey(rating.Season, bracket), rating.Value, characterID); err != nil {
+		return fmt.Errorf("failed to update leaderboard: %v", err)
+	}
+	return nil
+}
+
+// GetRatings retrieves every character's rating in a bracket, keyed by character ID
+func (r *Repository) GetRatings(bracket matchmaking.Bracket) (map[string]*matchmaking.Rating, error) {
+	fields, err := r.db.HGetAll(ratingPrefix + string(bracket))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ratings: %v", err)
+	}
+
+	ratings := make(map[string]*matchmaking.Rating, len(fields))
+	for id, data := range fields {
+		var rating matchmaking.Rating
+		if err := json.Unmarshal([]byte(data), &rating); err != nil {
+			return nil, fmt.Errorf("failed to decode rating of %s: %v", id, err)
+		}
+		ratings[id] = &rating
+	}
+	return ratings, nil
+}
+
+// DeleteRatings removes a character's ratings and its places on the
+// leaderboards of a season
+func (r *Repository) DeleteRatings(characterID string, season int) error {
+	for _, bracket := range matchmaking.Brackets() {
+		if err := r.db.HDel(ratingPrefix+string(bracket), characterID); err != nil {
+			return fmt.Errorf("failed to delete rating: %v", err)
+		}
+		if err := r.db.ZRem(leaderboardKey(season, bracket), characterID); err != nil {
+			return fmt.Errorf("failed to update leaderboard: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetLeaderboard retrieves the best rated characters of a season and bracket,
+// at most limit of them. Names are left for the caller to fill in.
+func (r *Repository) GetLeaderboard(season int, bracket matchmaking.Bracket, limit int) ([]matchmaking.Standing, error) {
+	members, err := r.db.ZRevRangeWithScores(leaderboardKey(season, bracket), 0, int64(limit)-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %v", err)
+	}
+
+	standings := make([]matchmaking.Standing, len(members))
+	for i, m := range members {
+		standings[i] = matchmaking.Standing{Rank: i + 1, CharacterID: m.Member, Rating: m.Score}
+	}
+	return standings, nil
+}
+
+// leaderboardKey returns the key of the leaderboard of a season and bracket
+func leaderboardKey(season int, bracket matchmaking.Bracket) string {
+	return fmt.Sprintf("%s%d:%s", boardPrefix, season, bracket)
+}
+
+// SaveSeason saves the current ranked season
+func (r *Repository) SaveSeason(season *matchmaking.Season) error {
+	return r.db.Set(seasonKey, season, 0)
+}
+
+// GetSeason retrieves the current ranked season, or nil if none was saved yet
+func (r *Repository) GetSeason() (*matchmaking.Season, error) {
+	exists, err := r.db.Exists(seasonKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check season: %v", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	var season matchmaking.Season
+	if err := r.db.Get(seasonKey, &season); err != nil {
+		return nil, fmt.Errorf("failed to get season: %v", err)
+	}
+	return &season, nil
+}
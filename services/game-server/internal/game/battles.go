@@ -0,0 +1,484 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/world"
+)
+
+const (
+	// finishedBattleRetention is how long finished battles stay in memory
+	finishedBattleRetention = time.Hour
+	// battleReach is how far away from a character its opponents may be
+	battleReach = interestRadius
+)
+
+var (
+	// ErrBattleNotFound is returned when a battle does not exist
+	ErrBattleNotFound = errors.New("battle not found")
+	// ErrNotBattleParticipant is returned when an account has no character in a battle
+	ErrNotBattleParticipant = errors.New("no character of this account is in the battle")
+	// ErrNotYourTurn is returned when acting while another participant has the turn
+	ErrNotYourTurn = errors.New("it is not your turn")
+	// ErrBattleOver is returned when acting in a battle that has ended
+	ErrBattleOver = errors.New("battle is over")
+	// ErrBattleConflict is returned when an action was based on an outdated battle version
+	ErrBattleConflict = errors.New("battle has changed since the given version")
+	// ErrInvalidOpponents is returned when a battle is created without valid opponents
+	ErrInvalidOpponents = errors.New("a battle needs either character or mob opponents")
+	// ErrOutOfReach is returned when a battle is started against opponents too far away
+	ErrOutOfReach = errors.New("opponents are too far away")
+	// ErrBattleRecordNotFound is returned when a battle has no recorded events
+	ErrBattleRecordNotFound = errors.New("battle record not found")
+)
+
+// CreateBattle starts a battle between an account's character and either
+// other characters of the account (PvP) or mobs (PvE) within reach. Characters
+// of other accounts are challenged instead and fight once they accept.
+func (gs *GameServer) CreateBattle(owner, characterID string, opponentIDs, mobIDs []string) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
+	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	char, err := gs.ownedCharacter(owner, characterID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range opponentIDs {
+		if opponent, exists := gs.players[id]; exists && opponent.Owner != owner {
+			return nil, ErrNotCharacterOwner
+		}
+	}
+	return gs.startBattle(char, opponentIDs, mobIDs)
+}
+
+// startBattle starts a battle between a character and either other characters
+// or mobs within reach; the caller must hold the mutex
+func (gs *GameServer) startBattle(char *character.Character, opponentIDs, mobIDs []string) (*combat.Battle, error) {
+	if (len(opponentIDs) == 0) == (len(mobIDs) == 0) {
+		return nil, ErrInvalidOpponents
+	}
+
+	battleType := combat.BattleTypePvP
+	if len(mobIDs) > 0 {
+		battleType = combat.BattleTypePvE
+	}
+	positions := []common.Coordinates{char.Position}
+	for _, id := range opponentIDs {
+		if opponent, exists := gs.players[id]; exists {
+			positions = append(positions, opponent.Position)
+		}
+	}
+	for _, id := range mobIDs {
+		if m, exists := gs.mobs[id]; exists {
+			positions = append(positions, m.Position)
+		}
+	}
+	battle := gs.newBattle(battleType, positions...)
+	battle.AddPlayer(char)
+
+	for _, id := range opponentIDs {
+		opponent, exists := gs.players[id]
+		if !exists || id == char.ID || battle.GetParticipant(id) != nil {
+			return nil, ErrInvalidOpponents
+		}
+		battle.AddPlayer(opponent)
+	}
+	for _, id := range mobIDs {
+		m, exists := gs.mobs[id]
+		if !exists || m.IsDead() || battle.GetParticipant(id) != nil {
+			return nil, ErrInvalidOpponents
+		}
+		battle.AddMob(m)
+	}
+
+	for _, at := range positions[1:] {
+		if distanceBetween(char.Position, at) > battleReach {
+			return nil, ErrOutOfReach
+		}
+	}
+	for _, p := range battle.Participants {
+		if gs.inBattle(p.ID) {
+			return nil, ErrCharacterInCombat
+		}
+	}
+
+	gs.battles[battle.ID] = battle
+	gs.markBattleDirty(battle.ID)
+
+	// Faster mobs act before the character gets a turn
+	gs.runMobTurns(battle)
+	return battle, nil
+}
+
+// GetBattle returns a battle the account has a character in
+func (gs *GameServer) GetBattle(owner, id string) (*combat.Battle, error) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	return gs.participantBattle(owner, id)
+}
+
+// GetBattleRecord returns the event stream of a battle the account has a
+// character in. Battles no longer in memory are read from storage. The seed
+// and rolls of a battle still being fought are left out, as they would
+// predict its next rolls.
+func (gs *GameServer) GetBattleRecord(owner, id string) (*combat.BattleRecord, error) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	record, err := gs.battleRecord(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if battle, exists := gs.battles[id]; exists && !battle.IsOver() {
+		record.Seed, record.Rolls = 0, 0
+	}
+	return record, nil
+}
+
+// battleRecord returns the complete event stream of a battle the account has
+// a character in; the caller must hold the mutex
+func (gs *GameServer) battleRecord(owner, id string) (*combat.BattleRecord, error) {
+	if battle, exists := gs.battles[id]; exists {
+		if len(gs.ownedParticipants(owner, battle)) == 0 {
+			return nil, ErrNotBattleParticipant
+		}
+		record := battle.Record()
+		if record == nil {
+			return nil, ErrBattleRecordNotFound
+		}
+		// Events keep being appended while the battle goes on
+		copied := *record
+		copied.Events = append([]combat.ReplayEvent(nil), record.Events...)
+		return &copied, nil
+	}
+
+	if gs.repo == nil {
+		return nil, ErrBattleNotFound
+	}
+	record, err := gs.repo.GetBattleRecord(id)
+	if err != nil || record.Initial == nil {
+		return nil, ErrBattleNotFound
+	}
+	if len(gs.ownedParticipants(owner, record.Initial)) == 0 {
+		return nil, ErrNotBattleParticipant
+	}
+	return record, nil
+}
+
+// GetBattleReport replays a battle the account has a character in and
+// summarizes it
+func (gs *GameServer) GetBattleReport(owner, id string) (*combat.BattleReport, error) {
+	gs.mutex.RLock()
+	record, err := gs.battleRecord(owner, id)
+	gs.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return record.Report()
+}
+
+// ListBattles returns the active battles the account has a character in
+func (gs *GameServer) ListBattles(owner string) []*combat.Battle {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	var battles []*combat.Battle
+	for _, battle := range gs.battles {
+		if !battle.IsOver() && len(gs.ownedParticipants(owner, battle)) > 0 {
+			battles = append(battles, battle)
+		}
+	}
+	sort.Slice(battles, func(i, j int) bool {
+		return battles[i].ID < battles[j].ID
+	})
+	return battles
+}
+
+// SubmitBattleAction uses an ability of the account's character whose turn it
+// is. The action is rejected unless version matches the battle's current
+// version, so a resubmitted request cannot act twice.
+func (gs *GameServer) SubmitBattleAction(owner, battleID string, version int, abilityName, targetID string) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
+	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	battle, actor, err := gs.ownTurn(owner, battleID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gs.playerAction(battle, actor.ID, abilityName, targetID); err != nil {
+		return nil, err
+	}
+	return battle, nil
+}
+
+// SubmitBattleMove moves the account's character whose turn it is on the
+// battle grid. Moving does not end the turn.
+func (gs *GameServer) SubmitBattleMove(owner, battleID string, version int, to common.Coordinates) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
+	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	battle, actor, err := gs.ownTurn(owner, battleID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gs.battleMove(battle, actor, func() error { return battle.Move(to) }); err != nil {
+		return nil, err
+	}
+	return battle, nil
+}
+
+// FleeBattle lets the account's character whose turn it is try to flee the
+// battle. A failed attempt ends the character's turn.
+func (gs *GameServer) FleeBattle(owner, battleID string, version int) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
+	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	battle, _, err := gs.ownTurn(owner, battleID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gs.battleFlee(battle); err != nil {
+		return nil, err
+	}
+	gs.runMobTurns(battle)
+	return battle, nil
+}
+
+// ForfeitBattle withdraws all of the account's characters from a battle
+func (gs *GameServer) ForfeitBattle(owner, battleID string) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
+	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	battle, err := gs.participantBattle(owner, battleID)
+	if err != nil {
+		return nil, err
+	}
+	if battle.IsOver() {
+		return nil, ErrBattleOver
+	}
+
+	levels := gs.playerLevels(battle)
+	for _, p := range gs.ownedParticipants(owner, battle) {
+		if p.IsActive {
+			if err := battle.Forfeit(p.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	gs.markBattleDirty(battle.ID)
+	for _, p := range battle.Participants {
+		if p.Type == "player" {
+			gs.markCharacterDirty(p.ID)
+		}
+	}
+
+	if battle.IsOver() {
+		gs.finishBattle(battle, levels)
+		return battle, nil
+	}
+	gs.publishTurnChanged(battle)
+	gs.runMobTurns(battle)
+	return battle, nil
+}
+
+// expireTurns skips the turns of characters that ran out of time to act and
+// lets the mobs answer. Characters that keep timing out forfeit. The caller
+// must hold the mutex.
+func (gs *GameServer) expireTurns(now time.Time) {
+	for _, battle := range gs.battles {
+		if !battle.TurnExpired(now) {
+			continue
+		}
+
+		levels := gs.playerLevels(battle)
+		if err := battle.TimeoutTurn(); err != nil {
+			continue
+		}
+		gs.markBattleDirty(battle.ID)
+		for _, p := range battle.Participants {
+			if p.Type == "player" {
+				gs.markCharacterDirty(p.ID)
+			}
+		}
+
+		if battle.IsOver() {
+			gs.finishBattle(battle, levels)
+			continue
+		}
+		gs.publishTurnChanged(battle)
+		gs.runMobTurns(battle)
+	}
+}
+
+// newBattle creates a battle fought where its participants stand. Its grid,
+// weather and steam bonus come from the terrain most of them stand on; the
+// caller must hold the mutex
+func (gs *GameServer) newBattle(battleType combat.BattleType, positions ...common.Coordinates) *combat.Battle {
+	terrain := gs.battlefield(positions)
+	battle := combat.NewBattle(battleType)
+	battle.SetTerrain(string(terrain.Type))
+	battle.SteamPowerBonus = terrain.SteamPowerBonus
+	return battle
+}
+
+// battlefield returns the terrain most of the positions lie on. Ties go to
+// the terrain of the earliest position; the caller must hold the mutex
+func (gs *GameServer) battlefield(positions []common.Coordinates) world.TerrainProperties {
+	terrains := make([]world.TerrainProperties, len(positions))
+	counts := make(map[world.TerrainType]int)
+	for i, at := range positions {
+		terrains[i] = gs.GetTerrainProperties(at.X, at.Y)
+		counts[terrains[i].Type]++
+	}
+
+	if len(terrains) == 0 {
+		return world.GetTerrainProperties(world.Plains)
+	}
+	best := terrains[0]
+	for _, terrain := range terrains[1:] {
+		if counts[terrain.Type] > counts[best.Type] {
+			best = terrain
+		}
+	}
+	return best
+}
+
+// restoreBattle reconnects a stored battle to the loaded characters and mobs
+func (gs *GameServer) restoreBattle(battle *combat.Battle) {
+	for _, p := range battle.Participants {
+		switch p.Type {
+		case "player":
+			if char, exists := gs.players[p.ID]; exists {
+				battle.Attach(combat.NewCharacterCombatant(char))
+			}
+		case "mob":
+			if m, exists := gs.mobs[p.ID]; exists {
+				battle.Attach(combat.NewMobCombatant(m))
+			}
+		}
+	}
+	// Nobody could act while the server was down
+	battle.TurnStartedAt = time.Now()
+	gs.battles[battle.ID] = battle
+	if battle.Public && !battle.IsOver() {
+		gs.openBroadcast(battle, battle.SpectatorDelay)
+	}
+}
+
+// pruneFinishedBattles drops battles from memory once they have been finished
+// for finishedBattleRetention; the caller must hold the mutex
+func (gs *GameServer) pruneFinishedBattles(now time.Time) {
+	for id, endedAt := range gs.finishedBattles {
+		if now.Sub(endedAt) > finishedBattleRetention && !gs.dirtyBattles[id] {
+			delete(gs.battles, id)
+			delete(gs.finishedBattles, id)
+		}
+	}
+}
+
+// participantBattle looks up a battle the account has a character in; the
+// caller must hold the mutex
+func (gs *GameServer) participantBattle(owner, id string) (*combat.Battle, error) {
+	battle, exists := gs.battles[id]
+	if !exists {
+		return nil, ErrBattleNotFound
+	}
+	if len(gs.ownedParticipants(owner, battle)) == 0 {
+		return nil, ErrNotBattleParticipant
+	}
+	return battle, nil
+}
+
+// ownTurn looks up a battle in which it is the turn of one of the account's
+// characters, checking that the battle is still at the given version; the
+// caller must hold the mutex
+func (gs *GameServer) ownTurn(owner, battleID string, version int) (*combat.Battle, *combat.Participant, error) {
+	battle, err := gs.participantBattle(owner, battleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if battle.IsOver() {
+		return nil, nil, ErrBattleOver
+	}
+	if battle.Version != version {
+		return nil, nil, ErrBattleConflict
+	}
+
+	actor := battle.ActiveParticipant()
+	if char, exists := gs.players[actor.ID]; !exists || char.Owner != owner {
+		return nil, nil, ErrNotYourTurn
+	}
+	return battle, actor, nil
+}
+
+// ownedParticipants returns the account's characters in a battle; the caller
+// must hold the mutex
+func (gs *GameServer) ownedParticipants(owner string, battle *combat.Battle) []*combat.Participant {
+	var owned []*combat.Participant
+	for _, p := range battle.Participants {
+		if char, exists := gs.players[p.ID]; exists && p.Type == "player" && char.Owner == owner {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+// fighting returns the IDs of the characters and mobs in active battles; the
+// caller must hold the mutex
+func (gs *GameServer) fighting() map[string]bool {
+	ids := make(map[string]bool)
+	for _, battle := range gs.battles {
+		if battle.IsOver() {
+			continue
+		}
+		for _, p := range battle.Participants {
+			ids[p.ID] = true
+		}
+	}
+	return ids
+}
+
+// inBattle reports whether a character or mob is fighting in an active
+// battle; the caller must hold the mutex
+func (gs *GameServer) inBattle(id string) bool {
+	for _, battle := range gs.battles {
+		if !battle.IsOver() && battle.GetParticipant(id) != nil {
+			return true
+		}
+	}
+	return false
+}
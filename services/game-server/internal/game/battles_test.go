@@ -0,0 +1,450 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+	"github.com/redfoxius/roleplay/services/game-server/internal/world"
+)
+
+// newBattleTestServer creates a server with a fast fighter owned by alice and
+// a slow one owned by bob
+func newBattleTestServer() *GameServer {
+	gs := newTestServer()
+	fast := newTestFighter("fast", 20)
+	fast.Owner = "alice"
+	slow := newTestFighter("slow", 10)
+	slow.Owner = "bob"
+	gs.players[fast.ID] = fast
+	gs.players[slow.ID] = slow
+	return gs
+}
+
+// startDuel starts a battle of alice's fast fighter against bob's slow one
+// through a challenge bob accepts
+func startDuel(t *testing.T, gs *GameServer) *combat.Battle {
+	t.Helper()
+	challenge, err := gs.Challenge("alice", "fast", []string{"slow"})
+	if err != nil {
+		t.Fatalf("Unexpected error challenging: %v", err)
+	}
+	_, battle, err := gs.AcceptChallenge("bob", challenge.ID, "slow")
+	if err != nil || battle == nil {
+		t.Fatalf("Expected the accepted challenge to start a battle, got %v", err)
+	}
+	return battle
+}
+
+func TestCreateBattle(t *testing.T) {
+	gs := newBattleTestServer()
+	spare := newTestFighter("spare", 10)
+	spare.Owner = "alice"
+	spare.Position = common.Coordinates{X: battleReach + 1}
+	gs.players[spare.ID] = spare
+
+	if _, err := gs.CreateBattle("bob", "fast", []string{"slow"}, nil); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	if _, err := gs.CreateBattle("alice", "fast", nil, nil); !errors.Is(err, ErrInvalidOpponents) {
+		t.Errorf("Expected ErrInvalidOpponents, got %v", err)
+	}
+	if _, err := gs.CreateBattle("alice", "fast", []string{"fast"}, nil); !errors.Is(err, ErrInvalidOpponents) {
+		t.Errorf("Expected ErrInvalidOpponents for fighting yourself, got %v", err)
+	}
+	if _, err := gs.CreateBattle("alice", "fast", []string{"slow"}, nil); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner for another account's character, got %v", err)
+	}
+	if _, err := gs.CreateBattle("alice", "fast", []string{"spare"}, nil); !errors.Is(err, ErrOutOfReach) {
+		t.Errorf("Expected ErrOutOfReach, got %v", err)
+	}
+
+	spare.Position = common.Coordinates{X: battleReach}
+	battle, err := gs.CreateBattle("alice", "fast", []string{"spare"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle.Type != combat.BattleTypePvP {
+		t.Errorf("Expected PvP battle, got %s", battle.Type)
+	}
+	if !gs.dirtyBattles[battle.ID] {
+		t.Error("Expected new battle to be marked dirty")
+	}
+
+	if _, err := gs.Challenge("bob", "slow", []string{"fast"}); !errors.Is(err, ErrCharacterInCombat) {
+		t.Errorf("Expected ErrCharacterInCombat, got %v", err)
+	}
+
+	if battles := gs.ListBattles("alice"); len(battles) != 1 || battles[0].ID != battle.ID {
+		t.Errorf("Expected alice to see the battle, got %v", battles)
+	}
+	if battles := gs.ListBattles("bob"); len(battles) != 0 {
+		t.Errorf("Expected bob to see no battles, got %d", len(battles))
+	}
+	if _, err := gs.GetBattle("bob", battle.ID); !errors.Is(err, ErrNotBattleParticipant) {
+		t.Errorf("Expected ErrNotBattleParticipant, got %v", err)
+	}
+}
+
+func TestBattlefield(t *testing.T) {
+	gs := newBattleTestServer()
+	gs.worldMap.Locations = map[common.Coordinates]*world.Location{
+		{X: 1, Y: 1}: {Terrain: world.Swamp},
+		{X: 2, Y: 1}: {Terrain: world.Swamp},
+		{X: 3, Y: 1}: {Terrain: world.Forest},
+		{X: 4, Y: 1}: {Terrain: world.SteamCity},
+	}
+
+	tests := []struct {
+		positions []common.Coordinates
+		expected  world.TerrainType
+	}{
+		{nil, world.Plains},
+		{[]common.Coordinates{{X: 3, Y: 1}, {X: 1, Y: 1}, {X: 2, Y: 1}}, world.Swamp},
+		{[]common.Coordinates{{X: 3, Y: 1}, {X: 1, Y: 1}}, world.Forest},
+		{[]common.Coordinates{{X: 9, Y: 9}, {X: 3, Y: 1}}, world.Plains},
+	}
+	for _, tt := range tests {
+		if terrain := gs.battlefield(tt.positions); terrain.Type != tt.expected {
+			t.Errorf("Expected %s battlefield for %v, got %s", tt.expected, tt.positions, terrain.Type)
+		}
+	}
+
+	battle := gs.newBattle(combat.BattleTypePvP, common.Coordinates{X: 4, Y: 1})
+	if battle.Terrain != string(world.SteamCity) {
+		t.Errorf("Expected a steam city battle, got %s", battle.Terrain)
+	}
+	if battle.SteamPowerBonus != world.GetTerrainProperties(world.SteamCity).SteamPowerBonus {
+		t.Errorf("Expected the steam city's steam bonus, got %d", battle.SteamPowerBonus)
+	}
+}
+
+func TestSubmitBattleAction(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+
+	if _, err := gs.SubmitBattleAction("bob", battle.ID, 0, "Wrench Strike", "fast"); !errors.Is(err, ErrNotYourTurn) {
+		t.Errorf("Expected ErrNotYourTurn, got %v", err)
+	}
+	if _, err := gs.SubmitBattleAction("alice", battle.ID, 0, "Wrench Strike", "slow"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Resubmitting the same request is rejected
+	if _, err := gs.SubmitBattleAction("alice", battle.ID, 0, "Wrench Strike", "slow"); !errors.Is(err, ErrBattleConflict) {
+		t.Errorf("Expected ErrBattleConflict, got %v", err)
+	}
+	if expected := 100 - battle.CombatLog[0].Damage; gs.players["slow"].Health != expected {
+		t.Errorf("Expected slow character health %d, got %d", expected, gs.players["slow"].Health)
+	}
+
+	if _, err := gs.SubmitBattleAction("bob", battle.ID, battle.Version, "Wrench Strike", "fast"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if battle.Version != 2 {
+		t.Errorf("Expected version 2, got %d", battle.Version)
+	}
+}
+
+func TestSubmitBattleMove(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+	if battle.Grid == nil {
+		t.Fatal("Expected battle to have a grid")
+	}
+
+	fast := battle.GetParticipant("fast")
+	to := common.Coordinates{X: fast.Position.X + 1, Y: fast.Position.Y}
+	if _, err := gs.SubmitBattleMove("bob", battle.ID, 0, to); !errors.Is(err, ErrNotYourTurn) {
+		t.Errorf("Expected ErrNotYourTurn, got %v", err)
+	}
+	if _, err := gs.SubmitBattleMove("alice", battle.ID, 0, to); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fast.Position != to {
+		t.Errorf("Expected position %v, got %v", to, fast.Position)
+	}
+	if gs.players["fast"].Position == to {
+		t.Error("Expected the world position to stay unchanged")
+	}
+	if _, err := gs.SubmitBattleMove("alice", battle.ID, 0, to); !errors.Is(err, ErrBattleConflict) {
+		t.Errorf("Expected ErrBattleConflict, got %v", err)
+	}
+
+	// The character still has its action after moving
+	if _, err := gs.SubmitBattleAction("alice", battle.ID, battle.Version, "Wrench Strike", "slow"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestChannelledClassAbility(t *testing.T) {
+	gs := newTestServer()
+	gs.worldMap.Locations = map[common.Coordinates]*world.Location{}
+	mage, err := gs.CreateCharacter("alice", "Vesper", character.SteamMage)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for mage.Level < 20 {
+		mage.LevelUp()
+	}
+	golem := &mob.Mob{
+		ID:            "golem",
+		Name:          "Brass Golem",
+		Health:        1000,
+		MaxHealth:     1000,
+		SteamPower:    20,
+		MaxSteamPower: 20,
+		Position:      mage.Position,
+		Abilities: []common.Ability{
+			{Name: "Slam", Type: "damage", Damage: 5, SteamCost: 5},
+		},
+	}
+	gs.mobs[golem.ID] = golem
+
+	battle, err := gs.CreateBattle("alice", mage.ID, nil, []string{golem.ID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The cannon reaches 4 tiles, so the mage walks up to the golem first
+	at := battle.GetParticipant(golem.ID).Position
+	if _, err := gs.SubmitBattleMove("alice", battle.ID, battle.Version, common.Coordinates{X: at.X - 4, Y: at.Y}); err != nil {
+		t.Fatalf("Unexpected error moving: %v", err)
+	}
+	if _, err := gs.SubmitBattleAction("alice", battle.ID, battle.Version, "Pressure Cannon", golem.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The golem acts while the cannon builds up, and it fires at the start of
+	// the mage's next turn
+	if _, channelling := battle.Channels[mage.ID]; channelling || golem.Health == golem.MaxHealth {
+		t.Errorf("Expected the cannon to have fired, got golem at %d health", golem.Health)
+	}
+	fired := false
+	for _, entry := range battle.CombatLog {
+		if entry.Character == mage.Name && entry.Action == "Pressure Cannon" && entry.Target == golem.Name && entry.Damage > 0 {
+			fired = true
+		}
+	}
+	if !fired {
+		t.Errorf("Expected the cannon's hit in the log, got %+v", battle.CombatLog)
+	}
+	if battle.ActiveParticipant().ID != mage.ID {
+		t.Errorf("Expected the mage to act after the cannon fired, got %s", battle.ActiveParticipant().ID)
+	}
+}
+
+func TestBattleRecordHidesSeed(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+	if _, err := gs.SubmitBattleAction("alice", battle.ID, 0, "Wrench Strike", "slow"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	record, err := gs.GetBattleRecord("alice", battle.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if record.Seed != 0 || record.Rolls != 0 {
+		t.Errorf("Expected the seed of an active battle to be hidden, got seed %d and %d rolls", record.Seed, record.Rolls)
+	}
+	if battle.Record().Seed != battle.Seed {
+		t.Error("Expected the battle to keep the seed of its record")
+	}
+	if _, err := gs.GetBattleReport("alice", battle.ID); err != nil {
+		t.Errorf("Expected the report to replay from the hidden seed, got %v", err)
+	}
+
+	gs.ForfeitBattle("bob", battle.ID)
+	if record, _ = gs.GetBattleRecord("alice", battle.ID); record.Seed != battle.Seed {
+		t.Errorf("Expected the seed %d of a finished battle, got %d", battle.Seed, record.Seed)
+	}
+}
+
+func TestForfeitBattle(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+
+	if _, err := gs.ForfeitBattle("bob", battle.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !battle.IsOver() {
+		t.Fatal("Expected battle to be over")
+	}
+	if len(battle.Winners) != 1 || battle.Winners[0] != "fast" {
+		t.Errorf("Expected fast character to win, got %v", battle.Winners)
+	}
+	if _, err := gs.ForfeitBattle("alice", battle.ID); !errors.Is(err, ErrBattleOver) {
+		t.Errorf("Expected ErrBattleOver, got %v", err)
+	}
+	if battles := gs.ListBattles("alice"); len(battles) != 0 {
+		t.Errorf("Expected no active battles, got %d", len(battles))
+	}
+
+	// Finished battles are dropped from memory once they have been saved
+	delete(gs.dirtyBattles, battle.ID)
+	gs.pruneFinishedBattles(time.Now().Add(finishedBattleRetention + time.Minute))
+	if _, exists := gs.battles[battle.ID]; exists {
+		t.Error("Expected finished battle to be pruned")
+	}
+}
+
+func TestFleeBattle(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+
+	if _, err := gs.FleeBattle("bob", battle.ID, battle.Version); !errors.Is(err, ErrNotYourTurn) {
+		t.Errorf("Expected ErrNotYourTurn, got %v", err)
+	}
+	if _, err := gs.FleeBattle("alice", battle.ID, battle.Version+1); !errors.Is(err, ErrBattleConflict) {
+		t.Errorf("Expected ErrBattleConflict, got %v", err)
+	}
+	if _, err := gs.FleeBattle("alice", battle.ID, battle.Version); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Whether or not the escape succeeded, the character's turn is over
+	fled := battle.GetParticipant("fast").Fled
+	if fled && (!battle.IsOver() || battle.Winners[0] != "slow") {
+		t.Errorf("Expected slow character to win after fast fled, got %v", battle.Winners)
+	}
+	if !fled && battle.ActiveParticipant().ID != "slow" {
+		t.Errorf("Expected slow character's turn after a failed escape, got %s", battle.ActiveParticipant().ID)
+	}
+}
+
+func TestTurnTimeouts(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+
+	gs.expireTurns(battle.TurnStartedAt.Add(time.Second))
+	if battle.ActiveParticipant().ID != "fast" {
+		t.Fatalf("Expected fast character to still have the turn, got %s", battle.ActiveParticipant().ID)
+	}
+
+	// Both characters are away; every expired turn is skipped until one of
+	// them has timed out too often and forfeits
+	for i := 0; i < 10 && !battle.IsOver(); i++ {
+		gs.expireTurns(battle.TurnStartedAt.Add(combat.DefaultTurnTimeout))
+	}
+	if !battle.IsOver() {
+		t.Fatal("Expected battle to end by forfeit")
+	}
+	if len(battle.Winners) != 1 || battle.Winners[0] != "slow" {
+		t.Errorf("Expected slow character to win, got %v", battle.Winners)
+	}
+	if !gs.dirtyBattles[battle.ID] {
+		t.Error("Expected battle to be marked dirty")
+	}
+}
+
+func TestRestoreBattle(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+
+	// Simulate a restart: the battle comes back from storage as JSON
+	data, err := json.Marshal(battle)
+	if err != nil {
+		t.Fatalf("Failed to encode battle: %v", err)
+	}
+	var stored combat.Battle
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("Failed to decode battle: %v", err)
+	}
+	stored.Relink()
+
+	restarted := newBattleTestServer()
+	restarted.restoreBattle(&stored)
+
+	if _, err := restarted.SubmitBattleAction("alice", battle.ID, 0, "Wrench Strike", "slow"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := 100 - stored.CombatLog[0].Damage; restarted.players["slow"].Health != expected {
+		t.Errorf("Expected restored battle to leave the character at %d health, got %d", expected, restarted.players["slow"].Health)
+	}
+	if stored.ActiveParticipant().ID != "slow" {
+		t.Errorf("Expected slow character's turn, got %s", stored.ActiveParticipant().ID)
+	}
+}
+
+func TestBattleRoutes(t *testing.T) {
+	gs := newBattleTestServer()
+
+	r := mux.NewRouter()
+	NewHandler(gs).RegisterRoutes(r)
+
+	serve := func(method, path, body, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), "username", username))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("POST", "/api/battles", `{"character_id":"fast","opponent_ids":["slow"]}`, "alice")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d for another account's character, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+	id := startDuel(t, gs).ID
+
+	action := `{"version":0,"ability":"Wrench Strike","target_id":"slow"}`
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		username string
+		status   int
+	}{
+		{"list", "GET", "/api/battles", "", "alice", http.StatusOK},
+		{"get", "GET", "/api/battles/" + id, "", "bob", http.StatusOK},
+		{"get as outsider", "GET", "/api/battles/" + id, "", "carol", http.StatusForbidden},
+		{"get missing", "GET", "/api/battles/missing", "", "alice", http.StatusNotFound},
+		{"action out of turn", "POST", "/api/battles/" + id + "/action", action, "bob", http.StatusConflict},
+		{"action", "POST", "/api/battles/" + id + "/action", action, "alice", http.StatusOK},
+		{"double submit", "POST", "/api/battles/" + id + "/action", action, "alice", http.StatusConflict},
+		{"move out of turn", "POST", "/api/battles/" + id + "/move", `{"version":0,"x":1,"y":3}`, "alice", http.StatusConflict},
+		{"forfeit", "POST", "/api/battles/" + id + "/forfeit", "", "bob", http.StatusOK},
+		{"action after end", "POST", "/api/battles/" + id + "/action", action, "alice", http.StatusConflict},
+		{"report", "GET", "/api/battles/" + id + "/report", "", "bob", http.StatusOK},
+		{"report as outsider", "GET", "/api/battles/" + id + "/report", "", "carol", http.StatusForbidden},
+		{"report missing", "GET", "/api/battles/missing/report", "", "alice", http.StatusNotFound},
+		{"export", "GET", "/api/battles/" + id + "/export", "", "alice", http.StatusOK},
+		{"export unknown format", "GET", "/api/battles/" + id + "/export?format=xml", "", "alice", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.path, tt.body, tt.username)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w = serve("GET", "/api/battles", "", "alice")
+	var response ListBattlesResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Battles) != 0 {
+		t.Errorf("Expected no active battles after forfeit, got %d", len(response.Battles))
+	}
+
+	w = serve("GET", "/api/battles/"+id+"/export?format=text", "", "alice")
+	if w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("Expected a plain text transcript, got %s", w.Header().Get("Content-Type"))
+	}
+	if transcript := w.Body.String(); !strings.Contains(transcript, "Wrench Strike on") || !strings.Contains(transcript, "Forfeit") {
+		t.Errorf("Expected the transcript to show the attack and the forfeit, got:\n%s", transcript)
+	}
+}
@@ -0,0 +1,206 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+)
+
+// challengeTimeout is how long a challenge waits for its opponents to accept
+const challengeTimeout = 2 * time.Minute
+
+var (
+	// ErrChallengeNotFound is returned when a challenge does not exist, has
+	// expired or does not concern the account
+	ErrChallengeNotFound = errors.New("challenge not found")
+	// ErrNotChallenged is returned when accepting a challenge with a character it was not sent to
+	ErrNotChallenged = errors.New("character is not challenged")
+)
+
+// Challenge invites characters of other accounts to a PvP battle against an
+// account's character. The battle starts once every opponent has accepted.
+type Challenge struct {
+	ID          string
+	Owner       string
+	CharacterID string
+	OpponentIDs []string
+	Accepted    []string // opponents that accepted
+	ExpiresAt   time.Time
+}
+
+// accepted reports whether an opponent accepted the challenge
+func (c *Challenge) accepted(id string) bool {
+	for _, accepted := range c.Accepted {
+		if accepted == id {
+			return true
+		}
+	}
+	return false
+}
+
+// challenged reports whether a character is among the challenge's opponents
+func (c *Challenge) challenged(id string) bool {
+	for _, opponent := range c.OpponentIDs {
+		if opponent == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Challenge challenges characters within reach to a PvP battle against an
+// account's character
+func (gs *GameServer) Challenge(owner, characterID string, opponentIDs []string) (*Challenge, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	char, err := gs.ownedCharacter(owner, characterID)
+	if err != nil {
+		return nil, err
+	}
+	if len(opponentIDs) == 0 {
+		return nil, ErrInvalidOpponents
+	}
+	seen := map[string]bool{characterID: true}
+	for _, id := range opponentIDs {
+		opponent, exists := gs.players[id]
+		if !exists || seen[id] {
+			return nil, ErrInvalidOpponents
+		}
+		seen[id] = true
+		if distanceBetween(char.Position, opponent.Position) > battleReach {
+			return nil, ErrOutOfReach
+		}
+	}
+	for id := range seen {
+		if gs.inBattle(id) {
+			return nil, ErrCharacterInCombat
+		}
+	}
+
+	challenge := &Challenge{
+		ID:          uuid.New().String(),
+		Owner:       owner,
+		CharacterID: characterID,
+		OpponentIDs: append([]string(nil), opponentIDs...),
+		ExpiresAt:   time.Now().Add(challengeTimeout),
+	}
+	gs.challenges[challenge.ID] = challenge
+	gs.publishTo(EventChallenged, challengeEvent(challenge), challenge.OpponentIDs...)
+
+	issued := *challenge
+	return &issued, nil
+}
+
+// AcceptChallenge accepts a challenge with an account's challenged character.
+// Once every opponent has accepted the battle starts and is returned.
+func (gs *GameServer) AcceptChallenge(owner, challengeID, characterID string) (*Challenge, *combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, nil, err
+	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	challenge, exists := gs.challenges[challengeID]
+	if !exists || time.Now().After(challenge.ExpiresAt) {
+		return nil, nil, ErrChallengeNotFound
+	}
+	if _, err := gs.ownedCharacter(owner, characterID); err != nil {
+		return nil, nil, err
+	}
+	if !challenge.challenged(characterID) {
+		return nil, nil, ErrNotChallenged
+	}
+	if !challenge.accepted(characterID) {
+		challenge.Accepted = append(challenge.Accepted, characterID)
+	}
+
+	accepted := *challenge
+	if len(challenge.Accepted) < len(challenge.OpponentIDs) {
+		return &accepted, nil, nil
+	}
+
+	char, exists := gs.players[challenge.CharacterID]
+	if !exists {
+		return nil, nil, ErrCharacterNotFound
+	}
+	battle, err := gs.startBattle(char, challenge.OpponentIDs, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	delete(gs.challenges, challenge.ID)
+	return &accepted, battle, nil
+}
+
+// DeclineChallenge withdraws a challenge the account issued, or declines one
+// sent to one of its characters
+func (gs *GameServer) DeclineChallenge(owner, challengeID string) error {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	challenge, exists := gs.challenges[challengeID]
+	if !exists || !gs.concerns(owner, challenge) {
+		return ErrChallengeNotFound
+	}
+	delete(gs.challenges, challenge.ID)
+	return nil
+}
+
+// ListChallenges returns the open challenges the account issued or received
+func (gs *GameServer) ListChallenges(owner string) []*Challenge {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	now := time.Now()
+	challenges := make([]*Challenge, 0)
+	for _, challenge := range gs.challenges {
+		if now.After(challenge.ExpiresAt) || !gs.concerns(owner, challenge) {
+			continue
+		}
+		listed := *challenge
+		challenges = append(challenges, &listed)
+	}
+	sort.Slice(challenges, func(i, j int) bool {
+		return challenges[i].ExpiresAt.Before(challenges[j].ExpiresAt)
+	})
+	return challenges
+}
+
+// concerns reports whether the account issued a challenge or owns one of its
+// opponents; the caller must hold the mutex
+func (gs *GameServer) concerns(owner string, challenge *Challenge) bool {
+	if challenge.Owner == owner {
+		return true
+	}
+	for _, id := range challenge.OpponentIDs {
+		if char, exists := gs.players[id]; exists && char.Owner == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// expireChallenges drops the challenges nobody accepted in time; the caller
+// must hold the mutex
+func (gs *GameServer) expireChallenges(now time.Time) {
+	for id, challenge := range gs.challenges {
+		if now.After(challenge.ExpiresAt) {
+			delete(gs.challenges, id)
+		}
+	}
+}
+
+// challengeEvent builds the event telling characters they were challenged
+func challengeEvent(challenge *Challenge) ChallengedEvent {
+	return ChallengedEvent{
+		ChallengeID: challenge.ID,
+		CharacterID: challenge.CharacterID,
+		OpponentIDs: challenge.OpponentIDs,
+		ExpiresAt:   challenge.ExpiresAt,
+	}
+}
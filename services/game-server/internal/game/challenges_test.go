@@ -0,0 +1,151 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+func TestChallenge(t *testing.T) {
+	gs := newBattleTestServer()
+	far := newTestFighter("far", 10)
+	far.Owner = "carol"
+	far.Position = common.Coordinates{X: battleReach + 1}
+	gs.players[far.ID] = far
+	sub := gs.events.Subscribe("slow")
+
+	if _, err := gs.Challenge("bob", "fast", []string{"slow"}); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	if _, err := gs.Challenge("alice", "fast", []string{"slow", "slow"}); !errors.Is(err, ErrInvalidOpponents) {
+		t.Errorf("Expected ErrInvalidOpponents for a repeated opponent, got %v", err)
+	}
+	if _, err := gs.Challenge("alice", "fast", []string{"far"}); !errors.Is(err, ErrOutOfReach) {
+		t.Errorf("Expected ErrOutOfReach, got %v", err)
+	}
+
+	challenge, err := gs.Challenge("alice", "fast", []string{"slow"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if event := <-sub.Events; event.Type != EventChallenged || event.Data.(ChallengedEvent).ChallengeID != challenge.ID {
+		t.Errorf("Expected slow to be told about the challenge, got %+v", event)
+	}
+	if len(gs.battles) != 0 {
+		t.Error("Expected no battle before the challenge is accepted")
+	}
+	if challenges := gs.ListChallenges("bob"); len(challenges) != 1 || challenges[0].ID != challenge.ID {
+		t.Errorf("Expected bob to see the challenge, got %v", challenges)
+	}
+	if challenges := gs.ListChallenges("carol"); len(challenges) != 0 {
+		t.Errorf("Expected carol to see no challenges, got %d", len(challenges))
+	}
+
+	if _, _, err := gs.AcceptChallenge("alice", challenge.ID, "fast"); !errors.Is(err, ErrNotChallenged) {
+		t.Errorf("Expected ErrNotChallenged, got %v", err)
+	}
+	if _, _, err := gs.AcceptChallenge("alice", challenge.ID, "slow"); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	_, battle, err := gs.AcceptChallenge("bob", challenge.ID, "slow")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle == nil || battle.GetParticipant("fast") == nil || battle.GetParticipant("slow") == nil {
+		t.Fatalf("Expected the accepted challenge to start a battle, got %+v", battle)
+	}
+	if _, _, err := gs.AcceptChallenge("bob", challenge.ID, "slow"); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("Expected the started challenge to be gone, got %v", err)
+	}
+}
+
+func TestChallengeSeveralOpponents(t *testing.T) {
+	gs := newBattleTestServer()
+	third := newTestFighter("third", 10)
+	third.Owner = "carol"
+	gs.players[third.ID] = third
+
+	challenge, err := gs.Challenge("alice", "fast", []string{"slow", "third"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	accepted, battle, err := gs.AcceptChallenge("bob", challenge.ID, "slow")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle != nil || len(accepted.Accepted) != 1 {
+		t.Errorf("Expected the battle to wait for carol, got %+v", accepted)
+	}
+	if _, battle, err = gs.AcceptChallenge("carol", challenge.ID, "third"); err != nil || battle == nil {
+		t.Fatalf("Expected the battle to start once everybody accepted, got %v", err)
+	}
+	if len(battle.Participants) != 3 {
+		t.Errorf("Expected 3 participants, got %d", len(battle.Participants))
+	}
+}
+
+func TestDeclineChallenge(t *testing.T) {
+	gs := newBattleTestServer()
+	challenge, _ := gs.Challenge("alice", "fast", []string{"slow"})
+
+	if err := gs.DeclineChallenge("carol", challenge.ID); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("Expected ErrChallengeNotFound for an outsider, got %v", err)
+	}
+	if err := gs.DeclineChallenge("bob", challenge.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, _, err := gs.AcceptChallenge("bob", challenge.ID, "slow"); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("Expected the declined challenge to be gone, got %v", err)
+	}
+
+	// Challenges nobody answers expire
+	challenge, _ = gs.Challenge("alice", "fast", []string{"slow"})
+	gs.expireChallenges(challenge.ExpiresAt.Add(time.Second))
+	if len(gs.challenges) != 0 {
+		t.Errorf("Expected the challenge to expire, got %d challenges", len(gs.challenges))
+	}
+}
+
+func TestChallengeRoutes(t *testing.T) {
+	gs := newBattleTestServer()
+	challenge, _ := gs.Challenge("alice", "fast", []string{"slow"})
+
+	r := mux.NewRouter()
+	NewHandler(gs).RegisterRoutes(r)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		username string
+		status   int
+	}{
+		{"create", "POST", "/api/challenges", `{"character_id":"slow","opponent_ids":["fast"]}`, "bob", http.StatusCreated},
+		{"create for another account", "POST", "/api/challenges", `{"character_id":"fast","opponent_ids":["slow"]}`, "bob", http.StatusForbidden},
+		{"list", "GET", "/api/challenges", "", "bob", http.StatusOK},
+		{"accept as challenger", "POST", "/api/challenges/" + challenge.ID + "/accept", `{"character_id":"fast"}`, "alice", http.StatusForbidden},
+		{"accept missing", "POST", "/api/challenges/missing/accept", `{"character_id":"slow"}`, "bob", http.StatusNotFound},
+		{"decline as outsider", "DELETE", "/api/challenges/" + challenge.ID, "", "carol", http.StatusNotFound},
+		{"accept", "POST", "/api/challenges/" + challenge.ID + "/accept", `{"character_id":"slow"}`, "bob", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "username", tt.username))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+)
+
+const (
+	// maxCharactersPerAccount is how many characters an account may own
+	maxCharactersPerAccount = 5
+	// characterRestoreWindow is how long a deleted character can be restored
+	characterRestoreWindow = 7 * 24 * time.Hour
+	// purgeInterval is how often expired deleted characters are purged
+	purgeInterval = time.Minute
+)
+
+var (
+	// ErrCharacterNotFound is returned when a character does not exist
+	ErrCharacterNotFound = errors.New("character not found")
+	// ErrNotCharacterOwner is returned when an account accesses another account's character
+	ErrNotCharacterOwner = errors.New("character belongs to another account")
+	// ErrCharacterLimit is returned when an account already owns the maximum number of characters
+	ErrCharacterLimit = errors.New("character limit reached")
+	// ErrCharacterInCombat is returned when a character or mob in combat is deleted, moved or sent into another fight
+	ErrCharacterInCombat = errors.New("character is in combat")
+	// ErrMoveTooFar is returned when a character is moved further than maxMoveDistance at once
+	ErrMoveTooFar = errors.New("move is too far")
+	// ErrRestoreExpired is returned when the restore window of a deleted character has passed
+	ErrRestoreExpired = errors.New("restore window has expired")
+)
+
+// ListCharacters returns the active characters owned by an account, sorted by name
+func (gs *GameServer) ListCharacters(owner string) []*character.Character {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	var chars []*character.Character
+	for _, char := range gs.players {
+		if char.Owner == owner {
+			chars = append(chars, char)
+		}
+	}
+	sort.Slice(chars, func(i, j int) bool {
+		return chars[i].Name < chars[j].Name
+	})
+	return chars
+}
+
+// GetCharacter returns an active character owned by an account
+func (gs *GameServer) GetCharacter(owner, id string) (*character.Character, error) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	return gs.ownedCharacter(owner, id)
+}
+
+// GetCombatStats returns the lifetime combat statistics of a character owned by an account
+func (gs *GameServer) GetCombatStats(owner, characterID string) (combat.CombatStats, error) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	if _, err := gs.ownedCharacter(owner, characterID); err != nil {
+		return combat.CombatStats{}, err
+	}
+	stats, exists := gs.combatStats[characterID]
+	if !exists {
+		return *combat.NewCombatStats(), nil
+	}
+	return *stats, nil
+}
+
+// RenameCharacter changes the name of a character owned by an account
+func (gs *GameServer) RenameCharacter(owner, id, name string) (*character.Character, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	char, err := gs.ownedCharacter(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := gs.names.Rename(char.Name, name, id); err != nil {
+		return nil, err
+	}
+
+	// The name changes in storage right away, so, as in CreateCharacter, the
+	// character is saved with it rather than by the next autosave
+	oldName := char.Name
+	char.Name = name
+	if gs.repo != nil {
+		if err := gs.repo.SaveCharacter(char); err != nil {
+			char.Name = oldName
+			if err := gs.names.Rename(name, oldName, id); err != nil {
+				log.Printf("Failed to restore name of unsaved character %s: %v", id, err)
+			}
+			return nil, fmt.Errorf("failed to save character: %v", err)
+		}
+	} else {
+		gs.markCharacterDirty(id)
+	}
+	return char, nil
+}
+
+// DeleteCharacter soft deletes a character owned by an account. The character
+// can be restored until characterRestoreWindow has passed.
+func (gs *GameServer) DeleteCharacter(owner, id string, now time.Time) error {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	char, err := gs.ownedCharacter(owner, id)
+	if err != nil {
+		return err
+	}
+	if gs.inBattle(id) {
+		return ErrCharacterInCombat
+	}
+
+	deletedAt := now
+	char.DeletedAt = &deletedAt
+	delete(gs.players, id)
+	gs.removedCharacters[id] = char
+	gs.markCharacterDirty(id)
+	return nil
+}
+
+// RestoreCharacter brings back a soft deleted character owned by an account
+func (gs *GameServer) RestoreCharacter(owner, id string, now time.Time) (*character.Character, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	char, exists := gs.removedCharacters[id]
+	if !exists {
+		return nil, ErrCharacterNotFound
+	}
+	if char.Owner != owner {
+		return nil, ErrNotCharacterOwner
+	}
+	if now.Sub(*char.DeletedAt) > characterRestoreWindow {
+		return nil, ErrRestoreExpired
+	}
+	if gs.ownedCount(owner) >= maxCharactersPerAccount {
+		return nil, ErrCharacterLimit
+	}
+
+	char.DeletedAt = nil
+	delete(gs.removedCharacters, id)
+	gs.players[id] = char
+	gs.markCharacterDirty(id)
+	return char, nil
+}
+
+// purgeDeletedCharacters permanently removes characters whose restore window
+// has passed; the caller must hold the mutex
+func (gs *GameServer) purgeDeletedCharacters(now time.Time) {
+	for id, char := range gs.removedCharacters {
+		if now.Sub(*char.DeletedAt) > characterRestoreWindow {
+			delete(gs.removedCharacters, id)
+			delete(gs.effects, id)
+			for _, ratings := range gs.ratings {
+				delete(ratings, id)
+			}
+			gs.markCharacterPurged(id)
+			if err := gs.names.Release(char.Name, id); err != nil {
+				log.Printf("Failed to release name of purged character %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// ownedCharacter looks up an active character and checks its owner; the caller must hold the mutex
+func (gs *GameServer) ownedCharacter(owner, id string) (*character.Character, error) {
+	char, exists := gs.players[id]
+	if !exists {
+		return nil, ErrCharacterNotFound
+	}
+	if char.Owner != owner {
+		return nil, ErrNotCharacterOwner
+	}
+	return char, nil
+}
+
+// ownedCount returns how many active characters an account owns; the caller must hold the mutex
+func (gs *GameServer) ownedCount(owner string) int {
+	count := 0
+	for _, char := range gs.players {
+		if char.Owner == owner {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,174 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+)
+
+func TestCreateCharacterRecordsOwner(t *testing.T) {
+	gs := newTestServer()
+
+	char, err := gs.CreateCharacter("alice", "Cogsworth", character.Engineer)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if char.Owner != "alice" {
+		t.Errorf("Expected owner alice, got %s", char.Owner)
+	}
+	if gs.players[char.ID] != char {
+		t.Error("Expected character to be stored by ID")
+	}
+	if !gs.dirtyCharacters[char.ID] {
+		t.Error("Expected new character to be marked dirty")
+	}
+
+	if _, err := gs.CreateCharacter("bob", "Cogsworth", character.Engineer); err == nil {
+		t.Error("Expected error for duplicate name")
+	}
+}
+
+func TestCreateCharacterLimit(t *testing.T) {
+	gs := newTestServer()
+
+	for i := 0; i < maxCharactersPerAccount; i++ {
+		if _, err := gs.CreateCharacter("alice", "Hero"+string(rune('A'+i)), character.Engineer); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if _, err := gs.CreateCharacter("alice", "OneTooMany", character.Engineer); !errors.Is(err, ErrCharacterLimit) {
+		t.Errorf("Expected ErrCharacterLimit, got %v", err)
+	}
+	if _, err := gs.CreateCharacter("bob", "OneTooMany", character.Engineer); err != nil {
+		t.Errorf("Expected other accounts to be unaffected, got %v", err)
+	}
+}
+
+func TestCharacterOwnership(t *testing.T) {
+	gs := newTestServer()
+	char, _ := gs.CreateCharacter("alice", "Cogsworth", character.Engineer)
+
+	if _, err := gs.GetCharacter("bob", char.ID); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	if _, err := gs.RenameCharacter("bob", char.ID, "Stolen"); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	if err := gs.DeleteCharacter("bob", char.ID, time.Now()); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	if _, err := gs.GetCharacter("alice", "missing"); !errors.Is(err, ErrCharacterNotFound) {
+		t.Errorf("Expected ErrCharacterNotFound, got %v", err)
+	}
+
+	renamed, err := gs.RenameCharacter("alice", char.ID, "Gearheart")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if renamed.Name != "Gearheart" {
+		t.Errorf("Expected name Gearheart, got %s", renamed.Name)
+	}
+}
+
+func TestDeleteAndRestoreCharacter(t *testing.T) {
+	gs := newTestServer()
+	char, _ := gs.CreateCharacter("alice", "Cogsworth", character.Engineer)
+	now := time.Now()
+
+	if err := gs.DeleteCharacter("alice", char.ID, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(gs.ListCharacters("alice")) != 0 {
+		t.Error("Expected deleted character to be hidden from the list")
+	}
+	if _, err := gs.CreateCharacter("bob", "Cogsworth", character.Engineer); err == nil {
+		t.Error("Expected name to stay reserved while the character can be restored")
+	}
+
+	restored, err := gs.RestoreCharacter("alice", char.ID, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Error("Expected DeletedAt to be cleared")
+	}
+	if len(gs.ListCharacters("alice")) != 1 {
+		t.Error("Expected restored character to be listed")
+	}
+
+	gs.DeleteCharacter("alice", char.ID, now)
+	if _, err := gs.RestoreCharacter("alice", char.ID, now.Add(characterRestoreWindow+time.Hour)); !errors.Is(err, ErrRestoreExpired) {
+		t.Errorf("Expected ErrRestoreExpired, got %v", err)
+	}
+
+	gs.purgeDeletedCharacters(now.Add(characterRestoreWindow + time.Hour))
+	if _, exists := gs.removedCharacters[char.ID]; exists {
+		t.Error("Expected expired character to be purged")
+	}
+	if !gs.purgedCharacters[char.ID] {
+		t.Error("Expected purged character to be marked for deletion from storage")
+	}
+}
+
+func TestCharacterRoutes(t *testing.T) {
+	gs := newTestServer()
+	char, _ := gs.CreateCharacter("alice", "Cogsworth", character.Engineer)
+
+	r := mux.NewRouter()
+	NewHandler(gs).RegisterRoutes(r)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		username string
+		status   int
+	}{
+		{"list", "GET", "/api/characters", "", "alice", http.StatusOK},
+		{"get", "GET", "/api/character/" + char.ID, "", "alice", http.StatusOK},
+		{"get other account", "GET", "/api/character/" + char.ID, "", "bob", http.StatusForbidden},
+		{"get missing", "GET", "/api/character/missing", "", "alice", http.StatusNotFound},
+		{"inventory", "GET", "/api/character/" + char.ID + "/inventory", "", "alice", http.StatusOK},
+		{"equipment", "GET", "/api/character/" + char.ID + "/equipment", "", "alice", http.StatusOK},
+		{"abilities", "GET", "/api/character/" + char.ID + "/abilities", "", "alice", http.StatusOK},
+		{"rename", "PUT", "/api/character/" + char.ID, `{"name":"Gearheart"}`, "alice", http.StatusOK},
+		{"delete", "DELETE", "/api/character/" + char.ID, "", "alice", http.StatusNoContent},
+		{"restore", "POST", "/api/character/" + char.ID + "/restore", "", "alice", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "username", tt.username))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/api/characters", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "username", "alice"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response ListCharactersResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Characters) != 1 || response.Characters[0].Name != "Gearheart" {
+		t.Errorf("Expected renamed character in list, got %v", response.Characters)
+	}
+}
@@ -0,0 +1,272 @@
+package game
+
+import (
+	"sync"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// EventType identifies the kind of real-time event sent to clients
+type EventType string
+
+const (
+	EventSnapshot       EventType = "snapshot"
+	EventCharacterMoved EventType = "character_moved"
+	EventMobMoved       EventType = "mob_moved"
+	EventMobSpawned     EventType = "mob_spawned"
+	EventMobDespawned   EventType = "mob_despawned"
+	EventTurnChanged    EventType = "turn_changed"
+	EventCombatMoved    EventType = "combat_moved"
+	EventDamage         EventType = "damage"
+	EventHealing        EventType = "healing"
+	EventStatusEffects  EventType = "status_effects"
+	EventCombatEnded    EventType = "combat_ended"
+	EventLoot           EventType = "loot"
+	EventLevelUp        EventType = "level_up"
+	EventMatchFound     EventType = "match_found"
+	EventRatingChanged  EventType = "rating_changed"
+	EventChallenged     EventType = "challenged"
+	EventSpectating     EventType = "spectating"
+	EventError          EventType = "error"
+)
+
+const (
+	// interestRadius is how far away positional events are still delivered
+	interestRadius = 15
+	// subscriberBuffer is how many events may queue for a slow client before dropping
+	subscriberBuffer = 64
+)
+
+// Event is a single real-time update delivered to subscribed clients
+type Event struct {
+	Type EventType   `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// SnapshotEvent is sent when a client connects and describes its surroundings
+type SnapshotEvent struct {
+	Character  *character.Character `json:"character"`
+	Characters []MovedEvent         `json:"characters"`
+	Mobs       []MovedEvent         `json:"mobs"`
+}
+
+// MovedEvent reports the position of a character or mob
+type MovedEvent struct {
+	ID       string             `json:"id"`
+	Name     string             `json:"name"`
+	Position common.Coordinates `json:"position"`
+}
+
+// DamageEvent reports damage or healing dealt by one combatant to another
+type DamageEvent struct {
+	BattleID     string `json:"battle_id,omitempty"`
+	SourceID     string `json:"source_id"`
+	TargetID     string `json:"target_id"`
+	Ability      string `json:"ability,omitempty"`
+	Amount       int    `json:"amount"`
+	TargetHealth int    `json:"target_health"`
+}
+
+// TurnChangedEvent reports whose turn it is in a battle
+type TurnChangedEvent struct {
+	BattleID          string `json:"battle_id"`
+	Round             int    `json:"round"`
+	ActiveCharacterID string `json:"active_character_id"`
+}
+
+// CombatMovedEvent reports a participant moving on a battle grid
+type CombatMovedEvent struct {
+	BattleID      string             `json:"battle_id"`
+	ParticipantID string             `json:"participant_id"`
+	Position      common.Coordinates `json:"position"`
+	MovementLeft  int                `json:"movement_left"`
+}
+
+// StatusEffectsEvent reports the status effects currently on a combatant
+type StatusEffectsEvent struct {
+	BattleID string                `json:"battle_id"`
+	TargetID string                `json:"target_id"`
+	Effects  []combat.StatusEffect `json:"effects"`
+}
+
+// CombatEndedEvent reports the outcome of a battle
+type CombatEndedEvent struct {
+	BattleID string `json:"battle_id,omitempty"`
+	Result   string `json:"result"`
+	WinnerID string `json:"winner_id,omitempty"`
+}
+
+// LootEvent reports the rewards a character received for winning a battle
+type LootEvent struct {
+	CharacterID string          `json:"character_id"`
+	BattleID    string          `json:"battle_id"`
+	Experience  int             `json:"experience"`
+	Money       common.Currency `json:"money"`
+	Items       []common.Item   `json:"items,omitempty"`
+}
+
+// LevelUpEvent reports a character reaching a new level
+type LevelUpEvent struct {
+	CharacterID string `json:"character_id"`
+	Level       int    `json:"level"`
+}
+
+// MatchFoundEvent reports the ranked battle the matchmaker started for a
+// queued character
+type MatchFoundEvent struct {
+	BattleID string     `json:"battle_id"`
+	Bracket  string     `json:"bracket"`
+	Teams    [][]string `json:"teams"`
+}
+
+// RatingChangedEvent reports a character's new rating after a ranked battle
+type RatingChangedEvent struct {
+	CharacterID string  `json:"character_id"`
+	BattleID    string  `json:"battle_id"`
+	Bracket     string  `json:"bracket"`
+	Rating      float64 `json:"rating"`
+	Change      float64 `json:"change"`
+}
+
+// ChallengedEvent reports a challenge to a PvP battle sent to a character
+type ChallengedEvent struct {
+	ChallengeID string    `json:"challenge_id"`
+	CharacterID string    `json:"character_id"`
+	OpponentIDs []string  `json:"opponent_ids"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ErrorEvent reports a rejected client command
+type ErrorEvent struct {
+	Message string `json:"message"`
+}
+
+// mobMovedEvent builds a MovedEvent for a mob
+func mobMovedEvent(m *mob.Mob) MovedEvent {
+	return MovedEvent{ID: m.ID, Name: m.Name, Position: m.Position}
+}
+
+// Subscriber receives the events relevant to one character, or the
+// broadcast of one battle for a spectator
+type Subscriber struct {
+	CharacterID string
+	BattleID    string // battle a spectator watches, empty for characters
+	Events      chan Event
+}
+
+// EventHub fans events out to subscribers
+type EventHub struct {
+	subscribers map[*Subscriber]bool
+	mutex       sync.RWMutex
+}
+
+// NewEventHub creates a new event hub
+func NewEventHub() *EventHub {
+	return &EventHub{
+		subscribers: make(map[*Subscriber]bool),
+	}
+}
+
+// Subscribe registers a subscriber for a character
+func (h *EventHub) Subscribe(characterID string) *Subscriber {
+	sub := &Subscriber{
+		CharacterID: characterID,
+		Events:      make(chan Event, subscriberBuffer),
+	}
+
+	h.mutex.Lock()
+	h.subscribers[sub] = true
+	h.mutex.Unlock()
+
+	return sub
+}
+
+// SubscribeSpectator registers a spectator of a battle
+func (h *EventHub) SubscribeSpectator(battleID string) *Subscriber {
+	sub := &Subscriber{
+		BattleID: battleID,
+		Events:   make(chan Event, subscriberBuffer),
+	}
+
+	h.mutex.Lock()
+	h.subscribers[sub] = true
+	h.mutex.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its event channel
+func (h *EventHub) Unsubscribe(sub *Subscriber) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.subscribers[sub] {
+		delete(h.subscribers, sub)
+		close(sub.Events)
+	}
+}
+
+// Publish delivers an event to every subscriber whose character is interested.
+// Events for clients that are not keeping up are dropped.
+func (h *EventHub) Publish(event Event, interested func(characterID string) bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for sub := range h.subscribers {
+		if sub.BattleID != "" || !interested(sub.CharacterID) {
+			continue
+		}
+		select {
+		case sub.Events <- event:
+		default:
+		}
+	}
+}
+
+// PublishToSpectators delivers an event to every spectator of a battle
+func (h *EventHub) PublishToSpectators(battleID string, event Event) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for sub := range h.subscribers {
+		if sub.BattleID != battleID || battleID == "" {
+			continue
+		}
+		select {
+		case sub.Events <- event:
+		default:
+		}
+	}
+}
+
+// Spectators returns how many spectators watch a battle
+func (h *EventHub) Spectators(battleID string) int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	count := 0
+	for sub := range h.subscribers {
+		if sub.BattleID == battleID && battleID != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// Send delivers an event to a single subscriber, dropping it if the client is not keeping up
+func (h *EventHub) Send(sub *Subscriber, event Event) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if !h.subscribers[sub] {
+		return
+	}
+	select {
+	case sub.Events <- event:
+	default:
+	}
+}
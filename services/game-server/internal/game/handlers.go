@@ -2,12 +2,16 @@ package game
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/redfoxius/roleplay/services/game-server/internal/character"
 	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
 	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/matchmaking"
 )
 
 // Handler handles HTTP requests for the game server
@@ -32,51 +36,225 @@ type CreateCharacterResponse struct {
 	Error     string               `json:"error,omitempty"`
 }
 
+// CharacterResponse represents the response for a single character
+type CharacterResponse struct {
+	Character *character.Character `json:"character"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// ListCharactersResponse represents the characters owned by the caller
+type ListCharactersResponse struct {
+	Characters []*character.Character `json:"characters"`
+}
+
+// RenameCharacterRequest represents a request to rename a character
+type RenameCharacterRequest struct {
+	Name string `json:"name"`
+}
+
+// InventoryResponse represents a character's inventory
+type InventoryResponse struct {
+	Inventory []common.Item `json:"inventory"`
+}
+
+// EquipmentResponse represents a character's equipped items
+type EquipmentResponse struct {
+	Equipment map[string]common.Item `json:"equipment"`
+}
+
+// AbilitiesResponse represents a character's abilities
+type AbilitiesResponse struct {
+	Abilities []common.Ability `json:"abilities"`
+}
+
+// CombatStatsResponse represents a character's lifetime combat statistics
+type CombatStatsResponse struct {
+	Stats combat.CombatStats `json:"stats"`
+}
+
 // StartCombatRequest represents a request to start combat
 type StartCombatRequest struct {
 	Participants []string `json:"participants"`
 }
 
-// StartCombatResponse represents the response for starting combat
-type StartCombatResponse struct {
-	GameID string              `json:"game_id"`
-	State  *combat.CombatState `json:"state"`
-	Error  string              `json:"error,omitempty"`
-}
-
 // StartMobCombatRequest represents a request to start combat with a mob
 type StartMobCombatRequest struct {
 	CharacterID string `json:"character_id"`
 	MobID       string `json:"mob_id"`
 }
 
-// StartMobCombatResponse represents the response for starting mob combat
-type StartMobCombatResponse struct {
-	Combat *combat.MobCombat `json:"combat"`
-	Error  string            `json:"error,omitempty"`
+// MobCombatActionRequest represents a request to use an ability on a mob
+type MobCombatActionRequest struct {
+	CharacterID string `json:"character_id"`
+	MobID       string `json:"mob_id"`
+	Ability     string `json:"ability"`
 }
 
-// MobCombatActionRequest represents a request to perform a combat action
-type MobCombatActionRequest struct {
-	CharacterID string          `json:"character_id"`
-	MobID       string          `json:"mob_id"`
-	Ability     *common.Ability `json:"ability"`
+// CreateBattleRequest represents a request to start a battle session
+type CreateBattleRequest struct {
+	CharacterID string   `json:"character_id"`
+	OpponentIDs []string `json:"opponent_ids"`
+	MobIDs      []string `json:"mob_ids"`
+}
+
+// CreateChallengeRequest represents a request to challenge characters of
+// other accounts to a PvP battle
+type CreateChallengeRequest struct {
+	CharacterID string   `json:"character_id"`
+	OpponentIDs []string `json:"opponent_ids"`
+}
+
+// AcceptChallengeRequest represents a request to accept a challenge with a character
+type AcceptChallengeRequest struct {
+	CharacterID string `json:"character_id"`
+}
+
+// ChallengeResponse represents a challenge, and the battle it started once
+// every opponent accepted
+type ChallengeResponse struct {
+	Challenge *Challenge     `json:"challenge,omitempty"`
+	Battle    *combat.Battle `json:"battle,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// ListChallengesResponse represents the challenges the caller issued or received
+type ListChallengesResponse struct {
+	Challenges []*Challenge `json:"challenges"`
+}
+
+// CreateRaidRequest represents a request to start a raid against a boss
+type CreateRaidRequest struct {
+	CharacterID string     `json:"character_id"`
+	BossID      string     `json:"boss_id"`
+	Teams       [][]string `json:"teams"`
+}
+
+// ListBossesResponse represents the bosses raids can be started against
+type ListBossesResponse struct {
+	Bosses []*combat.BossDefinition `json:"bosses"`
+}
+
+// BattleActionRequest represents an action submitted for the caller's turn
+type BattleActionRequest struct {
+	Version  int    `json:"version"`
+	Ability  string `json:"ability"`
+	TargetID string `json:"target_id"`
+}
+
+// BattleMoveRequest represents a request to move on a battle grid
+type BattleMoveRequest struct {
+	Version int `json:"version"`
+	X       int `json:"x"`
+	Y       int `json:"y"`
 }
 
-// MobCombatActionResponse represents the response for a combat action
-type MobCombatActionResponse struct {
-	CharacterDamage int    `json:"character_damage"`
-	MobDamage       int    `json:"mob_damage"`
-	Result          string `json:"result"`
-	Error           string `json:"error,omitempty"`
+// BattleFleeRequest represents a request to flee a battle
+type BattleFleeRequest struct {
+	Version int `json:"version"`
+}
+
+// BattleResponse represents a single battle
+type BattleResponse struct {
+	Battle *combat.Battle `json:"battle,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// BattleReportResponse represents the summary of a battle
+type BattleReportResponse struct {
+	Report *combat.BattleReport `json:"report,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// BattleBroadcastRequest represents a request to open a battle to spectators,
+// who see it delay seconds behind the fight, or to close it again
+type BattleBroadcastRequest struct {
+	Public bool `json:"public"`
+	Delay  *int `json:"delay,omitempty"`
+}
+
+// PublicBattlesResponse represents the running battles open to spectators
+type PublicBattlesResponse struct {
+	Battles []PublicBattle `json:"battles"`
+}
+
+// ListBattlesResponse represents the caller's active battles
+type ListBattlesResponse struct {
+	Battles []*combat.Battle `json:"battles"`
+}
+
+// JoinQueueRequest represents a request to queue a character for a ranked battle
+type JoinQueueRequest struct {
+	CharacterID string              `json:"character_id"`
+	Bracket     matchmaking.Bracket `json:"bracket"`
+}
+
+// QueueResponse represents a queued character's ticket and how far its
+// search has widened
+type QueueResponse struct {
+	Ticket       *matchmaking.Ticket `json:"ticket,omitempty"`
+	RatingWindow float64             `json:"rating_window,omitempty"`
+	LevelBand    int                 `json:"level_band,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// RatingsResponse represents a character's ratings in the current season
+type RatingsResponse struct {
+	Season  int                                        `json:"season"`
+	Ratings map[matchmaking.Bracket]matchmaking.Rating `json:"ratings"`
+}
+
+// LeaderboardResponse represents the best rated characters of a bracket in a season
+type LeaderboardResponse struct {
+	Season    int                    `json:"season"`
+	Bracket   matchmaking.Bracket    `json:"bracket"`
+	Standings []matchmaking.Standing `json:"standings"`
+}
+
+// SeasonResponse represents the current ranked season
+type SeasonResponse struct {
+	Season matchmaking.Season `json:"season"`
 }
 
 // RegisterRoutes registers all HTTP routes
 func (h *Handler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/api/character/create", h.handleCreateCharacter).Methods("POST")
+	r.HandleFunc("/api/characters", h.handleListCharacters).Methods("GET")
+	r.HandleFunc("/api/character/{id}", h.handleGetCharacter).Methods("GET")
+	r.HandleFunc("/api/character/{id}", h.handleRenameCharacter).Methods("PUT")
+	r.HandleFunc("/api/character/{id}", h.handleDeleteCharacter).Methods("DELETE")
+	r.HandleFunc("/api/character/{id}/restore", h.handleRestoreCharacter).Methods("POST")
+	r.HandleFunc("/api/character/{id}/inventory", h.handleGetInventory).Methods("GET")
+	r.HandleFunc("/api/character/{id}/equipment", h.handleGetEquipment).Methods("GET")
+	r.HandleFunc("/api/character/{id}/abilities", h.handleGetAbilities).Methods("GET")
+	r.HandleFunc("/api/character/{id}/stats", h.handleGetCombatStats).Methods("GET")
+	r.HandleFunc("/api/character/{id}/ratings", h.handleGetRatings).Methods("GET")
 	r.HandleFunc("/api/combat/start", h.handleStartCombat).Methods("POST")
 	r.HandleFunc("/api/mob-combat/start", h.handleStartMobCombat).Methods("POST")
 	r.HandleFunc("/api/mob-combat/action", h.handleMobCombatAction).Methods("POST")
+	r.HandleFunc("/api/battles", h.handleCreateBattle).Methods("POST")
+	r.HandleFunc("/api/battles", h.handleListBattles).Methods("GET")
+	r.HandleFunc("/api/battles/public", h.handleListPublicBattles).Methods("GET")
+	r.HandleFunc("/api/battles/{id}", h.handleGetBattle).Methods("GET")
+	r.HandleFunc("/api/battles/{id}/action", h.handleBattleAction).Methods("POST")
+	r.HandleFunc("/api/battles/{id}/move", h.handleBattleMove).Methods("POST")
+	r.HandleFunc("/api/battles/{id}/flee", h.handleFleeBattle).Methods("POST")
+	r.HandleFunc("/api/battles/{id}/forfeit", h.handleForfeitBattle).Methods("POST")
+	r.HandleFunc("/api/battles/{id}/report", h.handleBattleReport).Methods("GET")
+	r.HandleFunc("/api/battles/{id}/export", h.handleExportBattle).Methods("GET")
+	r.HandleFunc("/api/battles/{id}/broadcast", h.handleBattleBroadcast).Methods("PUT")
+	r.HandleFunc("/api/battles/{id}/spectate", h.handleSpectate).Methods("GET")
+	r.HandleFunc("/api/challenges", h.handleCreateChallenge).Methods("POST")
+	r.HandleFunc("/api/challenges", h.handleListChallenges).Methods("GET")
+	r.HandleFunc("/api/challenges/{id}/accept", h.handleAcceptChallenge).Methods("POST")
+	r.HandleFunc("/api/challenges/{id}", h.handleDeclineChallenge).Methods("DELETE")
+	r.HandleFunc("/api/raids", h.handleCreateRaid).Methods("POST")
+	r.HandleFunc("/api/raids/bosses", h.handleListBosses).Methods("GET")
+	r.HandleFunc("/api/matchmaking/queue", h.handleJoinQueue).Methods("POST")
+	r.HandleFunc("/api/matchmaking/queue/{id}", h.handleGetQueueTicket).Methods("GET")
+	r.HandleFunc("/api/matchmaking/queue/{id}", h.handleLeaveQueue).Methods("DELETE")
+	r.HandleFunc("/api/leaderboards/{bracket}", h.handleGetLeaderboard).Methods("GET")
+	r.HandleFunc("/api/seasons/current", h.handleGetSeason).Methods("GET")
+	r.HandleFunc("/api/ws", h.handleWebSocket).Methods("GET")
 }
 
 func (h *Handler) handleCreateCharacter(w http.ResponseWriter, r *http.Request) {
@@ -91,7 +269,7 @@ func (h *Handler) handleCreateCharacter(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	char, err := h.server.CreateCharacter(req.Name, req.Class)
+	char, err := h.server.CreateCharacter(username(r), req.Name, req.Class)
 	response := CreateCharacterResponse{
 		Character: char,
 	}
@@ -104,54 +282,148 @@ func (h *Handler) handleCreateCharacter(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
-func (h *Handler) handleStartCombat(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+func (h *Handler) handleListCharacters(w http.ResponseWriter, r *http.Request) {
+	response := ListCharactersResponse{
+		Characters: h.server.ListCharacters(username(r)),
 	}
 
-	var req StartCombatRequest
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) handleGetCharacter(w http.ResponseWriter, r *http.Request) {
+	char, err := h.server.GetCharacter(username(r), mux.Vars(r)["id"])
+	writeCharacterResponse(w, char, err)
+}
+
+func (h *Handler) handleRenameCharacter(w http.ResponseWriter, r *http.Request) {
+	var req RenameCharacterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	state, err := h.server.StartCombat(req.Participants)
-	response := StartCombatResponse{
-		State: state,
+	char, err := h.server.RenameCharacter(username(r), mux.Vars(r)["id"], req.Name)
+	writeCharacterResponse(w, char, err)
+}
+
+func (h *Handler) handleDeleteCharacter(w http.ResponseWriter, r *http.Request) {
+	if err := h.server.DeleteCharacter(username(r), mux.Vars(r)["id"], time.Now()); err != nil {
+		writeCharacterResponse(w, nil, err)
+		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleRestoreCharacter(w http.ResponseWriter, r *http.Request) {
+	char, err := h.server.RestoreCharacter(username(r), mux.Vars(r)["id"], time.Now())
+	writeCharacterResponse(w, char, err)
+}
+
+func (h *Handler) handleGetInventory(w http.ResponseWriter, r *http.Request) {
+	char, err := h.server.GetCharacter(username(r), mux.Vars(r)["id"])
 	if err != nil {
-		response.Error = err.Error()
-		w.WriteHeader(http.StatusBadRequest)
+		writeCharacterResponse(w, nil, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(InventoryResponse{Inventory: char.Inventory})
+}
+
+func (h *Handler) handleGetEquipment(w http.ResponseWriter, r *http.Request) {
+	char, err := h.server.GetCharacter(username(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeCharacterResponse(w, nil, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EquipmentResponse{Equipment: char.Equipment})
+}
+
+func (h *Handler) handleGetAbilities(w http.ResponseWriter, r *http.Request) {
+	char, err := h.server.GetCharacter(username(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeCharacterResponse(w, nil, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AbilitiesResponse{Abilities: char.Abilities})
+}
+
+func (h *Handler) handleGetCombatStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.server.GetCombatStats(username(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeCharacterResponse(w, nil, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CombatStatsResponse{Stats: stats})
+}
+
+// username returns the account name the auth middleware stored in the request context
+func username(r *http.Request) string {
+	name, _ := r.Context().Value("username").(string)
+	return name
+}
+
+// writeCharacterResponse writes a character or maps a character error to its status code
+func writeCharacterResponse(w http.ResponseWriter, char *character.Character, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response := CharacterResponse{
+		Character: char,
+	}
+	if err != nil {
+		response.Error = err.Error()
+		switch {
+		case errors.Is(err, ErrCharacterNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, ErrNotCharacterOwner):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, ErrNameTaken), errors.Is(err, ErrCharacterInCombat), errors.Is(err, ErrRestoreExpired):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+
 	json.NewEncoder(w).Encode(response)
 }
 
-func (h *Handler) handleStartMobCombat(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) handleStartCombat(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var req StartMobCombatRequest
+	var req StartCombatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	combat, err := h.server.StartMobCombat(req.CharacterID, req.MobID)
-	response := StartMobCombatResponse{
-		Combat: combat,
+	battle, err := h.server.StartCombat(username(r), req.Participants)
+	writeBattleResponse(w, battle, err)
+}
+
+func (h *Handler) handleStartMobCombat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	if err != nil {
-		response.Error = err.Error()
-		w.WriteHeader(http.StatusBadRequest)
+
+	var req StartMobCombatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	battle, err := h.server.StartMobCombat(username(r), req.CharacterID, req.MobID)
+	writeBattleResponse(w, battle, err)
 }
 
 func (h *Handler) handleMobCombatAction(w http.ResponseWriter, r *http.Request) {
@@ -166,23 +438,372 @@ func (h *Handler) handleMobCombatAction(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	combat, err := h.server.StartMobCombat(req.CharacterID, req.MobID)
+	battle, err := h.server.AttackMob(username(r), req.CharacterID, req.MobID, req.Ability)
+	writeBattleResponse(w, battle, err)
+}
+
+func (h *Handler) handleCreateBattle(w http.ResponseWriter, r *http.Request) {
+	var req CreateBattleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	battle, err := h.server.CreateBattle(username(r), req.CharacterID, req.OpponentIDs, req.MobIDs)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(BattleResponse{Battle: battle})
+		return
+	}
+	writeBattleResponse(w, nil, err)
+}
+
+func (h *Handler) handleListBattles(w http.ResponseWriter, r *http.Request) {
+	response := ListBattlesResponse{
+		Battles: h.server.ListBattles(username(r)),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) handleGetBattle(w http.ResponseWriter, r *http.Request) {
+	battle, err := h.server.GetBattle(username(r), mux.Vars(r)["id"])
+	writeBattleResponse(w, battle, err)
+}
+
+func (h *Handler) handleBattleAction(w http.ResponseWriter, r *http.Request) {
+	var req BattleActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	battle, err := h.server.SubmitBattleAction(username(r), mux.Vars(r)["id"], req.Version, req.Ability, req.TargetID)
+	writeBattleResponse(w, battle, err)
+}
+
+func (h *Handler) handleBattleMove(w http.ResponseWriter, r *http.Request) {
+	var req BattleMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	battle, err := h.server.SubmitBattleMove(username(r), mux.Vars(r)["id"], req.Version, common.Coordinates{X: req.X, Y: req.Y})
+	writeBattleResponse(w, battle, err)
+}
+
+func (h *Handler) handleFleeBattle(w http.ResponseWriter, r *http.Request) {
+	var req BattleFleeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	battle, err := h.server.FleeBattle(username(r), mux.Vars(r)["id"], req.Version)
+	writeBattleResponse(w, battle, err)
+}
+
+func (h *Handler) handleForfeitBattle(w http.ResponseWriter, r *http.Request) {
+	battle, err := h.server.ForfeitBattle(username(r), mux.Vars(r)["id"])
+	writeBattleResponse(w, battle, err)
+}
+
+func (h *Handler) handleBattleBroadcast(w http.ResponseWriter, r *http.Request) {
+	var req BattleBroadcastRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	delay := defaultSpectatorDelay
+	if req.Delay != nil {
+		delay = time.Duration(*req.Delay) * time.Second
+	}
+	battle, err := h.server.SetBattleBroadcast(username(r), mux.Vars(r)["id"], req.Public, delay)
+	writeBattleResponse(w, battle, err)
+}
+
+func (h *Handler) handleListPublicBattles(w http.ResponseWriter, r *http.Request) {
+	response := PublicBattlesResponse{
+		Battles: h.server.ListPublicBattles(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) handleBattleReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.server.GetBattleReport(username(r), mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBattleResponse(w, nil, err)
 		return
 	}
 
-	charDamage, mobDamage, err := h.server.HandleMobCombatAction(combat, req.Ability)
-	response := MobCombatActionResponse{
-		CharacterDamage: charDamage,
-		MobDamage:       mobDamage,
-		Result:          combat.GetCombatResult(),
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BattleReportResponse{Report: report})
+}
+
+// handleExportBattle writes a battle's event stream as JSON Lines, or with
+// format=text a transcript of the battle
+func (h *Handler) handleExportBattle(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "jsonl"
+	}
+	if format != "jsonl" && format != "text" {
+		http.Error(w, "Unknown export format", http.StatusBadRequest)
+		return
+	}
+
+	if format == "jsonl" {
+		record, err := h.server.GetBattleRecord(username(r), mux.Vars(r)["id"])
+		if err != nil {
+			writeBattleResponse(w, nil, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		record.WriteJSONL(w)
+		return
+	}
+
+	report, err := h.server.GetBattleReport(username(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeBattleResponse(w, nil, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	report.WriteTranscript(w)
+}
+
+func (h *Handler) handleCreateChallenge(w http.ResponseWriter, r *http.Request) {
+	var req CreateChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := h.server.Challenge(username(r), req.CharacterID, req.OpponentIDs)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(ChallengeResponse{Challenge: challenge})
+		return
+	}
+	writeChallengeResponse(w, nil, nil, err)
+}
+
+func (h *Handler) handleListChallenges(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListChallengesResponse{Challenges: h.server.ListChallenges(username(r))})
+}
+
+func (h *Handler) handleAcceptChallenge(w http.ResponseWriter, r *http.Request) {
+	var req AcceptChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	challenge, battle, err := h.server.AcceptChallenge(username(r), mux.Vars(r)["id"], req.CharacterID)
+	writeChallengeResponse(w, challenge, battle, err)
+}
+
+func (h *Handler) handleDeclineChallenge(w http.ResponseWriter, r *http.Request) {
+	if err := h.server.DeclineChallenge(username(r), mux.Vars(r)["id"]); err != nil {
+		writeChallengeResponse(w, nil, nil, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleCreateRaid(w http.ResponseWriter, r *http.Request) {
+	var req CreateRaidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	battle, err := h.server.CreateRaid(username(r), req.CharacterID, req.BossID, req.Teams)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(BattleResponse{Battle: battle})
+		return
+	}
+	writeBattleResponse(w, nil, err)
+}
+
+func (h *Handler) handleListBosses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListBossesResponse{Bosses: h.server.ListBosses()})
+}
+
+func (h *Handler) handleGetRatings(w http.ResponseWriter, r *http.Request) {
+	ratings, err := h.server.GetRatings(mux.Vars(r)["id"])
+	if err != nil {
+		writeCharacterResponse(w, nil, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RatingsResponse{Season: h.server.GetSeason().Number, Ratings: ratings})
+}
+
+func (h *Handler) handleJoinQueue(w http.ResponseWriter, r *http.Request) {
+	var req JoinQueueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ticket, err := h.server.JoinQueue(username(r), req.CharacterID, req.Bracket)
+	if err != nil {
+		writeQueueResponse(w, nil, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(queueResponse(ticket))
+}
+
+func (h *Handler) handleGetQueueTicket(w http.ResponseWriter, r *http.Request) {
+	ticket, err := h.server.GetQueueTicket(username(r), mux.Vars(r)["id"])
+	writeQueueResponse(w, ticket, err)
+}
+
+func (h *Handler) handleLeaveQueue(w http.ResponseWriter, r *http.Request) {
+	if err := h.server.LeaveQueue(username(r), mux.Vars(r)["id"]); err != nil {
+		writeQueueResponse(w, nil, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetLeaderboard lists the best rated characters of a bracket, of the
+// current season unless ?season= names another
+func (h *Handler) handleGetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	bracket := matchmaking.Bracket(mux.Vars(r)["bracket"])
+	season, err := positiveQuery(r, "season")
+	if err != nil {
+		http.Error(w, "Invalid season", http.StatusBadRequest)
+		return
+	}
+	limit, err := positiveQuery(r, "limit")
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	if season == 0 {
+		season = h.server.GetSeason().Number
+	}
+
+	standings, err := h.server.GetLeaderboard(bracket, season, limit)
+	if err != nil {
+		writeQueueResponse(w, nil, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LeaderboardResponse{Season: season, Bracket: bracket, Standings: standings})
+}
+
+func (h *Handler) handleGetSeason(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SeasonResponse{Season: h.server.GetSeason()})
+}
+
+// positiveQuery parses a positive integer query parameter, or returns 0 if
+// it is missing
+func positiveQuery(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err == nil && n < 1 {
+		err = errors.New(name + " must be positive")
+	}
+	return n, err
+}
+
+// queueResponse describes a ticket and its search window as of now
+func queueResponse(ticket *matchmaking.Ticket) QueueResponse {
+	response := QueueResponse{Ticket: ticket}
+	response.RatingWindow, response.LevelBand = ticket.Window(time.Now())
+	return response
+}
+
+// writeChallengeResponse writes a challenge and the battle it started or maps
+// a challenge error to its status code
+func writeChallengeResponse(w http.ResponseWriter, challenge *Challenge, battle *combat.Battle, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response := ChallengeResponse{
+		Challenge: challenge,
+		Battle:    battle,
 	}
 	if err != nil {
 		response.Error = err.Error()
+		switch {
+		case errors.Is(err, ErrChallengeNotFound), errors.Is(err, ErrCharacterNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, ErrNotCharacterOwner), errors.Is(err, ErrNotChallenged):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, ErrCharacterInCombat):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeQueueResponse writes a ticket or maps a matchmaking error to its status code
+func writeQueueResponse(w http.ResponseWriter, ticket *matchmaking.Ticket, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err == nil {
+		json.NewEncoder(w).Encode(queueResponse(ticket))
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrCharacterNotFound), errors.Is(err, ErrNotQueued):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, ErrNotCharacterOwner):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, ErrAlreadyQueued), errors.Is(err, ErrCharacterInCombat):
+		w.WriteHeader(http.StatusConflict)
+	default:
 		w.WriteHeader(http.StatusBadRequest)
 	}
+	json.NewEncoder(w).Encode(QueueResponse{Error: err.Error()})
+}
 
+// writeBattleResponse writes a battle or maps a battle error to its status code
+func writeBattleResponse(w http.ResponseWriter, battle *combat.Battle, err error) {
 	w.Header().Set("Content-Type", "application/json")
+
+	response := BattleResponse{
+		Battle: battle,
+	}
+	if err != nil {
+		response.Error = err.Error()
+		switch {
+		case errors.Is(err, ErrBattleNotFound), errors.Is(err, ErrBattleRecordNotFound), errors.Is(err, ErrCharacterNotFound), errors.Is(err, ErrBossNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, ErrNotBattleParticipant), errors.Is(err, ErrNotCharacterOwner), errors.Is(err, ErrBattleNotPublic):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, ErrNotYourTurn), errors.Is(err, ErrBattleOver), errors.Is(err, ErrBattleConflict), errors.Is(err, ErrCharacterInCombat), errors.Is(err, ErrRankedBattlePublic):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+
 	json.NewEncoder(w).Encode(response)
 }
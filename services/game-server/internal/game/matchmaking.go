@@ -0,0 +1,359 @@
+package game
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/matchmaking"
+)
+
+const (
+	// matchmakingInterval is how often the ranked queues are searched for matches
+	matchmakingInterval = time.Second
+	// seasonLength is how long a ranked season lasts
+	seasonLength = 28 * 24 * time.Hour
+
+	defaultLeaderboardLimit = 20
+	maxLeaderboardLimit     = 100
+)
+
+var (
+	// ErrUnknownBracket is returned for a ranked bracket that does not exist
+	ErrUnknownBracket = errors.New("unknown ranked bracket")
+	// ErrAlreadyQueued is returned when a queued character joins a queue again
+	ErrAlreadyQueued = errors.New("character is already queued")
+	// ErrNotQueued is returned when a character that is not queued leaves or checks a queue
+	ErrNotQueued = errors.New("character is not queued")
+)
+
+// ratingKey identifies a character's rating in a bracket
+type ratingKey struct {
+	bracket     matchmaking.Bracket
+	characterID string
+}
+
+// newQueues creates an empty queue for every bracket
+func newQueues() map[matchmaking.Bracket]*matchmaking.Queue {
+	queues := make(map[matchmaking.Bracket]*matchmaking.Queue)
+	for _, bracket := range matchmaking.Brackets() {
+		queues[bracket] = matchmaking.NewQueue(bracket)
+	}
+	return queues
+}
+
+// newRatings creates an empty rating table for every bracket
+func newRatings() map[matchmaking.Bracket]map[string]*matchmaking.Rating {
+	ratings := make(map[matchmaking.Bracket]map[string]*matchmaking.Rating)
+	for _, bracket := range matchmaking.Brackets() {
+		ratings[bracket] = make(map[string]*matchmaking.Rating)
+	}
+	return ratings
+}
+
+// JoinQueue queues an account's character for a ranked battle in a bracket.
+// The battle starts on its own once the matchmaker finds opponents.
+func (gs *GameServer) JoinQueue(owner, characterID string, bracket matchmaking.Bracket) (*matchmaking.Ticket, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	char, err := gs.ownedCharacter(owner, characterID)
+	if err != nil {
+		return nil, err
+	}
+	if !bracket.Valid() {
+		return nil, ErrUnknownBracket
+	}
+	if gs.inBattle(characterID) {
+		return nil, ErrCharacterInCombat
+	}
+	if gs.queuedTicket(characterID) != nil {
+		return nil, ErrAlreadyQueued
+	}
+
+	ticket := &matchmaking.Ticket{
+		CharacterID: characterID,
+		Owner:       owner,
+		Bracket:     bracket,
+		Level:       char.Level,
+		Rating:      gs.currentRating(bracket, characterID).Value,
+		QueuedAt:    time.Now(),
+	}
+	gs.queues[bracket].Add(ticket)
+
+	queued := *ticket
+	return &queued, nil
+}
+
+// LeaveQueue takes an account's character out of the ranked queue it is in
+func (gs *GameServer) LeaveQueue(owner, characterID string) error {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	if _, err := gs.ownedCharacter(owner, characterID); err != nil {
+		return err
+	}
+	for _, queue := range gs.queues {
+		if queue.Remove(characterID) {
+			return nil
+		}
+	}
+	return ErrNotQueued
+}
+
+// GetQueueTicket returns the ticket of an account's queued character
+func (gs *GameServer) GetQueueTicket(owner, characterID string) (*matchmaking.Ticket, error) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	if _, err := gs.ownedCharacter(owner, characterID); err != nil {
+		return nil, err
+	}
+	ticket := gs.queuedTicket(characterID)
+	if ticket == nil {
+		return nil, ErrNotQueued
+	}
+	queued := *ticket
+	return &queued, nil
+}
+
+// queuedTicket returns a character's ticket in any queue, or nil; the caller
+// must hold the mutex
+func (gs *GameServer) queuedTicket(characterID string) *matchmaking.Ticket {
+	for _, queue := range gs.queues {
+		if ticket := queue.Get(characterID); ticket != nil {
+			return ticket
+		}
+	}
+	return nil
+}
+
+// GetRatings returns a character's rating in every bracket this season
+func (gs *GameServer) GetRatings(characterID string) (map[matchmaking.Bracket]matchmaking.Rating, error) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	if _, exists := gs.players[characterID]; !exists {
+		return nil, ErrCharacterNotFound
+	}
+	ratings := make(map[matchmaking.Bracket]matchmaking.Rating)
+	for _, bracket := range matchmaking.Brackets() {
+		ratings[bracket] = gs.currentRating(bracket, characterID)
+	}
+	return ratings, nil
+}
+
+// GetSeason returns the current ranked season
+func (gs *GameServer) GetSeason() matchmaking.Season {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	return *gs.season
+}
+
+// GetLeaderboard returns the best rated characters of a bracket in a season,
+// at most limit of them. Season 0 is the current season, which is ranked from
+// the ratings in memory; past seasons are read from Redis.
+func (gs *GameServer) GetLeaderboard(bracket matchmaking.Bracket, season, limit int) ([]matchmaking.Standing, error) {
+	if !bracket.Valid() {
+		return nil, ErrUnknownBracket
+	}
+	if limit <= 0 {
+		limit = defaultLeaderboardLimit
+	}
+	limit = min(limit, maxLeaderboardLimit)
+
+	gs.mutex.RLock()
+	current := gs.season.Number
+	if season <= 0 || season == current {
+		standings := gs.currentStandings(bracket, limit)
+		gs.mutex.RUnlock()
+		return standings, nil
+	}
+	gs.mutex.RUnlock()
+
+	if season > current || gs.repo == nil {
+		return []matchmaking.Standing{}, nil
+	}
+	standings, err := gs.repo.GetLeaderboard(season, bracket, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+	for i := range standings {
+		standings[i].Name = gs.characterName(standings[i].CharacterID)
+	}
+	return standings, nil
+}
+
+// currentStandings ranks the characters that played a bracket this season;
+// the caller must hold the mutex
+func (gs *GameServer) currentStandings(bracket matchmaking.Bracket, limit int) []matchmaking.Standing {
+	standings := []matchmaking.Standing{}
+	for id, rating := range gs.ratings[bracket] {
+		if rating.Season == gs.season.Number && rating.Games > 0 {
+			standings = append(standings, matchmaking.Standing{CharacterID: id, Name: gs.characterName(id), Rating: rating.Value})
+		}
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Rating != standings[j].Rating {
+			return standings[i].Rating > standings[j].Rating
+		}
+		return standings[i].CharacterID < standings[j].CharacterID
+	})
+	if len(standings) > limit {
+		standings = standings[:limit]
+	}
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// characterName returns the name of an active or removed character, or ""
+// if it no longer exists; the caller must hold the mutex
+func (gs *GameServer) characterName(id string) string {
+	if char, exists := gs.players[id]; exists {
+		return char.Name
+	}
+	if char, exists := gs.removedCharacters[id]; exists {
+		return char.Name
+	}
+	return ""
+}
+
+// currentRating returns a character's rating in a bracket as it stands this
+// season, without storing a new or reset rating; the caller must hold the mutex
+func (gs *GameServer) currentRating(bracket matchmaking.Bracket, characterID string) matchmaking.Rating {
+	stored := gs.ratings[bracket][characterID]
+	if stored == nil {
+		return *matchmaking.NewRating(gs.season.Number)
+	}
+	rating := *stored
+	if rating.Season < gs.season.Number {
+		rating.ResetForSeason(gs.season.Number)
+	}
+	return rating
+}
+
+// rating returns a character's rating in a bracket for updating, creating it
+// or resetting it for the current season first; the caller must hold the mutex
+func (gs *GameServer) rating(bracket matchmaking.Bracket, characterID string) *matchmaking.Rating {
+	rating := gs.ratings[bracket][characterID]
+	if rating == nil {
+		rating = matchmaking.NewRating(gs.season.Number)
+		gs.ratings[bracket][characterID] = rating
+	} else if rating.Season < gs.season.Number {
+		rating.ResetForSeason(gs.season.Number)
+	}
+	return rating
+}
+
+// markRatingDirty flags a character's rating in a bracket for the next save;
+// the caller must hold the mutex
+func (gs *GameServer) markRatingDirty(bracket matchmaking.Bracket, characterID string) {
+	gs.dirtyRatings[ratingKey{bracket: bracket, characterID: characterID}] = true
+}
+
+// advanceSeason starts a new ranked season once the current one has ended;
+// the caller must hold the mutex
+func (gs *GameServer) advanceSeason(now time.Time) {
+	for !now.Before(gs.season.EndsAt) {
+		gs.season = gs.season.Next()
+		gs.dirtySeason = true
+		log.Printf("Ranked season %d started", gs.season.Number)
+	}
+}
+
+// matchmake starts a ranked battle for every match the queues can put
+// together; the caller must hold the mutex
+func (gs *GameServer) matchmake(now time.Time) {
+	for _, bracket := range matchmaking.Brackets() {
+		queue := gs.queues[bracket]
+		// Characters deleted or drawn into another battle lose their place
+		for _, ticket := range queue.Tickets() {
+			if _, exists := gs.players[ticket.CharacterID]; !exists || gs.inBattle(ticket.CharacterID) {
+				queue.Remove(ticket.CharacterID)
+			}
+		}
+		for _, match := range queue.Match(now) {
+			gs.startRankedBattle(match)
+		}
+	}
+}
+
+// startRankedBattle starts the PvP battle of a match and tells its members;
+// the caller must hold the mutex
+func (gs *GameServer) startRankedBattle(match *matchmaking.Match) {
+	var positions []common.Coordinates
+	for _, team := range match.Teams {
+		for _, ticket := range team {
+			positions = append(positions, gs.players[ticket.CharacterID].Position)
+		}
+	}
+	battle := gs.newBattle(combat.BattleTypePvP, positions...)
+	battle.Bracket = string(match.Bracket)
+
+	event := MatchFoundEvent{BattleID: battle.ID, Bracket: string(match.Bracket)}
+	for i, team := range match.Teams {
+		var ids []string
+		for _, ticket := range team {
+			battle.AddPlayer(gs.players[ticket.CharacterID])
+			battle.AddToTeam(ticket.CharacterID, teamID(i))
+			ids = append(ids, ticket.CharacterID)
+		}
+		event.Teams = append(event.Teams, ids)
+	}
+
+	// Ranked battles are there to be watched
+	gs.openBroadcast(battle, defaultSpectatorDelay)
+
+	gs.battles[battle.ID] = battle
+	gs.markBattleDirty(battle.ID)
+	gs.publishToCombat(battle, EventMatchFound, event)
+}
+
+// rateBattle updates the ratings of the characters of a finished ranked
+// battle. Cancelled battles are not rated. The caller must hold the mutex.
+func (gs *GameServer) rateBattle(battle *combat.Battle) {
+	if battle.Bracket == "" || battle.State != combat.BattleCompleted {
+		return
+	}
+	bracket := matchmaking.Bracket(battle.Bracket)
+
+	var sides [2][]*matchmaking.Rating
+	before := make(map[string]float64)
+	for i := range sides {
+		for _, id := range battle.Teams[teamID(i)] {
+			rating := gs.rating(bracket, id)
+			sides[i] = append(sides[i], rating)
+			before[id] = rating.Value
+		}
+	}
+
+	score := matchmaking.Draw
+	if len(battle.Winners) > 0 {
+		score = matchmaking.Loss
+		if p := battle.GetParticipant(battle.Winners[0]); p != nil && p.Team == teamID(0) {
+			score = matchmaking.Win
+		}
+	}
+	matchmaking.RateMatch(sides[0], sides[1], score)
+
+	for id, value := range before {
+		rating := gs.ratings[bracket][id]
+		gs.markRatingDirty(bracket, id)
+		gs.publishTo(EventRatingChanged, RatingChangedEvent{
+			CharacterID: id,
+			BattleID:    battle.ID,
+			Bracket:     battle.Bracket,
+			Rating:      rating.Value,
+			Change:      rating.Value - value,
+		}, id)
+	}
+}
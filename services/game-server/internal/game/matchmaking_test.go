@@ -0,0 +1,182 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/matchmaking"
+)
+
+func TestJoinQueue(t *testing.T) {
+	gs := newBattleTestServer()
+
+	if _, err := gs.JoinQueue("bob", "fast", matchmaking.Bracket1v1); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	if _, err := gs.JoinQueue("alice", "fast", "5v5"); !errors.Is(err, ErrUnknownBracket) {
+		t.Errorf("Expected ErrUnknownBracket, got %v", err)
+	}
+
+	ticket, err := gs.JoinQueue("alice", "fast", matchmaking.Bracket1v1)
+	if err != nil {
+		t.Fatalf("Failed to join queue: %v", err)
+	}
+	if ticket.Rating != matchmaking.DefaultRating {
+		t.Errorf("Expected a new character to queue at %v, got %v", matchmaking.DefaultRating, ticket.Rating)
+	}
+	if _, err := gs.JoinQueue("alice", "fast", matchmaking.Bracket2v2); !errors.Is(err, ErrAlreadyQueued) {
+		t.Errorf("Expected ErrAlreadyQueued, got %v", err)
+	}
+
+	if err := gs.LeaveQueue("alice", "fast"); err != nil {
+		t.Errorf("Failed to leave queue: %v", err)
+	}
+	if _, err := gs.GetQueueTicket("alice", "fast"); !errors.Is(err, ErrNotQueued) {
+		t.Errorf("Expected ErrNotQueued after leaving, got %v", err)
+	}
+}
+
+func TestRankedBattle(t *testing.T) {
+	gs := newBattleTestServer()
+	for _, q := range []struct{ owner, id string }{{"alice", "fast"}, {"bob", "slow"}} {
+		if _, err := gs.JoinQueue(q.owner, q.id, matchmaking.Bracket1v1); err != nil {
+			t.Fatalf("Failed to queue %s: %v", q.id, err)
+		}
+	}
+
+	gs.matchmake(time.Now())
+	if len(gs.battles) != 1 {
+		t.Fatalf("Expected a ranked battle to start, got %d battles", len(gs.battles))
+	}
+	var battle *combat.Battle
+	for _, b := range gs.battles {
+		battle = b
+	}
+	if battle.Bracket != string(matchmaking.Bracket1v1) || battle.GetParticipant("fast").Team == battle.GetParticipant("slow").Team {
+		t.Errorf("Expected a 1v1 battle between two teams, got bracket %q", battle.Bracket)
+	}
+	if gs.queuedTicket("fast") != nil || gs.queuedTicket("slow") != nil {
+		t.Errorf("Expected matched characters to leave the queue")
+	}
+	if listed := gs.ListPublicBattles(); len(listed) != 1 || len(listed[0].Battle.Participants) != 2 {
+		t.Errorf("Expected the ranked battle to be open to spectators, got %+v", listed)
+	}
+
+	if _, err := gs.ForfeitBattle("bob", battle.ID); err != nil {
+		t.Fatalf("Failed to forfeit: %v", err)
+	}
+	winner, loser := gs.currentRating(matchmaking.Bracket1v1, "fast"), gs.currentRating(matchmaking.Bracket1v1, "slow")
+	if winner.Value <= matchmaking.DefaultRating || loser.Value >= matchmaking.DefaultRating {
+		t.Errorf("Expected the winner to gain and the loser to lose rating, got %v and %v", winner.Value, loser.Value)
+	}
+	if !gs.dirtyRatings[ratingKey{matchmaking.Bracket1v1, "fast"}] {
+		t.Errorf("Expected the new rating to be saved")
+	}
+
+	standings, err := gs.GetLeaderboard(matchmaking.Bracket1v1, 0, 10)
+	if err != nil {
+		t.Fatalf("Failed to get leaderboard: %v", err)
+	}
+	if len(standings) != 2 || standings[0].CharacterID != "fast" || standings[0].Rank != 1 || standings[0].Name != "fast" {
+		t.Errorf("Expected fast to lead the leaderboard, got %+v", standings)
+	}
+}
+
+func TestMatchmakingDropsStaleTickets(t *testing.T) {
+	gs := newBattleTestServer()
+	gs.JoinQueue("alice", "fast", matchmaking.Bracket1v1)
+	gs.JoinQueue("bob", "slow", matchmaking.Bracket1v1)
+	delete(gs.players, "slow")
+
+	gs.matchmake(time.Now())
+	if len(gs.battles) != 0 {
+		t.Errorf("Expected no battle with a deleted character, got %d", len(gs.battles))
+	}
+	if gs.queuedTicket("slow") != nil || gs.queuedTicket("fast") == nil {
+		t.Errorf("Expected only the deleted character to leave the queue")
+	}
+}
+
+func TestSeasonReset(t *testing.T) {
+	gs := newBattleTestServer()
+	gs.ratings[matchmaking.Bracket1v1]["fast"] = &matchmaking.Rating{Season: 1, Value: 1700, Games: 10, Wins: 10}
+
+	gs.advanceSeason(gs.season.EndsAt)
+	if gs.season.Number != 2 || !gs.dirtySeason {
+		t.Fatalf("Expected season 2 to start, got season %d", gs.season.Number)
+	}
+
+	rating := gs.currentRating(matchmaking.Bracket1v1, "fast")
+	if rating.Value != 1600 || rating.Games != 0 {
+		t.Errorf("Expected a soft reset to 1600, got %+v", rating)
+	}
+	if standings, _ := gs.GetLeaderboard(matchmaking.Bracket1v1, 0, 10); len(standings) != 0 {
+		t.Errorf("Expected an empty leaderboard in a new season, got %+v", standings)
+	}
+}
+
+func TestMatchmakingRoutes(t *testing.T) {
+	gs := newBattleTestServer()
+
+	r := mux.NewRouter()
+	NewHandler(gs).RegisterRoutes(r)
+
+	serve := func(method, path, body, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), "username", username))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	join := `{"character_id":"fast","bracket":"1v1"}`
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		username string
+		status   int
+	}{
+		{"join", "POST", "/api/matchmaking/queue", join, "alice", http.StatusCreated},
+		{"join twice", "POST", "/api/matchmaking/queue", join, "alice", http.StatusConflict},
+		{"join as other", "POST", "/api/matchmaking/queue", `{"character_id":"slow","bracket":"1v1"}`, "alice", http.StatusForbidden},
+		{"join unknown bracket", "POST", "/api/matchmaking/queue", `{"character_id":"slow","bracket":"9v9"}`, "bob", http.StatusBadRequest},
+		{"status", "GET", "/api/matchmaking/queue/fast", "", "alice", http.StatusOK},
+		{"leave", "DELETE", "/api/matchmaking/queue/fast", "", "alice", http.StatusNoContent},
+		{"status after leaving", "GET", "/api/matchmaking/queue/fast", "", "alice", http.StatusNotFound},
+		{"ratings", "GET", "/api/character/fast/ratings", "", "bob", http.StatusOK},
+		{"ratings missing", "GET", "/api/character/missing/ratings", "", "bob", http.StatusNotFound},
+		{"leaderboard", "GET", "/api/leaderboards/1v1?limit=5", "", "bob", http.StatusOK},
+		{"leaderboard past season", "GET", "/api/leaderboards/1v1?season=1", "", "bob", http.StatusOK},
+		{"leaderboard unknown bracket", "GET", "/api/leaderboards/9v9", "", "bob", http.StatusBadRequest},
+		{"leaderboard invalid limit", "GET", "/api/leaderboards/1v1?limit=-1", "", "bob", http.StatusBadRequest},
+		{"season", "GET", "/api/seasons/current", "", "bob", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.path, tt.body, tt.username)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := serve("GET", "/api/character/fast/ratings", "", "bob")
+	var response RatingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Season != 1 || len(response.Ratings) != len(matchmaking.Brackets()) {
+		t.Errorf("Expected ratings in every bracket of season 1, got %+v", response)
+	}
+}
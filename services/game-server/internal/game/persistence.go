@@ -0,0 +1,402 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/matchmaking"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// ErrShuttingDown is returned for actions submitted after shutdown has begun
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// markCharacterDirty flags a character for the next save; the caller must hold the mutex
+func (gs *GameServer) markCharacterDirty(id string) {
+	gs.dirtyCharacters[id] = true
+}
+
+// markCharacterPurged flags a character for removal from storage; the caller must hold the mutex
+func (gs *GameServer) markCharacterPurged(id string) {
+	delete(gs.dirtyCharacters, id)
+	gs.purgedCharacters[id] = true
+}
+
+// markMobDirty flags a mob for the next save; the caller must hold the mutex
+func (gs *GameServer) markMobDirty(id string) {
+	delete(gs.deletedMobs, id)
+	gs.dirtyMobs[id] = true
+}
+
+// markMobDeleted flags a mob for removal from storage; the caller must hold the mutex
+func (gs *GameServer) markMobDeleted(id string) {
+	delete(gs.dirtyMobs, id)
+	gs.deletedMobs[id] = true
+}
+
+// markBattleDirty flags a battle for the next save; the caller must hold the mutex
+func (gs *GameServer) markBattleDirty(id string) {
+	gs.dirtyBattles[id] = true
+}
+
+// markStatsDirty flags a character's combat statistics for the next save; the
+// caller must hold the mutex
+func (gs *GameServer) markStatsDirty(id string) {
+	gs.dirtyStats[id] = true
+}
+
+// pendingSave is state SaveDirty writes, copied from the server under the
+// mutex so that storage can be written without holding it
+type pendingSave struct {
+	characters       map[string]*character.Character
+	purgedCharacters []string
+	mobs             map[string]*mob.Mob
+	deletedMobs      []string
+	battles          map[string]*combat.Battle
+	stats            map[string]*combat.CombatStats
+	ratings          map[ratingKey]matchmaking.Rating
+	season           matchmaking.Season
+	saveSeason       bool
+}
+
+// newPendingSave creates an empty pendingSave
+func newPendingSave() *pendingSave {
+	return &pendingSave{
+		characters: make(map[string]*character.Character),
+		mobs:       make(map[string]*mob.Mob),
+		battles:    make(map[string]*combat.Battle),
+		stats:      make(map[string]*combat.CombatStats),
+		ratings:    make(map[ratingKey]matchmaking.Rating),
+	}
+}
+
+// SaveDirty writes only the characters, mobs, battles, statistics and ratings
+// that changed since the last save. The changed state is copied under the
+// mutex and written without it, so that game actions do not wait on Redis.
+// Entities that fail to save stay dirty and are retried on the next call.
+func (gs *GameServer) SaveDirty() error {
+	gs.saveMutex.Lock()
+	defer gs.saveMutex.Unlock()
+
+	gs.mutex.Lock()
+	pending, err := gs.takeDirty()
+	gs.mutex.Unlock()
+
+	errs := []error{err}
+	failed := newPendingSave()
+
+	for id, char := range pending.characters {
+		if err := gs.repo.SaveCharacter(char); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save character %s: %v", id, err))
+			failed.characters[id] = char
+		}
+	}
+
+	for _, id := range pending.purgedCharacters {
+		if err := gs.repo.DeleteCharacter(id); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete character %s: %v", id, err))
+			failed.purgedCharacters = append(failed.purgedCharacters, id)
+			continue
+		}
+		if err := gs.repo.DeleteRatings(id, pending.season.Number); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete ratings of %s: %v", id, err))
+			failed.purgedCharacters = append(failed.purgedCharacters, id)
+		}
+	}
+
+	for id, m := range pending.mobs {
+		if err := gs.repo.SaveMob(m); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save mob %s: %v", id, err))
+			failed.mobs[id] = m
+		}
+	}
+
+	for _, id := range pending.deletedMobs {
+		if err := gs.repo.DeleteMob(id); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete mob %s: %v", id, err))
+			failed.deletedMobs = append(failed.deletedMobs, id)
+		}
+	}
+
+	for id, battle := range pending.battles {
+		if err := gs.repo.SaveBattle(battle); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save battle %s: %v", id, err))
+			failed.battles[id] = battle
+		}
+	}
+
+	for id, stats := range pending.stats {
+		if err := gs.repo.SaveCombatStats(id, stats); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save combat stats %s: %v", id, err))
+			failed.stats[id] = stats
+		}
+	}
+
+	for key, rating := range pending.ratings {
+		if err := gs.repo.SaveRating(key.bracket, key.characterID, &rating); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save %s rating of %s: %v", key.bracket, key.characterID, err))
+			failed.ratings[key] = rating
+		}
+	}
+
+	if pending.saveSeason {
+		if err := gs.repo.SaveSeason(&pending.season); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save season %d: %v", pending.season.Number, err))
+			failed.saveSeason = true
+		}
+	}
+
+	gs.mutex.Lock()
+	gs.markFailed(failed)
+	gs.mutex.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// takeDirty copies the state that changed since the last save and clears the
+// dirty flags. State that cannot be copied stays dirty. The caller must hold
+// the mutex.
+func (gs *GameServer) takeDirty() (*pendingSave, error) {
+	pending := newPendingSave()
+	pending.season = *gs.season
+	var errs []error
+
+	for id := range gs.dirtyCharacters {
+		char, exists := gs.players[id]
+		if !exists {
+			char, exists = gs.removedCharacters[id]
+		}
+		if exists {
+			var copied character.Character
+			if err := clone(char, &copied); err != nil {
+				errs = append(errs, fmt.Errorf("failed to copy character %s: %v", id, err))
+				continue
+			}
+			pending.characters[id] = &copied
+		}
+		delete(gs.dirtyCharacters, id)
+	}
+
+	for id := range gs.purgedCharacters {
+		pending.purgedCharacters = append(pending.purgedCharacters, id)
+		delete(gs.purgedCharacters, id)
+	}
+
+	for id := range gs.dirtyMobs {
+		if m, exists := gs.mobs[id]; exists {
+			var copied mob.Mob
+			if err := clone(m, &copied); err != nil {
+				errs = append(errs, fmt.Errorf("failed to copy mob %s: %v", id, err))
+				continue
+			}
+			pending.mobs[id] = &copied
+		}
+		delete(gs.dirtyMobs, id)
+	}
+
+	for id := range gs.deletedMobs {
+		pending.deletedMobs = append(pending.deletedMobs, id)
+		delete(gs.deletedMobs, id)
+	}
+
+	for id := range gs.dirtyBattles {
+		if battle, exists := gs.battles[id]; exists {
+			copied, err := copyBattle(battle)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to copy battle %s: %v", id, err))
+				continue
+			}
+			pending.battles[id] = copied
+		}
+		delete(gs.dirtyBattles, id)
+	}
+
+	for id := range gs.dirtyStats {
+		if stats, exists := gs.combatStats[id]; exists {
+			var copied combat.CombatStats
+			if err := clone(stats, &copied); err != nil {
+				errs = append(errs, fmt.Errorf("failed to copy combat stats %s: %v", id, err))
+				continue
+			}
+			pending.stats[id] = &copied
+		}
+		delete(gs.dirtyStats, id)
+	}
+
+	for key := range gs.dirtyRatings {
+		if rating, exists := gs.ratings[key.bracket][key.characterID]; exists {
+			pending.ratings[key] = *rating
+		}
+		delete(gs.dirtyRatings, key)
+	}
+
+	pending.saveSeason, gs.dirtySeason = gs.dirtySeason, false
+	return pending, errors.Join(errs...)
+}
+
+// markFailed flags state that failed to save for the next save, unless it
+// changed in storage terms while it was written: a character purged or a mob
+// deleted since is not saved again, and a mob stored again is not deleted.
+// The caller must hold the mutex.
+func (gs *GameServer) markFailed(failed *pendingSave) {
+	for id := range failed.characters {
+		if !gs.purgedCharacters[id] {
+			gs.markCharacterDirty(id)
+		}
+	}
+	for _, id := range failed.purgedCharacters {
+		gs.markCharacterPurged(id)
+	}
+	for id := range failed.mobs {
+		if !gs.deletedMobs[id] {
+			gs.markMobDirty(id)
+		}
+	}
+	for _, id := range failed.deletedMobs {
+		if !gs.dirtyMobs[id] {
+			gs.markMobDeleted(id)
+		}
+	}
+	for id := range failed.battles {
+		gs.markBattleDirty(id)
+	}
+	for id := range failed.stats {
+		gs.markStatsDirty(id)
+	}
+	for key := range failed.ratings {
+		gs.dirtyRatings[key] = true
+	}
+	if failed.saveSeason {
+		gs.dirtySeason = true
+	}
+}
+
+// copyBattle makes an independent copy of a battle to store, with its random
+// number state and record
+func copyBattle(battle *combat.Battle) (*combat.Battle, error) {
+	var copied combat.Battle
+	if err := clone(battle, &copied); err != nil {
+		return nil, err
+	}
+	copied.Seed, copied.Rolls = battle.Seed, battle.Rolls
+	if record := battle.Record(); record != nil {
+		var copiedRecord combat.BattleRecord
+		if err := clone(record, &copiedRecord); err != nil {
+			return nil, err
+		}
+		copied.SetRecord(&copiedRecord)
+	}
+	return &copied, nil
+}
+
+// clone deep copies the exported state of src into dst
+func clone(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// beginAction registers an in-flight combat action so shutdown can wait for it
+func (gs *GameServer) beginAction() error {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	if gs.closing {
+		return ErrShuttingDown
+	}
+	gs.actions.Add(1)
+	return nil
+}
+
+// endAction marks an in-flight combat action as finished
+func (gs *GameServer) endAction() {
+	gs.actions.Done()
+}
+
+// Shutdown stops accepting combat actions, waits for in-flight ones to finish
+// and flushes all dirty state to Redis. State is flushed even if draining
+// times out.
+func (gs *GameServer) Shutdown(ctx context.Context) error {
+	gs.mutex.Lock()
+	gs.closing = true
+	gs.mutex.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		gs.actions.Wait()
+		close(drained)
+	}()
+
+	var drainErr error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		drainErr = fmt.Errorf("timed out draining combat actions: %v", ctx.Err())
+	}
+
+	if err := gs.SaveDirty(); err != nil {
+		return errors.Join(drainErr, err)
+	}
+	return drainErr
+}
+
+// AutoSaver periodically flushes dirty game state to Redis
+type AutoSaver struct {
+	server   *GameServer
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewAutoSaver creates an autosaver that saves every interval
+func NewAutoSaver(server *GameServer, interval time.Duration) *AutoSaver {
+	return &AutoSaver{
+		server:   server,
+		interval: interval,
+	}
+}
+
+// Start begins saving in the background
+func (as *AutoSaver) Start() {
+	if as.stop != nil {
+		return
+	}
+	as.stop = make(chan struct{})
+	as.done = make(chan struct{})
+
+	go func() {
+		defer close(as.done)
+
+		ticker := time.NewTicker(as.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-as.stop:
+				return
+			case <-ticker.C:
+				if err := as.server.SaveDirty(); err != nil {
+					log.Printf("Autosave failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop halts the background saver and waits for a running save to finish
+func (as *AutoSaver) Stop() {
+	if as.stop == nil {
+		return
+	}
+	close(as.stop)
+	<-as.done
+	as.stop = nil
+}
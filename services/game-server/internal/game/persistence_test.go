@@ -0,0 +1,173 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+func TestTickMarksChangedEntitiesDirty(t *testing.T) {
+	gs := newTestServer()
+	tired := &character.Character{
+		ID:                "tired",
+		MovementPoints:    0,
+		MaxMovementPoints: 100,
+		SteamPower:        50,
+		MaxSteamPower:     50,
+	}
+	rested := &character.Character{
+		ID:                "rested",
+		MovementPoints:    100,
+		MaxMovementPoints: 100,
+		SteamPower:        50,
+		MaxSteamPower:     50,
+	}
+	gs.players[tired.ID] = tired
+	gs.players[rested.ID] = rested
+
+	gs.Tick(1, time.Now())
+
+	if !gs.dirtyCharacters["tired"] {
+		t.Error("Expected regenerating character to be dirty")
+	}
+	if gs.dirtyCharacters["rested"] {
+		t.Error("Expected unchanged character to stay clean")
+	}
+}
+
+func TestRegenMarksOnlyChangedEntitiesDirty(t *testing.T) {
+	gs := newBattleTestServer()
+	startDuel(t, gs)
+	// Fighters regenerate steam power per turn, not on the tick
+	for _, id := range []string{"fast", "slow"} {
+		gs.players[id].SteamPower = 10
+		gs.players[id].MovementPoints = gs.players[id].MaxMovementPoints
+	}
+	drained := mob.NewMob("Steam Golem", mob.Mechanical, 1)
+	drained.SteamPower = 0
+	full := mob.NewMob("Steam Golem", mob.Mechanical, 1)
+	overcharged := mob.NewMob("Steam Golem", mob.Mechanical, 1)
+	overcharged.SteamPower = overcharged.MaxSteamPower + 5
+	for _, m := range []*mob.Mob{drained, full, overcharged} {
+		gs.mobs[m.ID] = m
+	}
+
+	gs.regenerateResources()
+
+	if gs.dirtyCharacters["fast"] || gs.dirtyCharacters["slow"] {
+		t.Error("Expected fighting characters to stay clean")
+	}
+	if !gs.dirtyMobs[drained.ID] || drained.SteamPower != steamPowerRegen {
+		t.Errorf("Expected the drained mob to regenerate and be dirty, got %d steam power", drained.SteamPower)
+	}
+	if gs.dirtyMobs[full.ID] || gs.dirtyMobs[overcharged.ID] {
+		t.Error("Expected mobs at or above full steam power to stay clean")
+	}
+}
+
+func TestRemoveMobMarksDeleted(t *testing.T) {
+	gs := newTestServer()
+	m := mob.NewMob("Steam Golem", mob.Mechanical, 1)
+	gs.mobs[m.ID] = m
+	gs.dirtyMobs[m.ID] = true
+
+	gs.RemoveMob(m.ID)
+
+	if gs.dirtyMobs[m.ID] {
+		t.Error("Expected removed mob to no longer be dirty")
+	}
+	if !gs.deletedMobs[m.ID] {
+		t.Error("Expected removed mob to be marked for deletion")
+	}
+}
+
+func TestTakeDirtyCopiesState(t *testing.T) {
+	gs := newTestServer()
+	char := &character.Character{ID: "hero", Name: "Hero", Health: 100}
+	m := mob.NewMob("Steam Golem", mob.Mechanical, 1)
+	gs.players[char.ID] = char
+	gs.mobs[m.ID] = m
+	battle := combat.NewBattle(combat.BattleTypePvP)
+	gs.battles[battle.ID] = battle
+	gs.markCharacterDirty(char.ID)
+	gs.markMobDirty(m.ID)
+	gs.markBattleDirty(battle.ID)
+
+	pending, err := gs.takeDirty()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(gs.dirtyCharacters) != 0 || len(gs.dirtyMobs) != 0 {
+		t.Error("Expected the dirty flags to be cleared")
+	}
+
+	// The copies are written after the mutex is released
+	char.Health = 10
+	if copied := pending.characters[char.ID]; copied == nil || copied == char || copied.Health != 100 {
+		t.Errorf("Expected an independent copy of the character, got %+v", copied)
+	}
+	if copied := pending.battles[battle.ID]; copied == nil || copied == battle || copied.Seed != battle.Seed {
+		t.Error("Expected a copy of the battle with its random number state")
+	}
+
+	// Failed saves are retried, unless the mob was deleted in the meantime
+	gs.markMobDeleted(m.ID)
+	gs.markFailed(pending)
+	if !gs.dirtyCharacters[char.ID] {
+		t.Error("Expected the character that failed to save to be dirty again")
+	}
+	if gs.dirtyMobs[m.ID] || !gs.deletedMobs[m.ID] {
+		t.Error("Expected the deleted mob to stay deleted")
+	}
+}
+
+func TestShutdownDrainsActions(t *testing.T) {
+	gs := newTestServer()
+
+	if err := gs.beginAction(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- gs.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Expected shutdown to wait for the in-flight action")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := gs.beginAction(); err != ErrShuttingDown {
+		t.Errorf("Expected ErrShuttingDown, got %v", err)
+	}
+
+	gs.endAction()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected shutdown to finish once the action completed")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	gs := newTestServer()
+	gs.beginAction()
+	defer gs.endAction()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := gs.Shutdown(ctx); err == nil {
+		t.Error("Expected error when draining times out")
+	}
+}
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+var (
+	// ErrBossNotFound is returned when a raid is started against an unknown boss
+	ErrBossNotFound = errors.New("boss not found")
+	// ErrInvalidRaidTeams is returned when a raid team lists an unknown or repeated character
+	ErrInvalidRaidTeams = errors.New("raid teams must list distinct existing characters")
+)
+
+// CreateRaid starts a raid battle of several teams of characters against a
+// boss. The account's character leads the first team.
+func (gs *GameServer) CreateRaid(owner, characterID, bossID string, teams [][]string) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
+	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	char, err := gs.ownedCharacter(owner, characterID)
+	if err != nil {
+		return nil, err
+	}
+	def, exists := combat.GetBoss(bossID)
+	if !exists {
+		return nil, ErrBossNotFound
+	}
+	if len(teams) == 0 {
+		teams = [][]string{nil}
+	}
+
+	positions := []common.Coordinates{char.Position}
+	for _, team := range teams {
+		for _, id := range team {
+			if member, exists := gs.players[id]; exists {
+				positions = append(positions, member.Position)
+			}
+		}
+	}
+	battle := gs.newBattle(combat.BattleTypeRaid, positions...)
+	battle.AddPlayer(char)
+	battle.AddToTeam(char.ID, teamID(0))
+	for i, team := range teams {
+		for _, id := range team {
+			member, exists := gs.players[id]
+			if !exists || battle.GetParticipant(id) != nil {
+				return nil, ErrInvalidRaidTeams
+			}
+			battle.AddPlayer(member)
+			battle.AddToTeam(id, teamID(i))
+		}
+	}
+	for _, p := range battle.Participants {
+		if gs.inBattle(p.ID) {
+			return nil, ErrCharacterInCombat
+		}
+	}
+
+	boss := combat.NewBossMob(def)
+	boss.Position = char.Position
+	gs.mobs[boss.ID] = boss
+	gs.markMobDirty(boss.ID)
+	battle.AddBoss(boss, def)
+
+	gs.battles[battle.ID] = battle
+	gs.markBattleDirty(battle.ID)
+
+	// A faster boss acts before the raid gets a turn
+	gs.runMobTurns(battle)
+	return battle, nil
+}
+
+// ListBosses returns the bosses raids can be started against
+func (gs *GameServer) ListBosses() []*combat.BossDefinition {
+	return combat.ListBosses()
+}
+
+// teamID returns the ID of the i-th team of a raid or ranked battle
+func teamID(i int) string {
+	return fmt.Sprintf("team-%d", i+1)
+}
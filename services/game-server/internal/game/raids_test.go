@@ -0,0 +1,59 @@
+package game
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+)
+
+func TestCreateRaid(t *testing.T) {
+	gs := newBattleTestServer()
+	healer := newTestFighter("healer", 15)
+	healer.Owner = "carol"
+	gs.players[healer.ID] = healer
+
+	if _, err := gs.CreateRaid("alice", "fast", "missing", nil); !errors.Is(err, ErrBossNotFound) {
+		t.Errorf("Expected ErrBossNotFound, got %v", err)
+	}
+	if _, err := gs.CreateRaid("alice", "fast", "clockwork_dragon", [][]string{{"fast"}}); !errors.Is(err, ErrInvalidRaidTeams) {
+		t.Errorf("Expected ErrInvalidRaidTeams, got %v", err)
+	}
+
+	battle, err := gs.CreateRaid("alice", "fast", "clockwork_dragon", [][]string{{"slow"}, {"healer"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle.Type != combat.BattleTypeRaid || battle.Boss == nil {
+		t.Fatal("Expected a raid with a boss")
+	}
+	if _, exists := gs.mobs[battle.Boss.MobID]; !exists || !gs.dirtyMobs[battle.Boss.MobID] {
+		t.Error("Expected the boss to be added to the world")
+	}
+	if team := battle.GetParticipant("healer").Team; team != "team-2" {
+		t.Errorf("Expected healer in team-2, got %s", team)
+	}
+	if opponents := battle.Opponents("fast"); len(opponents) != 1 || opponents[0].ID != battle.Boss.MobID {
+		t.Errorf("Expected the boss to be the raid's only opponent, got %d opponents", len(opponents))
+	}
+	if battles := gs.ListBattles("carol"); len(battles) != 1 {
+		t.Errorf("Expected carol to see the raid, got %d battles", len(battles))
+	}
+
+	// The boss and its adds leave the world with the raid
+	if _, err := gs.ForfeitBattle("alice", battle.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := gs.ForfeitBattle("bob", battle.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := gs.ForfeitBattle("carol", battle.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !battle.IsOver() {
+		t.Fatal("Expected the raid to be over")
+	}
+	if _, exists := gs.mobs[battle.Boss.MobID]; exists {
+		t.Error("Expected the boss to be removed after the raid")
+	}
+}
@@ -0,0 +1,93 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+)
+
+// ErrNameTaken is returned when a character name is already in use
+var ErrNameTaken = errors.New("character name is already taken")
+
+// NameIndex persists character name reservations
+type NameIndex interface {
+	ReserveCharacterName(name, characterID string) (bool, error)
+	ReleaseCharacterName(name, characterID string) error
+}
+
+// NameRegistry enforces case-insensitive unique character names. Names are
+// reserved in the persistent index first, so two servers sharing the index
+// can never hand out the same name.
+type NameRegistry struct {
+	names map[string]string // normalized name -> character ID
+	index NameIndex
+}
+
+// NewNameRegistry creates a name registry backed by an index
+func NewNameRegistry(index NameIndex) *NameRegistry {
+	return &NameRegistry{
+		names: make(map[string]string),
+		index: index,
+	}
+}
+
+// Reserve validates a name and claims it for a character
+func (nr *NameRegistry) Reserve(name, characterID string) error {
+	if err := character.ValidateName(name); err != nil {
+		return err
+	}
+	return nr.claim(name, characterID)
+}
+
+// Rename moves a character's reservation from one name to another
+func (nr *NameRegistry) Rename(oldName, newName, characterID string) error {
+	if err := nr.Reserve(newName, characterID); err != nil {
+		return err
+	}
+	if character.NormalizeName(oldName) == character.NormalizeName(newName) {
+		return nil
+	}
+	return nr.Release(oldName, characterID)
+}
+
+// Release frees a character's name
+func (nr *NameRegistry) Release(name, characterID string) error {
+	key := character.NormalizeName(name)
+	if nr.names[key] == characterID {
+		delete(nr.names, key)
+	}
+	if err := nr.index.ReleaseCharacterName(key, characterID); err != nil {
+		return fmt.Errorf("failed to release name %s: %v", name, err)
+	}
+	return nil
+}
+
+// Lookup returns the ID of the character using a name
+func (nr *NameRegistry) Lookup(name string) (string, bool) {
+	id, exists := nr.names[character.NormalizeName(name)]
+	return id, exists
+}
+
+// claim records a name for a character without validating it. It is used
+// directly for characters loaded from storage, which may predate the rules.
+func (nr *NameRegistry) claim(name, characterID string) error {
+	key := character.NormalizeName(name)
+	if owner, exists := nr.names[key]; exists {
+		if owner != characterID {
+			return ErrNameTaken
+		}
+		return nil
+	}
+
+	ok, err := nr.index.ReserveCharacterName(key, characterID)
+	if err != nil {
+		return fmt.Errorf("failed to reserve name %s: %v", name, err)
+	}
+	if !ok {
+		return ErrNameTaken
+	}
+
+	nr.names[key] = characterID
+	return nil
+}
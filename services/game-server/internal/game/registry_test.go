@@ -0,0 +1,111 @@
+package game
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+)
+
+// memoryNameIndex is an in-memory NameIndex for tests
+type memoryNameIndex struct {
+	names map[string]string
+}
+
+func newMemoryNameIndex() *memoryNameIndex {
+	return &memoryNameIndex{names: make(map[string]string)}
+}
+
+func (m *memoryNameIndex) ReserveCharacterName(name, characterID string) (bool, error) {
+	if owner, exists := m.names[name]; exists {
+		return owner == characterID, nil
+	}
+	m.names[name] = characterID
+	return true, nil
+}
+
+func (m *memoryNameIndex) ReleaseCharacterName(name, characterID string) error {
+	if m.names[name] == characterID {
+		delete(m.names, name)
+	}
+	return nil
+}
+
+func TestNameRegistryCaseInsensitive(t *testing.T) {
+	nr := NewNameRegistry(newMemoryNameIndex())
+
+	if err := nr.Reserve("Cogsworth", "char1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := nr.Reserve("COGSWORTH", "char2"); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("Expected ErrNameTaken, got %v", err)
+	}
+	if id, _ := nr.Lookup("cogsworth"); id != "char1" {
+		t.Errorf("Expected char1, got %s", id)
+	}
+}
+
+func TestNameRegistrySharedIndex(t *testing.T) {
+	index := newMemoryNameIndex()
+	first := NewNameRegistry(index)
+	second := NewNameRegistry(index)
+
+	if err := first.Reserve("Cogsworth", "char1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := second.Reserve("cogsworth", "char2"); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("Expected ErrNameTaken from the shared index, got %v", err)
+	}
+}
+
+func TestNameRegistryRename(t *testing.T) {
+	index := newMemoryNameIndex()
+	nr := NewNameRegistry(index)
+	nr.Reserve("Cogsworth", "char1")
+
+	// Changing only the case keeps the reservation
+	if err := nr.Rename("Cogsworth", "CogsWorth", "char1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, exists := index.names["cogsworth"]; !exists {
+		t.Error("Expected reservation to survive a case-only rename")
+	}
+
+	if err := nr.Rename("CogsWorth", "Gearheart", "char1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, exists := nr.Lookup("Cogsworth"); exists {
+		t.Error("Expected old name to be released")
+	}
+	if err := nr.Reserve("Cogsworth", "char2"); err != nil {
+		t.Errorf("Expected released name to be available, got %v", err)
+	}
+}
+
+func TestNameRegistryValidation(t *testing.T) {
+	nr := NewNameRegistry(newMemoryNameIndex())
+
+	if err := nr.Reserve("Admin", "char1"); !errors.Is(err, character.ErrInvalidName) {
+		t.Errorf("Expected ErrInvalidName for reserved name, got %v", err)
+	}
+	if err := nr.Reserve("x", "char1"); !errors.Is(err, character.ErrInvalidName) {
+		t.Errorf("Expected ErrInvalidName for short name, got %v", err)
+	}
+}
+
+func TestCreateCharacterNameRules(t *testing.T) {
+	gs := newTestServer()
+
+	if _, err := gs.CreateCharacter("alice", "Cogsworth", character.Engineer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := gs.CreateCharacter("bob", "cogsworth", character.Engineer); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("Expected ErrNameTaken, got %v", err)
+	}
+	if _, err := gs.CreateCharacter("bob", "Moderator", character.Engineer); !errors.Is(err, character.ErrInvalidName) {
+		t.Errorf("Expected ErrInvalidName, got %v", err)
+	}
+	if len(gs.ListCharacters("bob")) != 0 {
+		t.Error("Expected rejected characters not to be created")
+	}
+}
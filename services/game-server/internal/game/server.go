@@ -2,37 +2,113 @@ package game
 
 import (
 	"fmt"
+	"log"
 	"math"
 	"sync"
+	"time"
 
 	"github.com/redfoxius/roleplay/services/game-server/internal/character"
 	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
 	"github.com/redfoxius/roleplay/services/game-server/internal/common"
 	"github.com/redfoxius/roleplay/services/game-server/internal/database"
+	"github.com/redfoxius/roleplay/services/game-server/internal/matchmaking"
 	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
 	"github.com/redfoxius/roleplay/services/game-server/internal/world"
 )
 
+const (
+	// regenInterval is how often characters and mobs regenerate resources
+	regenInterval = time.Second
+	// mobAIInterval is how often mobs re-evaluate their targets and move
+	mobAIInterval = time.Second
+	// effectInterval is the length of one out-of-combat status effect turn
+	effectInterval = time.Second
+
+	movementPointsRegen = 5
+	steamPowerRegen     = 2
+
+	// maxMoveDistance is how many tiles a character moves on the world map at a time
+	maxMoveDistance = 1
+)
+
 // GameServer represents the game server
 type GameServer struct {
 	players     map[string]*character.Character
-	activeGames map[string]*combat.CombatState
+	battles     map[string]*combat.Battle
 	mobs        map[string]*mob.Mob
+	mobAI       map[string]*mob.AIBehavior
+	effects     map[string]*combat.EffectManager
+	combatStats map[string]*combat.CombatStats
 	mutex       sync.RWMutex
+	saveMutex   sync.Mutex // serializes saves, so an older copy never overwrites a newer one
 	repo        *database.Repository
 	worldMap    *world.WorldMap
 	spawner     *world.WorldSpawner
+	events      *EventHub
+	names       *NameRegistry
+	lastRegen   time.Time
+	lastMobAI   time.Time
+	lastEffects time.Time
+	lastPurge   time.Time
+
+	// queues hold the characters waiting for a ranked battle, by bracket
+	queues map[matchmaking.Bracket]*matchmaking.Queue
+	// ratings are every character's ranked ratings, by bracket and character ID
+	ratings         map[matchmaking.Bracket]map[string]*matchmaking.Rating
+	season          *matchmaking.Season
+	lastMatchmaking time.Time
+
+	// removedCharacters holds soft deleted characters until they are restored or purged
+	removedCharacters map[string]*character.Character
+	// finishedBattles records when battles ended, so they can be dropped from memory
+	finishedBattles map[string]time.Time
+	// broadcasts hold the events of public battles back for their spectators, by battle ID
+	broadcasts map[string]*broadcast
+	// challenges are the PvP battles waiting for their opponents to accept, by ID
+	challenges map[string]*Challenge
+
+	dirtyCharacters  map[string]bool
+	purgedCharacters map[string]bool
+	dirtyMobs        map[string]bool
+	deletedMobs      map[string]bool
+	dirtyBattles     map[string]bool
+	dirtyStats       map[string]bool
+	dirtyRatings     map[ratingKey]bool
+	dirtySeason      bool
+	closing          bool
+	actions          sync.WaitGroup
 }
 
 // NewGameServer creates a new game server
 func NewGameServer(repo *database.Repository) *GameServer {
 	server := &GameServer{
 		players:     make(map[string]*character.Character),
-		activeGames: make(map[string]*combat.CombatState),
+		battles:     make(map[string]*combat.Battle),
 		mobs:        make(map[string]*mob.Mob),
+		mobAI:       make(map[string]*mob.AIBehavior),
+		effects:     make(map[string]*combat.EffectManager),
+		combatStats: make(map[string]*combat.CombatStats),
 		repo:        repo,
 		worldMap:    world.NewWorldMap(100, 100), // Create a 100x100 world
 		spawner:     world.NewWorldSpawner(),
+		events:      NewEventHub(),
+		names:       NewNameRegistry(repo),
+
+		removedCharacters: make(map[string]*character.Character),
+		finishedBattles:   make(map[string]time.Time),
+		broadcasts:        make(map[string]*broadcast),
+		challenges:        make(map[string]*Challenge),
+
+		queues:  newQueues(),
+		ratings: newRatings(),
+
+		dirtyCharacters:  make(map[string]bool),
+		purgedCharacters: make(map[string]bool),
+		dirtyMobs:        make(map[string]bool),
+		deletedMobs:      make(map[string]bool),
+		dirtyBattles:     make(map[string]bool),
+		dirtyStats:       make(map[string]bool),
+		dirtyRatings:     make(map[ratingKey]bool),
 	}
 
 	// Initialize spawn points
@@ -40,9 +116,18 @@ func NewGameServer(repo *database.Repository) *GameServer {
 
 	// Load existing data from Redis
 	server.loadData()
+	if server.season == nil {
+		server.season = matchmaking.NewSeason(1, time.Now(), seasonLength)
+		server.dirtySeason = true
+	}
 	return server
 }
 
+// Events returns the hub that streams real-time events to clients
+func (gs *GameServer) Events() *EventHub {
+	return gs.events
+}
+
 // GetWorldMap returns the world map
 func (gs *GameServer) GetWorldMap() *world.WorldMap {
 	return gs.worldMap
@@ -64,6 +149,172 @@ func (gs *GameServer) UpdateWorld() {
 	gs.spawner.UpdateSpawner()
 }
 
+// Tick advances the server-authoritative simulation by one tick. It is meant
+// to be driven by a TickScheduler.
+func (gs *GameServer) Tick(tick uint64, now time.Time) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	gs.worldMap.UpdateWorld()
+	gs.spawner.UpdateSpawnerAt(now)
+	gs.syncSpawnedMobs()
+
+	if now.Sub(gs.lastMobAI) >= mobAIInterval {
+		gs.updateMobAI()
+		gs.lastMobAI = now
+	}
+
+	gs.expireTurns(now)
+	gs.expireChallenges(now)
+	gs.releaseBroadcasts(now)
+
+	if now.Sub(gs.lastMatchmaking) >= matchmakingInterval {
+		gs.advanceSeason(now)
+		gs.matchmake(now)
+		gs.lastMatchmaking = now
+	}
+
+	if now.Sub(gs.lastEffects) >= effectInterval {
+		gs.expireEffects()
+		gs.lastEffects = now
+	}
+
+	if now.Sub(gs.lastRegen) >= regenInterval {
+		gs.regenerateResources()
+		gs.lastRegen = now
+	}
+
+	if now.Sub(gs.lastPurge) >= purgeInterval {
+		gs.purgeDeletedCharacters(now)
+		gs.pruneFinishedBattles(now)
+		gs.lastPurge = now
+	}
+}
+
+// syncSpawnedMobs registers newly spawned mobs and drops dead ones
+func (gs *GameServer) syncSpawnedMobs() {
+	for _, m := range gs.spawner.GetAllMobs() {
+		if _, exists := gs.mobs[m.ID]; !exists && !m.IsDead() {
+			gs.mobs[m.ID] = m
+			gs.markMobDirty(m.ID)
+			gs.publishNear(EventMobSpawned, mobMovedEvent(m), m.Position)
+		}
+	}
+
+	for id, m := range gs.mobs {
+		if m.IsDead() {
+			delete(gs.mobs, id)
+			delete(gs.mobAI, id)
+			delete(gs.effects, id)
+			gs.markMobDeleted(id)
+			gs.publishNear(EventMobDespawned, mobMovedEvent(m), m.Position)
+		}
+	}
+}
+
+// updateMobAI lets every mob pick a target and take one step
+func (gs *GameServer) updateMobAI() {
+	fighting := gs.fighting()
+	for id, m := range gs.mobs {
+		// Mobs in battle act on their turns instead
+		if fighting[id] {
+			continue
+		}
+		ai := gs.mobBehavior(m)
+
+		ai.UpdateAI(m, gs.nearbyCharacters(m.Position, ai.AggroRange))
+
+		before := m.Position
+		switch {
+		case ai.State == mob.Aggressive && ai.Target != nil:
+			if distanceBetween(m.Position, ai.Target.Position) > 1 {
+				next := stepToward(m.Position, ai.Target.Position)
+				m.MoveTo(next.X, next.Y)
+			}
+		case ai.State == mob.Fleeing && ai.Target != nil:
+			next := stepAway(m.Position, ai.Target.Position)
+			if next.X >= 0 && next.Y >= 0 && next.X < gs.worldMap.Width && next.Y < gs.worldMap.Height {
+				m.MoveTo(next.X, next.Y)
+			}
+		}
+		if m.Position != before {
+			gs.markMobDirty(id)
+			gs.publishNear(EventMobMoved, mobMovedEvent(m), before, m.Position)
+		}
+	}
+}
+
+// expireEffects advances out-of-combat status effects by one turn
+func (gs *GameServer) expireEffects() {
+	for id, em := range gs.effects {
+		em.UpdateEffects()
+		if len(em.Effects) == 0 {
+			delete(gs.effects, id)
+		}
+	}
+}
+
+// regenerateResources restores movement points and steam power. Steam power
+// of characters and mobs in battle regenerates per turn instead.
+func (gs *GameServer) regenerateResources() {
+	fighting := gs.fighting()
+	for id, char := range gs.players {
+		movement, steam := char.MovementPoints, char.SteamPower
+
+		char.MovementPoints = max(movement, min(movement+movementPointsRegen, char.MaxMovementPoints))
+
+		regen := steamPowerRegen + gs.GetTerrainProperties(char.Position.X, char.Position.Y).SteamPowerBonus/5
+		if regen > 0 && !fighting[id] {
+			char.SteamPower = max(steam, min(steam+regen, char.MaxSteamPower))
+		}
+
+		if char.MovementPoints != movement || char.SteamPower != steam {
+			gs.markCharacterDirty(id)
+		}
+	}
+
+	for id, m := range gs.mobs {
+		if fighting[id] {
+			continue
+		}
+		steam := m.SteamPower
+		m.SteamPower = max(steam, min(steam+steamPowerRegen, m.MaxSteamPower))
+		if m.SteamPower != steam {
+			gs.markMobDirty(id)
+		}
+	}
+}
+
+// ApplyEffect applies an out-of-combat status effect to a character or mob
+func (gs *GameServer) ApplyEffect(entityID string, effect combat.Effect) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	em, exists := gs.effects[entityID]
+	if !exists {
+		em = combat.NewEffectManager()
+		gs.effects[entityID] = em
+	}
+	effect.Remaining = effect.Duration
+	em.AddEffect(&effect)
+}
+
+// GetEffects returns the out-of-combat status effects on a character or mob
+func (gs *GameServer) GetEffects(entityID string) []combat.Effect {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	em, exists := gs.effects[entityID]
+	if !exists {
+		return nil
+	}
+	effects := make([]combat.Effect, 0, len(em.Effects))
+	for _, effect := range em.Effects {
+		effects = append(effects, *effect)
+	}
+	return effects
+}
+
 // GetRegionAt returns the region at the given coordinates
 func (gs *GameServer) GetRegionAt(x, y int) string {
 	location := gs.GetLocationAt(x, y)
@@ -90,6 +341,18 @@ func (gs *GameServer) loadData() error {
 		return fmt.Errorf("failed to load characters: %v", err)
 	}
 	for _, char := range characters {
+		if err := gs.names.claim(char.Name, char.ID); err != nil {
+			log.Printf("Character %s has a conflicting name %s: %v", char.ID, char.Name, err)
+		}
+		if char.DeletedAt != nil {
+			gs.removedCharacters[char.ID] = char
+			continue
+		}
+		// Characters saved before they learned abilities from the class
+		// table catch up here
+		if char.LearnAbilities() {
+			gs.markCharacterDirty(char.ID)
+		}
 		gs.players[char.ID] = char
 	}
 
@@ -102,108 +365,518 @@ func (gs *GameServer) loadData() error {
 		gs.mobs[m.ID] = m
 	}
 
+	// Load lifetime combat statistics
+	stats, err := gs.repo.GetAllCombatStats()
+	if err != nil {
+		return fmt.Errorf("failed to load combat stats: %v", err)
+	}
+	for id, cs := range stats {
+		gs.combatStats[id] = cs
+	}
+
+	// Load the ranked season and ratings
+	season, err := gs.repo.GetSeason()
+	if err != nil {
+		return fmt.Errorf("failed to load season: %v", err)
+	}
+	gs.season = season
+	for _, bracket := range matchmaking.Brackets() {
+		ratings, err := gs.repo.GetRatings(bracket)
+		if err != nil {
+			return fmt.Errorf("failed to load %s ratings: %v", bracket, err)
+		}
+		gs.ratings[bracket] = ratings
+	}
+
+	// Load battles that were still being fought
+	battles, err := gs.repo.GetAllBattles()
+	if err != nil {
+		return fmt.Errorf("failed to load battles: %v", err)
+	}
+	for _, battle := range battles {
+		if battle.IsOver() {
+			continue
+		}
+		// A battle nothing has happened in yet has no record
+		if record, err := gs.repo.GetBattleRecord(battle.ID); err == nil {
+			battle.SetRecord(record)
+		}
+		gs.restoreBattle(battle)
+	}
+
 	return nil
 }
 
-// CreateCharacter creates a new character
-func (gs *GameServer) CreateCharacter(name string, class character.Class) (*character.Character, error) {
+// CreateCharacter creates a new character owned by an account
+func (gs *GameServer) CreateCharacter(owner, name string, class character.Class) (*character.Character, error) {
 	gs.mutex.Lock()
 	defer gs.mutex.Unlock()
 
-	if _, exists := gs.players[name]; exists {
-		return nil, fmt.Errorf("character with name %s already exists", name)
+	if gs.ownedCount(owner) >= maxCharactersPerAccount {
+		return nil, ErrCharacterLimit
 	}
 
 	char := character.NewCharacter(name, class)
+	if err := gs.names.Reserve(name, char.ID); err != nil {
+		return nil, err
+	}
+	char.Owner = owner
 	char.ApplyClassBonuses()
 
-	// Save to Redis
-	if err := gs.repo.SaveCharacter(char); err != nil {
-		return nil, fmt.Errorf("failed to save character: %v", err)
+	// The name is reserved in storage right away, so the character is saved
+	// with it rather than by the next autosave: a crash in between would keep
+	// the name for a character that was never stored
+	if gs.repo != nil {
+		if err := gs.repo.SaveCharacter(char); err != nil {
+			if err := gs.names.Release(name, char.ID); err != nil {
+				log.Printf("Failed to release name of unsaved character %s: %v", char.ID, err)
+			}
+			return nil, fmt.Errorf("failed to save character: %v", err)
+		}
+	} else {
+		gs.markCharacterDirty(char.ID)
 	}
 
-	gs.players[name] = char
+	gs.players[char.ID] = char
 	return char, nil
 }
 
-// StartCombat initiates a new combat between players
-func (gs *GameServer) StartCombat(participantNames []string) (*combat.CombatState, error) {
+// StartCombat initiates a new PvP battle between characters of an account
+func (gs *GameServer) StartCombat(owner string, participantIDs []string) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
+	}
+	defer gs.endAction()
+
 	gs.mutex.Lock()
 	defer gs.mutex.Unlock()
 
 	var participants []*character.Character
-	for _, name := range participantNames {
-		if char, exists := gs.players[name]; exists {
-			participants = append(participants, char)
-		} else {
-			return nil, fmt.Errorf("player %s not found", name)
+	for _, id := range participantIDs {
+		char, err := gs.ownedCharacter(owner, id)
+		if err != nil {
+			return nil, err
+		}
+		if gs.inBattle(char.ID) {
+			return nil, ErrCharacterInCombat
 		}
+		participants = append(participants, char)
 	}
 
 	if len(participants) < 2 {
 		return nil, fmt.Errorf("need at least 2 participants for combat")
 	}
+	for _, char := range participants[1:] {
+		if distanceBetween(participants[0].Position, char.Position) > battleReach {
+			return nil, ErrOutOfReach
+		}
+	}
+
+	positions := make([]common.Coordinates, len(participants))
+	for i, char := range participants {
+		positions[i] = char.Position
+	}
+	battle := gs.newBattle(combat.BattleTypePvP, positions...)
+	for _, char := range participants {
+		battle.AddPlayer(char)
+	}
 
-	combatState := combat.NewCombatState(participants)
+	gs.battles[battle.ID] = battle
+	gs.markBattleDirty(battle.ID)
+	return battle, nil
+}
 
-	// Save to Redis
-	if err := gs.repo.SaveCombatState(combatState); err != nil {
-		return nil, fmt.Errorf("failed to save combat state: %v", err)
+// ExecuteCombatAction performs the active character's ability in a battle.
+// Mobs in the battle take their turns right after.
+func (gs *GameServer) ExecuteCombatAction(battleID, characterID, abilityName, targetID string) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
 	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	battle, exists := gs.battles[battleID]
+	if !exists {
+		return nil, ErrBattleNotFound
+	}
+
+	if err := gs.playerAction(battle, characterID, abilityName, targetID); err != nil {
+		return nil, err
+	}
+	return battle, nil
+}
 
-	gs.activeGames[combatState.ID] = combatState
-	return combatState, nil
+// StartMobCombat starts a PvE battle between an account's character and a
+// mob, or returns the battle they are already fighting
+func (gs *GameServer) StartMobCombat(owner, characterID, mobID string) (*combat.Battle, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	if _, err := gs.ownedCharacter(owner, characterID); err != nil {
+		return nil, err
+	}
+	return gs.mobBattle(characterID, mobID)
 }
 
-// StartMobCombat starts combat between a character and a mob
-func (s *GameServer) StartMobCombat(characterID, mobID string) (*combat.MobCombat, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// AttackMob uses an account's character's ability on a mob, starting a
+// battle if needed
+func (gs *GameServer) AttackMob(owner, characterID, mobID, abilityName string) (*combat.Battle, error) {
+	if err := gs.beginAction(); err != nil {
+		return nil, err
+	}
+	defer gs.endAction()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	if _, err := gs.ownedCharacter(owner, characterID); err != nil {
+		return nil, err
+	}
+	battle, err := gs.mobBattle(characterID, mobID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A faster mob strikes before the character gets a turn
+	gs.runMobTurns(battle)
+	if battle.IsOver() {
+		return battle, nil
+	}
+
+	if err := gs.playerAction(battle, characterID, abilityName, mobID); err != nil {
+		return nil, err
+	}
+	return battle, nil
+}
 
-	char, exists := s.players[characterID]
+// mobBattle finds or creates the battle between a character and a mob; the
+// caller must hold the mutex
+func (gs *GameServer) mobBattle(characterID, mobID string) (*combat.Battle, error) {
+	char, exists := gs.players[characterID]
 	if !exists {
 		return nil, fmt.Errorf("character not found")
 	}
 
-	m, exists := s.mobs[mobID]
-	if !exists {
+	m, exists := gs.mobs[mobID]
+	if !exists || m.IsDead() {
 		return nil, fmt.Errorf("mob not found")
 	}
 
-	combat := combat.NewMobCombat(char, m)
-	return combat, nil
+	for _, battle := range gs.battles {
+		if !battle.IsOver() && battle.GetParticipant(char.ID) != nil && battle.GetParticipant(m.ID) != nil {
+			return battle, nil
+		}
+	}
+	if distanceBetween(char.Position, m.Position) > battleReach {
+		return nil, ErrOutOfReach
+	}
+	if gs.inBattle(char.ID) || gs.inBattle(m.ID) {
+		return nil, ErrCharacterInCombat
+	}
+
+	battle := gs.newBattle(combat.BattleTypePvE, char.Position, m.Position)
+	battle.AddPlayer(char)
+	battle.AddMob(m)
+
+	gs.battles[battle.ID] = battle
+	gs.markBattleDirty(battle.ID)
+	return battle, nil
 }
 
-// HandleMobCombatAction handles a combat action between a character and mob
-func (s *GameServer) HandleMobCombatAction(combat *combat.MobCombat, ability *common.Ability) (int, int, error) {
-	// Character attacks
-	charDamage, err := combat.CharacterAttack(ability)
-	if err != nil {
-		return 0, 0, err
+// playerAction performs a character's ability on its turn and lets the mobs
+// answer; the caller must hold the mutex
+func (gs *GameServer) playerAction(battle *combat.Battle, characterID, abilityName, targetID string) error {
+	if battle.IsOver() {
+		return ErrBattleOver
 	}
 
-	// Save character state
-	if err := s.repo.SaveCharacter(combat.Character); err != nil {
-		return 0, 0, fmt.Errorf("failed to save character state: %v", err)
+	actor := battle.ActiveParticipant()
+	if actor.ID != characterID {
+		return ErrNotYourTurn
 	}
 
-	// Check if combat is over after character attack
-	if combat.IsCombatOver() {
-		return charDamage, 0, nil
+	var ability *common.Ability
+	for i := range actor.Abilities {
+		if actor.Abilities[i].Name == abilityName {
+			ability = &actor.Abilities[i]
+			break
+		}
+	}
+	if ability == nil {
+		return fmt.Errorf("ability %s not found", abilityName)
 	}
 
-	// Mob attacks
-	mobDamage, err := combat.MobAttack()
-	if err != nil {
-		return charDamage, 0, err
+	if err := gs.battleAction(battle, actor, ability, targetID); err != nil {
+		return err
+	}
+	gs.runMobTurns(battle)
+	return nil
+}
+
+// runMobTurns lets mobs act until it is a player's turn or the battle ends;
+// the caller must hold the mutex
+func (gs *GameServer) runMobTurns(battle *combat.Battle) {
+	defer gs.registerAdds(battle)
+	for !battle.IsOver() {
+		gs.registerAdds(battle)
+		actor := battle.ActiveParticipant()
+		mc, ok := actor.Combatant().(*combat.MobCombatant)
+		if !ok {
+			return
+		}
+
+		if !battle.MobTurn(gs.mobBehavior(mc.Mob), mobActions{gs: gs, battle: battle, actor: actor}) {
+			battle.SkipTurn()
+			gs.markBattleDirty(battle.ID)
+			gs.publishTurnChanged(battle)
+		}
+	}
+}
+
+// mobActions carries out a mob's turn through the game server, so that every
+// step is published to the battle; the caller must hold the mutex
+type mobActions struct {
+	gs     *GameServer
+	battle *combat.Battle
+	actor  *combat.Participant
+}
+
+// Move performs a move for the mob
+func (a mobActions) Move(move func() error) error {
+	return a.gs.battleMove(a.battle, a.actor, move)
+}
+
+// Act uses an ability of the mob
+func (a mobActions) Act(ability *common.Ability, targetID string) error {
+	return a.gs.battleAction(a.battle, a.actor, ability, targetID)
+}
+
+// Flee lets the mob try to flee
+func (a mobActions) Flee() error {
+	return a.gs.battleFlee(a.battle)
+}
+
+// registerAdds adds mobs that joined a battle while it was fought, e.g. a
+// boss's adds, to the world so they are saved and restored with the battle;
+// the caller must hold the mutex
+func (gs *GameServer) registerAdds(battle *combat.Battle) {
+	for _, p := range battle.Participants {
+		if _, exists := gs.mobs[p.ID]; exists || p.Type != "mob" {
+			continue
+		}
+		if mc, ok := p.Combatant().(*combat.MobCombatant); ok {
+			gs.mobs[p.ID] = mc.Mob
+			gs.markMobDirty(p.ID)
+		}
+	}
+}
+
+// mobBehavior returns a mob's AI, creating it on first use; the caller must
+// hold the mutex
+func (gs *GameServer) mobBehavior(m *mob.Mob) *mob.AIBehavior {
+	ai, exists := gs.mobAI[m.ID]
+	if !exists {
+		ai = mob.NewAIBehavior(mob.MobType(m.Type))
+		gs.mobAI[m.ID] = ai
+	}
+	return ai
+}
+
+// battleMove performs a move on the battle grid and publishes where the
+// participant ended up; the caller must hold the mutex
+func (gs *GameServer) battleMove(battle *combat.Battle, actor *combat.Participant, move func() error) error {
+	from := actor.Position
+	if err := move(); err != nil {
+		return err
+	}
+	if actor.Position == from {
+		return nil
+	}
+
+	gs.markBattleDirty(battle.ID)
+	gs.publishToCombat(battle, EventCombatMoved, CombatMovedEvent{
+		BattleID:      battle.ID,
+		ParticipantID: actor.ID,
+		Position:      actor.Position,
+		MovementLeft:  actor.MovementLeft,
+	})
+	return nil
+}
+
+// battleFlee lets the active participant try to flee the battle; a failed
+// attempt ends its turn. The caller must hold the mutex.
+func (gs *GameServer) battleFlee(battle *combat.Battle) error {
+	levels := gs.playerLevels(battle)
+	if _, err := battle.Flee(); err != nil {
+		return err
+	}
+	gs.markBattleDirty(battle.ID)
+
+	if battle.IsOver() {
+		gs.finishBattle(battle, levels)
+	} else {
+		gs.publishTurnChanged(battle)
+	}
+	return nil
+}
+
+// battleAction executes an ability for the active participant and publishes
+// the outcome to the battle; the caller must hold the mutex
+func (gs *GameServer) battleAction(battle *combat.Battle, actor *combat.Participant, ability *common.Ability, targetID string) error {
+	target := battle.GetParticipant(targetID)
+	if target == nil {
+		return fmt.Errorf("target %s is not in this battle", targetID)
+	}
+
+	levels := gs.playerLevels(battle)
+	health := target.Health
+	if _, err := battle.ExecuteAction(ability, targetID); err != nil {
+		return err
+	}
+
+	gs.markBattleDirty(battle.ID)
+	for _, p := range battle.Participants {
+		switch p.Type {
+		case "player":
+			gs.markCharacterDirty(p.ID)
+		case "mob":
+			gs.markMobDirty(p.ID)
+		}
+	}
+
+	gs.publishToCombat(battle, EventDamage, DamageEvent{
+		BattleID:     battle.ID,
+		SourceID:     actor.ID,
+		TargetID:     target.ID,
+		Ability:      ability.Name,
+		Amount:       max(0, health-target.Health),
+		TargetHealth: target.Health,
+	})
+	if target.Health > health {
+		gs.publishToCombat(battle, EventHealing, DamageEvent{
+			BattleID:     battle.ID,
+			SourceID:     actor.ID,
+			TargetID:     target.ID,
+			Ability:      ability.Name,
+			Amount:       target.Health - health,
+			TargetHealth: target.Health,
+		})
+	}
+	gs.publishToCombat(battle, EventStatusEffects, StatusEffectsEvent{
+		BattleID: battle.ID,
+		TargetID: target.ID,
+		Effects:  battle.GetStatusEffects(target.ID),
+	})
+
+	if !battle.IsOver() {
+		gs.publishTurnChanged(battle)
+		return nil
+	}
+
+	gs.finishBattle(battle, levels)
+	return nil
+}
+
+// playerLevels records the level of every character in a battle, so level
+// ups from battle rewards can be announced; the caller must hold the mutex
+func (gs *GameServer) playerLevels(battle *combat.Battle) map[string]int {
+	levels := make(map[string]int)
+	for _, p := range battle.Participants {
+		if cc, ok := p.Combatant().(*combat.CharacterCombatant); ok {
+			levels[p.ID] = cc.Character.Level
+		}
 	}
+	return levels
+}
 
-	// Save character state again after mob attack
-	if err := s.repo.SaveCharacter(combat.Character); err != nil {
-		return 0, 0, fmt.Errorf("failed to save character state: %v", err)
+// finishBattle announces loot, level ups and the end of a battle; the caller
+// must hold the mutex
+func (gs *GameServer) finishBattle(battle *combat.Battle, levels map[string]int) {
+	gs.finishedBattles[battle.ID] = time.Now()
+
+	for _, id := range battle.Winners {
+		if p := battle.GetParticipant(id); p != nil && p.Type == "player" {
+			gs.publishTo(EventLoot, LootEvent{
+				CharacterID: id,
+				BattleID:    battle.ID,
+				Experience:  p.Experience,
+				Money:       p.Money,
+				Items:       p.Loot,
+			}, id)
+		}
+	}
+	gs.recordBattleResult(battle)
+	gs.rateBattle(battle)
+	for id, level := range levels {
+		if char := gs.players[id]; char != nil && char.Level > level {
+			gs.publishNear(EventLevelUp, LevelUpEvent{CharacterID: id, Level: char.Level}, char.Position)
+		}
 	}
 
-	return charDamage, mobDamage, nil
+	ended := CombatEndedEvent{BattleID: battle.ID, Result: battle.State}
+	if len(battle.Winners) > 0 {
+		ended.WinnerID = battle.Winners[0]
+	}
+	gs.publishToCombat(battle, EventCombatEnded, ended)
+
+	// Bosses and their adds only exist for their raid
+	if battle.Boss != nil {
+		for _, p := range battle.Participants {
+			if p.Type == "mob" {
+				delete(gs.mobs, p.ID)
+				delete(gs.mobAI, p.ID)
+				gs.markMobDeleted(p.ID)
+			}
+		}
+	}
+}
+
+// recordBattleResult merges a finished battle's statistics into the lifetime
+// statistics of its characters and saves the battle, the rewarded characters
+// and their statistics together; the caller must hold the mutex
+func (gs *GameServer) recordBattleResult(battle *combat.Battle) {
+	chars := make([]*character.Character, 0, len(battle.Participants))
+	stats := make(map[string]*combat.CombatStats)
+	for _, p := range battle.Participants {
+		char := gs.players[p.ID]
+		if p.Type != "player" || char == nil {
+			continue
+		}
+		lifetime, exists := gs.combatStats[p.ID]
+		if !exists {
+			lifetime = combat.NewCombatStats()
+			gs.combatStats[p.ID] = lifetime
+		}
+		lifetime.Merge(battle.Stats[p.ID])
+		chars = append(chars, char)
+		stats[p.ID] = lifetime
+		gs.markCharacterDirty(p.ID)
+		gs.markStatsDirty(p.ID)
+	}
+
+	if gs.repo == nil {
+		return
+	}
+	if err := gs.repo.SaveBattleResult(battle, chars, stats); err != nil {
+		log.Printf("Failed to save result of battle %s: %v", battle.ID, err)
+		return
+	}
+	delete(gs.dirtyBattles, battle.ID)
+	for _, char := range chars {
+		delete(gs.dirtyCharacters, char.ID)
+		delete(gs.dirtyStats, char.ID)
+	}
+}
+
+// publishTurnChanged tells a battle whose turn it is; the caller must hold the mutex
+func (gs *GameServer) publishTurnChanged(battle *combat.Battle) {
+	gs.publishToCombat(battle, EventTurnChanged, TurnChangedEvent{
+		BattleID:          battle.ID,
+		Round:             battle.Round,
+		ActiveCharacterID: battle.ActiveParticipant().ID,
+	})
 }
 
 // SaveGameState saves the current game state to Redis
@@ -225,10 +898,10 @@ func (gs *GameServer) SaveGameState() error {
 		}
 	}
 
-	// Save all active combat states
-	for _, state := range gs.activeGames {
-		if err := gs.repo.SaveCombatState(state); err != nil {
-			return fmt.Errorf("failed to save combat state %s: %v", state.ID, err)
+	// Save all battles
+	for _, battle := range gs.battles {
+		if err := gs.repo.SaveBattle(battle); err != nil {
+			return fmt.Errorf("failed to save battle %s: %v", battle.ID, err)
 		}
 	}
 
@@ -244,6 +917,13 @@ func (gs *GameServer) MoveCharacter(characterID string, x, y int) error {
 	if !exists {
 		return fmt.Errorf("character not found")
 	}
+	if gs.inBattle(characterID) {
+		return ErrCharacterInCombat
+	}
+	// Characters walk from tile to tile, paying for the terrain of each
+	if distanceBetween(character.Position, common.Coordinates{X: x, Y: y}) > maxMoveDistance {
+		return ErrMoveTooFar
+	}
 
 	// Check if the target location is valid
 	targetLocation := gs.GetLocationAt(x, y)
@@ -267,14 +947,17 @@ func (gs *GameServer) MoveCharacter(characterID string, x, y int) error {
 	}
 
 	// Update character position and movement points
+	from := character.Position
 	character.Position = common.Coordinates{X: x, Y: y}
 	character.MovementPoints -= movementCost
 
-	// Save character state
-	if err := gs.repo.SaveCharacter(character); err != nil {
-		return fmt.Errorf("failed to save character state: %v", err)
-	}
+	gs.publishNear(EventCharacterMoved, MovedEvent{
+		ID:       character.ID,
+		Name:     character.Name,
+		Position: character.Position,
+	}, from, character.Position)
 
+	gs.markCharacterDirty(character.ID)
 	return nil
 }
 
@@ -291,28 +974,114 @@ func (gs *GameServer) GetCharacterLocation(characterID string) (common.Coordinat
 	return character.Position, nil
 }
 
+// Snapshot returns a character together with everything within its interest range
+func (gs *GameServer) Snapshot(characterID string) (*SnapshotEvent, error) {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	char, exists := gs.players[characterID]
+	if !exists {
+		return nil, fmt.Errorf("character %s not found", characterID)
+	}
+
+	snapshot := &SnapshotEvent{Character: char}
+	for _, other := range gs.nearbyCharacters(char.Position, interestRadius) {
+		if other.ID != char.ID {
+			snapshot.Characters = append(snapshot.Characters, MovedEvent{ID: other.ID, Name: other.Name, Position: other.Position})
+		}
+	}
+	for _, m := range gs.mobs {
+		if !m.IsDead() && distanceBetween(char.Position, m.Position) <= interestRadius {
+			snapshot.Mobs = append(snapshot.Mobs, mobMovedEvent(m))
+		}
+	}
+	return snapshot, nil
+}
+
 // GetNearbyCharacters returns all characters within a certain distance
 func (gs *GameServer) GetNearbyCharacters(x, y, distance int) []*character.Character {
 	gs.mutex.RLock()
 	defer gs.mutex.RUnlock()
 
-	var nearby []*character.Character
-	center := common.Coordinates{X: x, Y: y}
+	return gs.nearbyCharacters(common.Coordinates{X: x, Y: y}, distance)
+}
 
+// nearbyCharacters returns all characters within a certain distance; the caller must hold the mutex
+func (gs *GameServer) nearbyCharacters(center common.Coordinates, distance int) []*character.Character {
+	var nearby []*character.Character
 	for _, char := range gs.players {
 		if distanceBetween(center, char.Position) <= distance {
 			nearby = append(nearby, char)
 		}
 	}
-
 	return nearby
 }
 
+// publishNear sends an event to every character within interest range of any
+// of the given positions; the caller must hold the mutex
+func (gs *GameServer) publishNear(eventType EventType, data interface{}, positions ...common.Coordinates) {
+	gs.events.Publish(Event{Type: eventType, Data: data}, func(characterID string) bool {
+		char, exists := gs.players[characterID]
+		if !exists {
+			return false
+		}
+		for _, pos := range positions {
+			if distanceBetween(char.Position, pos) <= interestRadius {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// publishTo sends an event to the given characters only
+func (gs *GameServer) publishTo(eventType EventType, data interface{}, characterIDs ...string) {
+	gs.events.Publish(Event{Type: eventType, Data: data}, func(characterID string) bool {
+		for _, id := range characterIDs {
+			if id == characterID {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// publishToCombat sends an event to every member of a battle, and to its
+// spectators once the battle's spectator delay has passed
+func (gs *GameServer) publishToCombat(battle *combat.Battle, eventType EventType, data interface{}) {
+	ids := make([]string, 0, len(battle.Participants))
+	for _, p := range battle.Participants {
+		ids = append(ids, p.ID)
+	}
+	gs.publishTo(eventType, data, ids...)
+	gs.broadcastEvent(battle, eventType, data)
+}
+
 // distanceBetween calculates the Manhattan distance between two points
 func distanceBetween(a, b common.Coordinates) int {
 	return int(math.Abs(float64(a.X-b.X)) + math.Abs(float64(a.Y-b.Y)))
 }
 
+// stepToward returns the position one tile from a in the direction of b
+func stepToward(a, b common.Coordinates) common.Coordinates {
+	return common.Coordinates{X: a.X + sign(b.X-a.X), Y: a.Y + sign(b.Y-a.Y)}
+}
+
+// stepAway returns the position one tile from a away from b
+func stepAway(a, b common.Coordinates) common.Coordinates {
+	return common.Coordinates{X: a.X - sign(b.X-a.X), Y: a.Y - sign(b.Y-a.Y)}
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
 // GetNearbyMobs returns all mobs within a certain distance
 func (gs *GameServer) GetNearbyMobs(x, y, distance int) []*mob.Mob {
 	gs.mutex.RLock()
@@ -348,4 +1117,5 @@ func (gs *GameServer) RemoveMob(mobID string) {
 	defer gs.mutex.Unlock()
 
 	delete(gs.mobs, mobID)
+	gs.markMobDeleted(mobID)
 }
@@ -0,0 +1,383 @@
+package game
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// newTestFighter creates a character with a single attack ability
+func newTestFighter(id string, dexterity int) *character.Character {
+	return &character.Character{
+		ID:            id,
+		Name:          id,
+		Health:        100,
+		MaxHealth:     100,
+		SteamPower:    50,
+		MaxSteamPower: 50,
+		Stats:         common.Stats{Dexterity: dexterity},
+		Abilities: []common.Ability{
+			// Reaches across the battle grid, so tests can attack without moving
+			{Name: "Wrench Strike", Type: "damage", Damage: 20, SteamCost: 5, Range: 10},
+		},
+	}
+}
+
+func TestAttackMob(t *testing.T) {
+	gs := newTestServer()
+	char := newTestFighter("player1", 10)
+	char.Owner = "alice"
+	gs.players[char.ID] = char
+	m := &mob.Mob{
+		ID:            "mob1",
+		Name:          "Steam Rat",
+		Type:          string(mob.Mechanical),
+		Health:        100,
+		MaxHealth:     100,
+		SteamPower:    20,
+		MaxSteamPower: 20,
+		Abilities: []common.Ability{
+			{Name: "Bite", Type: "damage", Damage: 10, SteamCost: 5},
+		},
+	}
+	gs.mobs[m.ID] = m
+
+	if _, err := gs.AttackMob("bob", char.ID, m.ID, "Wrench Strike"); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	if _, err := gs.StartMobCombat("bob", char.ID, m.ID); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	distant := &mob.Mob{ID: "mob2", Name: "Distant Rat", Health: 100, MaxHealth: 100, Position: common.Coordinates{X: battleReach + 1}}
+	gs.mobs[distant.ID] = distant
+	if _, err := gs.AttackMob("alice", char.ID, distant.ID, "Wrench Strike"); !errors.Is(err, ErrOutOfReach) {
+		t.Errorf("Expected ErrOutOfReach, got %v", err)
+	}
+
+	battle, err := gs.AttackMob("alice", char.ID, m.ID, "Wrench Strike")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Attacks may miss, crit or vary, so the damage is checked against the log
+	if len(battle.CombatLog) != 2 || battle.CombatLog[1].Character != m.Name {
+		t.Fatalf("Expected the character's attack and the mob's answer, got %+v", battle.CombatLog)
+	}
+	if m.Health != 100-battle.CombatLog[0].Damage {
+		t.Errorf("Expected mob health %d, got %d", 100-battle.CombatLog[0].Damage, m.Health)
+	}
+	if char.Health != 100-battle.CombatLog[1].Damage {
+		t.Errorf("Expected character health %d, got %d", 100-battle.CombatLog[1].Damage, char.Health)
+	}
+	if battle.ActiveParticipant().ID != char.ID {
+		t.Errorf("Expected character's turn, got %s", battle.ActiveParticipant().ID)
+	}
+	if !gs.dirtyBattles[battle.ID] || !gs.dirtyCharacters[char.ID] || !gs.dirtyMobs[m.ID] {
+		t.Error("Expected battle, character and mob to be marked dirty")
+	}
+
+	// The next attack continues the same battle
+	again, err := gs.AttackMob("alice", char.ID, m.ID, "Wrench Strike")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if again.ID != battle.ID {
+		t.Errorf("Expected battle %s to continue, got %s", battle.ID, again.ID)
+	}
+
+	// Neither the character nor the mob can be pulled into another fight
+	other := newTestFighter("player2", 10)
+	other.Owner = "alice"
+	gs.players[other.ID] = other
+	if _, err := gs.AttackMob("alice", other.ID, m.ID, "Wrench Strike"); !errors.Is(err, ErrCharacterInCombat) {
+		t.Errorf("Expected ErrCharacterInCombat for the mob, got %v", err)
+	}
+	if _, err := gs.StartCombat("alice", []string{char.ID, other.ID}); !errors.Is(err, ErrCharacterInCombat) {
+		t.Errorf("Expected ErrCharacterInCombat for the character, got %v", err)
+	}
+	if len(gs.battles) != 1 {
+		t.Errorf("Expected 1 battle, got %d", len(gs.battles))
+	}
+}
+
+func TestExecuteCombatActionTurns(t *testing.T) {
+	gs := newTestServer()
+	fast := newTestFighter("fast", 20)
+	slow := newTestFighter("slow", 10)
+	fast.Owner = "alice"
+	slow.Owner = "alice"
+	gs.players[fast.ID] = fast
+	gs.players[slow.ID] = slow
+
+	if _, err := gs.StartCombat("bob", []string{slow.ID, fast.ID}); !errors.Is(err, ErrNotCharacterOwner) {
+		t.Errorf("Expected ErrNotCharacterOwner, got %v", err)
+	}
+	battle, err := gs.StartCombat("alice", []string{slow.ID, fast.ID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := gs.StartCombat("alice", []string{slow.ID, fast.ID}); !errors.Is(err, ErrCharacterInCombat) {
+		t.Errorf("Expected ErrCharacterInCombat, got %v", err)
+	}
+
+	if _, err := gs.ExecuteCombatAction(battle.ID, slow.ID, "Wrench Strike", fast.ID); err == nil {
+		t.Error("Expected error when acting out of turn")
+	}
+	if _, err := gs.ExecuteCombatAction(battle.ID, fast.ID, "Fireball", slow.ID); err == nil {
+		t.Error("Expected error for unknown ability")
+	}
+	if _, err := gs.ExecuteCombatAction(battle.ID, fast.ID, "Wrench Strike", slow.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := 100 - battle.CombatLog[0].Damage; slow.Health != expected {
+		t.Errorf("Expected slow character health %d, got %d", expected, slow.Health)
+	}
+	if battle.ActiveParticipant().ID != slow.ID {
+		t.Errorf("Expected slow character's turn, got %s", battle.ActiveParticipant().ID)
+	}
+
+	// Characters in an ongoing battle cannot be deleted
+	fast.Owner = "tester"
+	if err := gs.DeleteCharacter("tester", fast.ID, time.Now()); err != ErrCharacterInCombat {
+		t.Errorf("Expected ErrCharacterInCombat, got %v", err)
+	}
+}
+
+func TestMobTurns(t *testing.T) {
+	gs := newTestServer()
+	char := newTestFighter("player1", 10)
+	char.Owner = "alice"
+	gs.players[char.ID] = char
+	m := &mob.Mob{
+		ID:            "mob1",
+		Name:          "Steam Rat",
+		Type:          string(mob.Biological),
+		Health:        100,
+		MaxHealth:     100,
+		SteamPower:    20,
+		MaxSteamPower: 20,
+		Attributes:    common.Attributes{Dexterity: common.Attribute{Name: "Dexterity", Value: 30}},
+		Abilities: []common.Ability{
+			{Name: "Bite", Type: "damage", Damage: 10, SteamCost: 5, Range: 1},
+		},
+	}
+	gs.mobs[m.ID] = m
+
+	// The faster mob closes in on the character and bites
+	battle, err := gs.CreateBattle("alice", char.ID, nil, []string{m.ID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if battle.Distance(m.ID, char.ID) > 1 {
+		t.Errorf("Expected mob to close in, got distance %d", battle.Distance(m.ID, char.ID))
+	}
+	if battle.ActiveParticipant().ID != char.ID {
+		t.Errorf("Expected character's turn, got %s", battle.ActiveParticipant().ID)
+	}
+	if gs.mobAI[m.ID].State != mob.Aggressive {
+		t.Errorf("Expected aggressive mob, got %s", gs.mobAI[m.ID].State)
+	}
+
+	// A badly hurt mob flees from its edge of the battlefield without
+	// dropping loot
+	gs.ForfeitBattle("alice", battle.ID)
+	char.Health = 100
+	m.Health = 10
+	fled, err := gs.CreateBattle("alice", char.ID, nil, []string{m.ID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !fled.IsOver() || !fled.GetParticipant(m.ID).Fled {
+		t.Fatal("Expected the mob to flee and end the battle")
+	}
+	if len(fled.Winners) != 1 || fled.Winners[0] != char.ID {
+		t.Errorf("Expected character to win, got %v", fled.Winners)
+	}
+	if m.IsDead() || m.Health != 10 {
+		t.Errorf("Expected fled mob to keep its health, got %d", m.Health)
+	}
+	if gs.mobAI[m.ID].State != mob.Fleeing {
+		t.Errorf("Expected fleeing mob, got %s", gs.mobAI[m.ID].State)
+	}
+}
+
+func TestMobFollowsThreat(t *testing.T) {
+	gs := newTestServer()
+	near := newTestFighter("near", 10)
+	tank := newTestFighter("tank", 10)
+	gs.players[near.ID] = near
+	gs.players[tank.ID] = tank
+	m := &mob.Mob{
+		ID:            "mob1",
+		Name:          "Steam Rat",
+		Health:        100,
+		MaxHealth:     100,
+		SteamPower:    20,
+		MaxSteamPower: 20,
+		Attributes:    common.Attributes{Dexterity: common.Attribute{Name: "Dexterity", Value: 30}},
+		Abilities: []common.Ability{
+			{Name: "Spit", Type: "damage", Damage: 10, SteamCost: 5},
+		},
+	}
+	gs.mobs[m.ID] = m
+
+	battle := combat.NewBattle(combat.BattleTypePvE)
+	battle.AddPlayer(near)
+	battle.AddPlayer(tank)
+	battle.AddMob(m)
+	battle.GetParticipant(near.ID).Position = common.Coordinates{X: 5, Y: 0}
+	battle.GetParticipant(tank.ID).Position = common.Coordinates{X: 0, Y: 0}
+	battle.GetParticipant(m.ID).Position = common.Coordinates{X: 6, Y: 0}
+	battle.Threat = map[string]map[string]int{m.ID: {tank.ID: 30, near.ID: 5}}
+	gs.battles[battle.ID] = battle
+
+	// The mob ignores the nearer character for the one with more threat
+	gs.runMobTurns(battle)
+	if last := battle.CombatLog[len(battle.CombatLog)-1]; last.Character != m.Name || last.Target != tank.Name {
+		t.Errorf("Expected the mob to attack the tank, got %+v", last)
+	}
+}
+
+func TestMobSupportsOwnSide(t *testing.T) {
+	gs := newTestServer()
+	char := newTestFighter("player1", 10)
+	gs.players[char.ID] = char
+	healer := &mob.Mob{
+		ID:            "healer",
+		Name:          "Steam Rat",
+		Health:        100,
+		MaxHealth:     100,
+		SteamPower:    50,
+		MaxSteamPower: 50,
+		Attributes:    common.Attributes{Dexterity: common.Attribute{Name: "Dexterity", Value: 30}},
+		Abilities: []common.Ability{
+			{Name: "Steam Punch", Damage: 10, SteamCost: 5},
+			{Name: "Steam Regeneration", Type: "healing", Healing: 40, SteamCost: 10, Range: 2},
+			{Name: "Chemical Rage", Type: "buff", SteamCost: 10, Cooldown: 6},
+		},
+	}
+	hurt := &mob.Mob{ID: "hurt", Name: "Hurt Rat", Health: 30, MaxHealth: 100}
+	gs.mobs[healer.ID] = healer
+	gs.mobs[hurt.ID] = hurt
+
+	battle := combat.NewBattle(combat.BattleTypePvE)
+	battle.AddPlayer(char)
+	battle.AddMob(healer)
+	battle.AddMob(hurt)
+	battle.GetParticipant(char.ID).Position = common.Coordinates{X: 0, Y: 0}
+	battle.GetParticipant(healer.ID).Position = common.Coordinates{X: 1, Y: 0}
+	battle.GetParticipant(hurt.ID).Position = common.Coordinates{X: 2, Y: 0}
+	gs.battles[battle.ID] = battle
+
+	// The wounded ally is healed rather than the character
+	gs.runMobTurns(battle)
+	first := battle.CombatLog[0]
+	if first.Action != "Steam Regeneration" || first.Target != hurt.Name {
+		t.Fatalf("Expected the healer to heal its ally, got %+v", first)
+	}
+	if battle.GetParticipant(char.ID).Health != 100 {
+		t.Errorf("Expected the character not to be healed, got %d health", battle.GetParticipant(char.ID).Health)
+	}
+
+	// Buffs go to the mob's own side too, here to the healer itself
+	hurt.Health = 100
+	healer.Health = 90
+	battle.GetParticipant(healer.ID).Cooldowns = nil
+	battle.CombatLog = nil
+	for battle.ActiveParticipant().ID != healer.ID {
+		battle.SkipTurn()
+	}
+	gs.runMobTurns(battle)
+	if first := battle.CombatLog[0]; first.Action != "Chemical Rage" || first.Target != healer.Name {
+		t.Errorf("Expected the healer to buff its own side, got %+v", first)
+	}
+}
+
+func TestMobUsesUntypedAbilities(t *testing.T) {
+	gs := newTestServer()
+	char := newTestFighter("player1", 10)
+	gs.players[char.ID] = char
+	m := mob.NewMob("Steam Golem", mob.Mechanical, 1)
+	m.Attributes.Dexterity.Value = 30
+	gs.mobs[m.ID] = m
+
+	battle := combat.NewBattle(combat.BattleTypePvE)
+	battle.AddPlayer(char)
+	battle.AddMob(m)
+	battle.GetParticipant(char.ID).Position = common.Coordinates{X: 0, Y: 0}
+	battle.GetParticipant(m.ID).Position = common.Coordinates{X: 1, Y: 0}
+	gs.battles[battle.ID] = battle
+
+	gs.runMobTurns(battle)
+	if len(battle.CombatLog) == 0 || battle.CombatLog[0].Action != "Steam Punch" || battle.CombatLog[0].Target != char.Name {
+		t.Fatalf("Expected the mob to use its untyped Steam Punch on the character, got %+v", battle.CombatLog)
+	}
+}
+
+func TestBattleRewardsRecorded(t *testing.T) {
+	gs := newTestServer()
+	char := newTestFighter("player1", 10)
+	char.Owner = "alice"
+	gs.players[char.ID] = char
+	m := &mob.Mob{
+		ID:            "mob1",
+		Name:          "Steam Rat",
+		Type:          string(mob.Mechanical),
+		Health:        30,
+		MaxHealth:     30,
+		Experience:    100,
+		MoneyDrop:     common.NewCurrency(0, 5, 0, 0),
+		LootTable:     common.LootTable{Items: []common.LootItem{{ID: "brass_gear", Name: "Brass Gear", Quantity: 1, Chance: 1.0}}},
+		SteamPower:    20,
+		MaxSteamPower: 20,
+	}
+	gs.mobs[m.ID] = m
+	// A fled rat drops nothing, so it fights to the end
+	gs.mobBehavior(m).FleeThreshold = 0
+	sub := gs.events.Subscribe(char.ID)
+
+	// Attacks may miss, so keep attacking until the rat is down
+	var battle *combat.Battle
+	for i := 0; i < 20 && (battle == nil || !battle.IsOver()); i++ {
+		var err error
+		if battle, err = gs.AttackMob("alice", char.ID, m.ID, "Wrench Strike"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if !battle.IsOver() || len(battle.Winners) != 1 || battle.Winners[0] != char.ID {
+		t.Fatalf("Expected character to win, got %v", battle.Winners)
+	}
+
+	if char.Experience != 100 {
+		t.Errorf("Expected 100 experience, got %d", char.Experience)
+	}
+	if char.Money.Silver != 5 {
+		t.Errorf("Expected 5 silver, got %d", char.Money.Silver)
+	}
+	if len(char.Inventory) != 1 || char.Inventory[0].ID != "brass_gear" {
+		t.Errorf("Expected a brass gear in the inventory, got %+v", char.Inventory)
+	}
+
+	stats := gs.combatStats[char.ID]
+	if stats == nil || stats.Victories != 1 || stats.Kills != 1 || stats.LootCollected["Brass Gear"] != 1 {
+		t.Errorf("Expected lifetime stats to record the victory, kill and loot, got %+v", stats)
+	}
+	if !gs.dirtyStats[char.ID] || !gs.dirtyCharacters[char.ID] {
+		t.Error("Expected character and stats to be marked dirty")
+	}
+
+	var loot *LootEvent
+	for len(sub.Events) > 0 {
+		if event := <-sub.Events; event.Type == EventLoot {
+			e := event.Data.(LootEvent)
+			loot = &e
+		}
+	}
+	if loot == nil || loot.Experience != 100 || len(loot.Items) != 1 {
+		t.Errorf("Expected a loot event with the rewards, got %+v", loot)
+	}
+}
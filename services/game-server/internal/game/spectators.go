@@ -0,0 +1,264 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+const (
+	// defaultSpectatorDelay is how far behind the fight spectators see a
+	// public battle unless its participants chose otherwise
+	defaultSpectatorDelay = 10 * time.Second
+	// maxSpectatorDelay is the longest delay a public battle can have
+	maxSpectatorDelay = 5 * time.Minute
+)
+
+var (
+	// ErrBattleNotPublic is returned when spectating a battle that is not open to spectators
+	ErrBattleNotPublic = errors.New("battle is not open to spectators")
+	// ErrInvalidSpectatorDelay is returned for a spectator delay out of range
+	ErrInvalidSpectatorDelay = errors.New("spectator delay must be between 0 and 5 minutes")
+	// ErrRankedBattlePublic is returned when closing a ranked battle to spectators
+	ErrRankedBattlePublic = errors.New("ranked battles are always open to spectators")
+	// ErrSpectatorCannotAct is returned for commands sent by a spectator
+	ErrSpectatorCannotAct = errors.New("spectators cannot act in a battle")
+)
+
+// ParticipantView is what spectators see of a participant. Its steam power,
+// abilities, cooldowns and rewards stay hidden.
+type ParticipantView struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Type      string             `json:"type"`
+	Class     character.Class    `json:"class,omitempty"`
+	Level     int                `json:"level"`
+	Team      string             `json:"team,omitempty"`
+	OwnerID   string             `json:"owner_id,omitempty"`
+	Health    int                `json:"health"`
+	MaxHealth int                `json:"max_health"`
+	Position  common.Coordinates `json:"position"`
+	IsActive  bool               `json:"is_active"`
+	Fled      bool               `json:"fled,omitempty"`
+}
+
+// BattleView is a battle as spectators see it
+type BattleView struct {
+	BattleID            string                           `json:"battle_id"`
+	Type                combat.BattleType                `json:"type"`
+	Bracket             string                           `json:"bracket,omitempty"`
+	State               string                           `json:"state"`
+	Round               int                              `json:"round"`
+	ActiveParticipantID string                           `json:"active_participant_id,omitempty"`
+	Terrain             string                           `json:"terrain"`
+	Weather             string                           `json:"weather"`
+	Grid                *combat.Grid                     `json:"grid,omitempty"`
+	Participants        []ParticipantView                `json:"participants"`
+	StatusEffects       map[string][]combat.StatusEffect `json:"status_effects,omitempty"`
+	Winners             []string                         `json:"winners,omitempty"`
+	StartedAt           time.Time                        `json:"started_at"`
+}
+
+// PublicBattle is a running public battle as listed for spectators
+type PublicBattle struct {
+	Battle     BattleView `json:"battle"`
+	Delay      int        `json:"delay"` // seconds spectators are behind the fight
+	Spectators int        `json:"spectators"`
+}
+
+// newBattleView captures what spectators may see of a battle right now
+func newBattleView(battle *combat.Battle) BattleView {
+	view := BattleView{
+		BattleID:      battle.ID,
+		Type:          battle.Type,
+		Bracket:       battle.Bracket,
+		State:         battle.State,
+		Round:         battle.Round,
+		Terrain:       battle.Terrain,
+		Weather:       battle.Weather,
+		Grid:          battle.Grid,
+		Participants:  make([]ParticipantView, 0, len(battle.Participants)),
+		StatusEffects: make(map[string][]combat.StatusEffect),
+		Winners:       append([]string(nil), battle.Winners...),
+		StartedAt:     battle.StartedAt,
+	}
+	if active := battle.ActiveParticipant(); active != nil && !battle.IsOver() {
+		view.ActiveParticipantID = active.ID
+	}
+	for _, p := range battle.Participants {
+		view.Participants = append(view.Participants, ParticipantView{
+			ID:        p.ID,
+			Name:      p.Name,
+			Type:      p.Type,
+			Class:     p.Class,
+			Level:     p.Level,
+			Team:      p.Team,
+			OwnerID:   p.OwnerID,
+			Health:    p.Health,
+			MaxHealth: p.MaxHealth,
+			Position:  p.Position,
+			IsActive:  p.IsActive,
+			Fled:      p.Fled,
+		})
+		if effects := battle.GetStatusEffects(p.ID); len(effects) > 0 {
+			view.StatusEffects[p.ID] = append([]combat.StatusEffect(nil), effects...)
+		}
+	}
+	return view
+}
+
+// delayedEvent is a battle event held back from spectators until it is due,
+// together with the battle as it was right after the event
+type delayedEvent struct {
+	due   time.Time
+	event Event
+	view  BattleView
+}
+
+// broadcast holds the stream of a public battle for its spectators, who see
+// it delay behind the fight
+type broadcast struct {
+	delay   time.Duration
+	view    BattleView // the battle as spectators currently see it
+	pending []delayedEvent
+}
+
+// openBroadcast opens a battle to spectators with the given delay; the
+// caller must hold the mutex
+func (gs *GameServer) openBroadcast(battle *combat.Battle, delay time.Duration) {
+	battle.Public = true
+	battle.SpectatorDelay = delay
+	if b := gs.broadcasts[battle.ID]; b != nil {
+		// Events already held back keep their release time
+		b.delay = delay
+		return
+	}
+	gs.broadcasts[battle.ID] = &broadcast{delay: delay, view: newBattleView(battle)}
+}
+
+// SetBattleBroadcast opens a battle an account has a character in to
+// spectators, who see it delay behind the fight, or closes it again. Ranked
+// battles cannot be closed.
+func (gs *GameServer) SetBattleBroadcast(owner, id string, public bool, delay time.Duration) (*combat.Battle, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	battle, err := gs.participantBattle(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if battle.IsOver() {
+		return nil, ErrBattleOver
+	}
+	if delay < 0 || delay > maxSpectatorDelay {
+		return nil, ErrInvalidSpectatorDelay
+	}
+
+	if public {
+		gs.openBroadcast(battle, delay)
+	} else {
+		if battle.Bracket != "" {
+			return nil, ErrRankedBattlePublic
+		}
+		battle.Public = false
+		delete(gs.broadcasts, battle.ID)
+	}
+	gs.markBattleDirty(battle.ID)
+	return battle, nil
+}
+
+// ListPublicBattles returns the running battles open to spectators, as
+// spectators currently see them, longest running first
+func (gs *GameServer) ListPublicBattles() []PublicBattle {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	battles := []PublicBattle{}
+	for id, b := range gs.broadcasts {
+		if battle := gs.battles[id]; battle == nil || battle.IsOver() {
+			continue
+		}
+		battles = append(battles, PublicBattle{
+			Battle:     b.view,
+			Delay:      int(b.delay / time.Second),
+			Spectators: gs.events.Spectators(id),
+		})
+	}
+	sort.Slice(battles, func(i, j int) bool {
+		if !battles[i].Battle.StartedAt.Equal(battles[j].Battle.StartedAt) {
+			return battles[i].Battle.StartedAt.Before(battles[j].Battle.StartedAt)
+		}
+		return battles[i].Battle.BattleID < battles[j].Battle.BattleID
+	})
+	return battles
+}
+
+// Spectate subscribes an account to the broadcast of a public battle and
+// sends it the battle as spectators currently see it. Spectators only
+// receive events; they cannot act.
+func (gs *GameServer) Spectate(owner, id string) (*Subscriber, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	if owner == "" {
+		return nil, ErrBattleNotPublic
+	}
+	if _, exists := gs.battles[id]; !exists {
+		return nil, ErrBattleNotFound
+	}
+	b := gs.broadcasts[id]
+	if b == nil {
+		return nil, ErrBattleNotPublic
+	}
+
+	// Holding the mutex, no held back event can be released before the
+	// spectator has its view
+	sub := gs.events.SubscribeSpectator(id)
+	gs.events.Send(sub, Event{Type: EventSpectating, Data: b.view})
+	return sub, nil
+}
+
+// broadcastEvent holds a battle event back for the spectators of a public
+// battle; the caller must hold the mutex
+func (gs *GameServer) broadcastEvent(battle *combat.Battle, eventType EventType, data interface{}) {
+	b := gs.broadcasts[battle.ID]
+	if b == nil {
+		return
+	}
+	// The effects are the battle's own, which keep changing until released
+	if effects, ok := data.(StatusEffectsEvent); ok {
+		effects.Effects = append([]combat.StatusEffect(nil), effects.Effects...)
+		data = effects
+	}
+	b.pending = append(b.pending, delayedEvent{
+		due:   time.Now().Add(b.delay),
+		event: Event{Type: eventType, Data: data},
+		view:  newBattleView(battle),
+	})
+}
+
+// releaseBroadcasts sends spectators the battle events that are due and
+// closes the broadcasts of finished battles once everything was sent; the
+// caller must hold the mutex
+func (gs *GameServer) releaseBroadcasts(now time.Time) {
+	for id, b := range gs.broadcasts {
+		released := 0
+		for _, delayed := range b.pending {
+			if now.Before(delayed.due) {
+				break
+			}
+			b.view = delayed.view
+			gs.events.PublishToSpectators(id, delayed.event)
+			released++
+		}
+		b.pending = b.pending[released:]
+
+		if battle := gs.battles[id]; battle == nil || (battle.IsOver() && len(b.pending) == 0) {
+			delete(gs.broadcasts, id)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+func TestSpectatorDelay(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+
+	if _, err := gs.Spectate("carol", battle.ID); !errors.Is(err, ErrBattleNotPublic) {
+		t.Errorf("Expected ErrBattleNotPublic for a private battle, got %v", err)
+	}
+	if _, err := gs.SetBattleBroadcast("carol", battle.ID, true, time.Minute); !errors.Is(err, ErrNotBattleParticipant) {
+		t.Errorf("Expected ErrNotBattleParticipant, got %v", err)
+	}
+	if _, err := gs.SetBattleBroadcast("alice", battle.ID, true, time.Hour); !errors.Is(err, ErrInvalidSpectatorDelay) {
+		t.Errorf("Expected ErrInvalidSpectatorDelay, got %v", err)
+	}
+	if _, err := gs.SetBattleBroadcast("alice", battle.ID, true, 30*time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !battle.Public || battle.SpectatorDelay != 30*time.Second {
+		t.Errorf("Expected a public battle with a 30s delay, got %v and %v", battle.Public, battle.SpectatorDelay)
+	}
+
+	sub, err := gs.Spectate("carol", battle.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	snapshot := <-sub.Events
+	if snapshot.Type != EventSpectating {
+		t.Errorf("Expected %s event, got %s", EventSpectating, snapshot.Type)
+	}
+
+	if _, err := gs.SubmitBattleAction("alice", battle.ID, 0, "Wrench Strike", "slow"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	gs.releaseBroadcasts(time.Now())
+	if len(sub.Events) != 0 {
+		t.Errorf("Expected the attack to be held back, got %d events", len(sub.Events))
+	}
+	if listed := gs.ListPublicBattles(); len(listed) != 1 || listed[0].Battle.Participants[1].Health != 100 {
+		t.Errorf("Expected the listing to show the battle before the attack, got %+v", listed)
+	}
+
+	gs.releaseBroadcasts(time.Now().Add(31 * time.Second))
+	if event := <-sub.Events; event.Type != EventDamage {
+		t.Errorf("Expected %s event, got %s", EventDamage, event.Type)
+	}
+	listed := gs.ListPublicBattles()
+	if len(listed) != 1 || listed[0].Battle.Participants[1].Health != gs.players["slow"].Health {
+		t.Errorf("Expected the listing to show the battle after the attack, got %+v", listed)
+	}
+	if listed[0].Spectators != 1 || listed[0].Delay != 30 {
+		t.Errorf("Expected 1 spectator and a 30s delay, got %d and %d", listed[0].Spectators, listed[0].Delay)
+	}
+}
+
+func TestBattleViewHidesResources(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+
+	data, err := json.Marshal(newBattleView(battle))
+	if err != nil {
+		t.Fatalf("Failed to encode view: %v", err)
+	}
+	for _, hidden := range []string{"steam", "cooldown", "abilities", "Wrench Strike", "loot"} {
+		if strings.Contains(strings.ToLower(string(data)), strings.ToLower(hidden)) {
+			t.Errorf("Expected the spectator view to hide %s, got %s", hidden, data)
+		}
+	}
+}
+
+func TestBroadcastCloses(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+	gs.SetBattleBroadcast("alice", battle.ID, true, 0)
+
+	battle.Bracket = "1v1"
+	if _, err := gs.SetBattleBroadcast("bob", battle.ID, false, 0); !errors.Is(err, ErrRankedBattlePublic) {
+		t.Errorf("Expected ErrRankedBattlePublic, got %v", err)
+	}
+	battle.Bracket = ""
+	if _, err := gs.SetBattleBroadcast("bob", battle.ID, false, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(gs.ListPublicBattles()) != 0 {
+		t.Error("Expected a closed battle not to be listed")
+	}
+
+	gs.SetBattleBroadcast("alice", battle.ID, true, 0)
+	sub, _ := gs.Spectate("carol", battle.ID)
+	<-sub.Events
+	gs.ForfeitBattle("bob", battle.ID)
+	gs.releaseBroadcasts(time.Now())
+
+	if event := <-sub.Events; event.Type != EventCombatEnded {
+		t.Errorf("Expected %s event, got %s", EventCombatEnded, event.Type)
+	}
+	if _, exists := gs.broadcasts[battle.ID]; exists {
+		t.Error("Expected the broadcast to close once the battle ended")
+	}
+	if len(gs.ListPublicBattles()) != 0 {
+		t.Error("Expected a finished battle not to be listed")
+	}
+}
+
+func TestSpectatorCannotAct(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+	gs.SetBattleBroadcast("alice", battle.ID, true, 0)
+
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "username", "alice")))
+		})
+	})
+	NewHandler(gs).RegisterRoutes(r)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/battles/" + battle.ID + "/spectate"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	var event struct {
+		Type EventType `json:"type"`
+	}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read view: %v", err)
+	}
+	if event.Type != EventSpectating {
+		t.Errorf("Expected %s event, got %s", EventSpectating, event.Type)
+	}
+
+	// Even the account of the active character cannot act as a spectator
+	if err := conn.WriteJSON(Command{Type: CommandBattleAction, BattleID: battle.ID, Ability: "Wrench Strike", TargetID: "slow"}); err != nil {
+		t.Fatalf("Failed to send command: %v", err)
+	}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read error: %v", err)
+	}
+	if event.Type != EventError {
+		t.Errorf("Expected %s event, got %s", EventError, event.Type)
+	}
+	if current, _ := gs.GetBattle("alice", battle.ID); current.Version != 0 {
+		t.Errorf("Expected the battle to be untouched, got version %d", current.Version)
+	}
+}
+
+func TestSpectatorRoutes(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+
+	r := mux.NewRouter()
+	NewHandler(gs).RegisterRoutes(r)
+
+	serve := func(method, path, body, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), "username", username))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	broadcast := "/api/battles/" + battle.ID + "/broadcast"
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		username string
+		status   int
+	}{
+		{"spectate private", "GET", "/api/battles/" + battle.ID + "/spectate", "", "carol", http.StatusForbidden},
+		{"spectate missing", "GET", "/api/battles/missing/spectate", "", "carol", http.StatusNotFound},
+		{"open as outsider", "PUT", broadcast, `{"public":true}`, "carol", http.StatusForbidden},
+		{"open with bad delay", "PUT", broadcast, `{"public":true,"delay":-5}`, "alice", http.StatusBadRequest},
+		{"open", "PUT", broadcast, `{"public":true,"delay":20}`, "alice", http.StatusOK},
+		{"list", "GET", "/api/battles/public", "", "carol", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.path, tt.body, tt.username)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := serve("GET", "/api/battles/public", "", "carol")
+	var response PublicBattlesResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Battles) != 1 || response.Battles[0].Battle.BattleID != battle.ID || response.Battles[0].Delay != 20 {
+		t.Errorf("Expected the battle listed with a 20s delay, got %+v", response.Battles)
+	}
+}
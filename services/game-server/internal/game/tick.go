@@ -0,0 +1,153 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// TickFunc performs the work of a single server tick
+type TickFunc func(tick uint64, now time.Time)
+
+// TickMetrics holds timing statistics for the tick loop
+type TickMetrics struct {
+	Ticks         uint64
+	Overruns      uint64
+	LastDuration  time.Duration
+	MaxDuration   time.Duration
+	TotalDuration time.Duration
+}
+
+// AverageDuration returns the average time spent per tick
+func (m TickMetrics) AverageDuration() time.Duration {
+	if m.Ticks == 0 {
+		return 0
+	}
+	return m.TotalDuration / time.Duration(m.Ticks)
+}
+
+// TickScheduler runs a TickFunc at a fixed rate
+type TickScheduler struct {
+	interval time.Duration
+	fn       TickFunc
+	tick     uint64
+	epoch    time.Time
+	metrics  TickMetrics
+	running  bool
+	stop     chan struct{}
+	done     chan struct{}
+	mutex    sync.Mutex
+}
+
+// NewTickScheduler creates a scheduler that calls fn tickRate times per second
+func NewTickScheduler(tickRate int, fn TickFunc) *TickScheduler {
+	if tickRate <= 0 {
+		tickRate = 1
+	}
+	return &TickScheduler{
+		interval: time.Second / time.Duration(tickRate),
+		fn:       fn,
+		epoch:    time.Now(),
+	}
+}
+
+// Interval returns the time budget of a single tick
+func (ts *TickScheduler) Interval() time.Duration {
+	return ts.interval
+}
+
+// Start begins running ticks in the background
+func (ts *TickScheduler) Start() {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if ts.running {
+		return
+	}
+	ts.running = true
+	ts.stop = make(chan struct{})
+	ts.done = make(chan struct{})
+
+	go ts.run(ts.stop, ts.done)
+}
+
+// Stop halts the background loop and waits for the current tick to finish
+func (ts *TickScheduler) Stop() {
+	ts.mutex.Lock()
+	if !ts.running {
+		ts.mutex.Unlock()
+		return
+	}
+	ts.running = false
+	close(ts.stop)
+	done := ts.done
+	ts.mutex.Unlock()
+
+	<-done
+}
+
+// Step runs a single tick synchronously. The tick time advances by exactly one
+// interval per step, so a sequence of steps is deterministic. Step cannot be
+// used while the background loop is running.
+func (ts *TickScheduler) Step() error {
+	ts.mutex.Lock()
+	if ts.running {
+		ts.mutex.Unlock()
+		return fmt.Errorf("tick scheduler is running")
+	}
+	ts.mutex.Unlock()
+
+	now := ts.epoch.Add(time.Duration(ts.tick+1) * ts.interval)
+	ts.runTick(now)
+	return nil
+}
+
+// Metrics returns a snapshot of the tick timing statistics
+func (ts *TickScheduler) Metrics() TickMetrics {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	return ts.metrics
+}
+
+// run is the background tick loop
+func (ts *TickScheduler) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(ts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			ts.runTick(now)
+		}
+	}
+}
+
+// runTick executes one tick and records its duration
+func (ts *TickScheduler) runTick(now time.Time) {
+	ts.tick++
+	start := time.Now()
+	ts.fn(ts.tick, now)
+	elapsed := time.Since(start)
+
+	ts.mutex.Lock()
+	ts.metrics.Ticks++
+	ts.metrics.LastDuration = elapsed
+	ts.metrics.TotalDuration += elapsed
+	if elapsed > ts.metrics.MaxDuration {
+		ts.metrics.MaxDuration = elapsed
+	}
+	overrun := elapsed > ts.interval
+	if overrun {
+		ts.metrics.Overruns++
+	}
+	ts.mutex.Unlock()
+
+	if overrun {
+		log.Printf("Tick %d overran its budget: took %v, budget %v", ts.tick, elapsed, ts.interval)
+	}
+}
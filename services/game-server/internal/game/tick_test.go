@@ -0,0 +1,209 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/matchmaking"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+	"github.com/redfoxius/roleplay/services/game-server/internal/world"
+)
+
+// newTestServer creates a game server without a Redis repository
+func newTestServer() *GameServer {
+	return &GameServer{
+		players:     make(map[string]*character.Character),
+		battles:     make(map[string]*combat.Battle),
+		mobs:        make(map[string]*mob.Mob),
+		mobAI:       make(map[string]*mob.AIBehavior),
+		effects:     make(map[string]*combat.EffectManager),
+		combatStats: make(map[string]*combat.CombatStats),
+		worldMap:    world.NewWorldMap(20, 20),
+		spawner:     world.NewWorldSpawner(),
+		events:      NewEventHub(),
+		names:       NewNameRegistry(newMemoryNameIndex()),
+
+		removedCharacters: make(map[string]*character.Character),
+		finishedBattles:   make(map[string]time.Time),
+		broadcasts:        make(map[string]*broadcast),
+		challenges:        make(map[string]*Challenge),
+
+		queues:  newQueues(),
+		ratings: newRatings(),
+		season:  matchmaking.NewSeason(1, time.Now(), seasonLength),
+
+		dirtyCharacters:  make(map[string]bool),
+		purgedCharacters: make(map[string]bool),
+		dirtyMobs:        make(map[string]bool),
+		deletedMobs:      make(map[string]bool),
+		dirtyBattles:     make(map[string]bool),
+		dirtyStats:       make(map[string]bool),
+		dirtyRatings:     make(map[ratingKey]bool),
+	}
+}
+
+func TestTickSchedulerStep(t *testing.T) {
+	var ticks []uint64
+	var times []time.Time
+	ts := NewTickScheduler(10, func(tick uint64, now time.Time) {
+		ticks = append(ticks, tick)
+		times = append(times, now)
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := ts.Step(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if len(ticks) != 3 {
+		t.Fatalf("Expected 3 ticks, got %d", len(ticks))
+	}
+	for i, tick := range ticks {
+		if tick != uint64(i+1) {
+			t.Errorf("Expected tick %d, got %d", i+1, tick)
+		}
+	}
+	if times[1].Sub(times[0]) != 100*time.Millisecond {
+		t.Errorf("Expected ticks 100ms apart, got %v", times[1].Sub(times[0]))
+	}
+	if ts.Metrics().Ticks != 3 {
+		t.Errorf("Expected 3 ticks in metrics, got %d", ts.Metrics().Ticks)
+	}
+}
+
+func TestTickSchedulerOverrun(t *testing.T) {
+	ts := NewTickScheduler(1000, func(tick uint64, now time.Time) {
+		time.Sleep(5 * time.Millisecond)
+	})
+
+	if err := ts.Step(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	metrics := ts.Metrics()
+	if metrics.Overruns != 1 {
+		t.Errorf("Expected 1 overrun, got %d", metrics.Overruns)
+	}
+	if metrics.MaxDuration < 5*time.Millisecond {
+		t.Errorf("Expected max duration of at least 5ms, got %v", metrics.MaxDuration)
+	}
+}
+
+func TestTickSchedulerStartStop(t *testing.T) {
+	ticked := make(chan struct{}, 1)
+	ts := NewTickScheduler(100, func(tick uint64, now time.Time) {
+		select {
+		case ticked <- struct{}{}:
+		default:
+		}
+	})
+
+	ts.Start()
+	if err := ts.Step(); err == nil {
+		t.Error("Expected error when stepping a running scheduler")
+	}
+
+	select {
+	case <-ticked:
+	case <-time.After(time.Second):
+		t.Error("Expected the background loop to tick")
+	}
+	ts.Stop()
+
+	if err := ts.Step(); err != nil {
+		t.Errorf("Unexpected error after stop: %v", err)
+	}
+}
+
+func TestServerTickRegeneratesResources(t *testing.T) {
+	gs := newTestServer()
+	char := &character.Character{
+		ID:                "player1",
+		Name:              "Player 1",
+		MovementPoints:    0,
+		MaxMovementPoints: 100,
+		SteamPower:        0,
+		MaxSteamPower:     50,
+		Position:          common.Coordinates{X: -1, Y: -1}, // No location, defaults to plains
+	}
+	gs.players[char.ID] = char
+
+	ts := NewTickScheduler(10, gs.Tick)
+
+	// The first tick regenerates immediately
+	ts.Step()
+	if char.MovementPoints != movementPointsRegen {
+		t.Errorf("Expected movement points %d, got %d", movementPointsRegen, char.MovementPoints)
+	}
+	if char.SteamPower != steamPowerRegen {
+		t.Errorf("Expected steam power %d, got %d", steamPowerRegen, char.SteamPower)
+	}
+
+	// No regeneration until a full second has passed
+	for i := 0; i < 9; i++ {
+		ts.Step()
+	}
+	if char.MovementPoints != movementPointsRegen {
+		t.Errorf("Expected movement points %d, got %d", movementPointsRegen, char.MovementPoints)
+	}
+
+	ts.Step()
+	if char.MovementPoints != 2*movementPointsRegen {
+		t.Errorf("Expected movement points %d, got %d", 2*movementPointsRegen, char.MovementPoints)
+	}
+}
+
+func TestServerTickMobAI(t *testing.T) {
+	gs := newTestServer()
+	char := &character.Character{
+		ID:       "player1",
+		Name:     "Player 1",
+		Position: common.Coordinates{X: 5, Y: 5},
+	}
+	gs.players[char.ID] = char
+
+	m := mob.NewMob("Steam Golem", mob.Mechanical, 1)
+	m.MoveTo(8, 5)
+	gs.mobs[m.ID] = m
+
+	ts := NewTickScheduler(1, gs.Tick)
+	ts.Step()
+
+	if m.Position != (common.Coordinates{X: 7, Y: 5}) {
+		t.Errorf("Expected mob to step toward player to (7,5), got %v", m.Position)
+	}
+}
+
+func TestServerTickExpiresEffects(t *testing.T) {
+	gs := newTestServer()
+	gs.ApplyEffect("player1", *combat.WeaknessEffect)
+
+	ts := NewTickScheduler(1, gs.Tick)
+	ts.Step()
+	if len(gs.GetEffects("player1")) != 1 {
+		t.Errorf("Expected 1 effect after one turn, got %d", len(gs.GetEffects("player1")))
+	}
+
+	ts.Step()
+	if len(gs.GetEffects("player1")) != 0 {
+		t.Errorf("Expected effect to expire, got %d", len(gs.GetEffects("player1")))
+	}
+}
+
+func TestServerTickSkipsSteamInBattle(t *testing.T) {
+	gs := newBattleTestServer()
+	gs.players["fast"].SteamPower = 10
+	gs.players["slow"].SteamPower = 10
+	startDuel(t, gs)
+
+	gs.regenerateResources()
+
+	// Steam power in battle only regenerates per turn
+	if gs.players["fast"].SteamPower != 10 {
+		t.Errorf("Expected steam power 10, got %d", gs.players["fast"].SteamPower)
+	}
+}
@@ -0,0 +1,175 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is the time allowed to write a message to the client
+	writeWait = 10 * time.Second
+	// pongWait is the time allowed to read the next pong from the client
+	pongWait = 60 * time.Second
+	// pingPeriod is how often pings are sent; it must be less than pongWait
+	pingPeriod = pongWait * 9 / 10
+	// maxCommandSize is the largest command a client may send
+	maxCommandSize = 4096
+)
+
+// Client command types
+const (
+	CommandMove         = "move"
+	CommandBattleAction = "battle_action"
+	CommandMobAttack    = "mob_attack"
+)
+
+// Command is a message sent by a client over the WebSocket
+type Command struct {
+	Type     string `json:"type"`
+	X        int    `json:"x,omitempty"`
+	Y        int    `json:"y,omitempty"`
+	BattleID string `json:"battle_id,omitempty"`
+	Version  int    `json:"version"`
+	MobID    string `json:"mob_id,omitempty"`
+	Ability  string `json:"ability,omitempty"`
+	TargetID string `json:"target_id,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// handleWebSocket streams events for a character and accepts its commands
+func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	characterID := r.URL.Query().Get("character_id")
+	if characterID == "" {
+		http.Error(w, "character_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.server.GetCharacter(username(r), characterID); err != nil {
+		writeCharacterResponse(w, nil, err)
+		return
+	}
+
+	snapshot, err := h.server.Snapshot(characterID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	sub := h.server.Events().Subscribe(characterID)
+	h.server.Events().Send(sub, Event{Type: EventSnapshot, Data: snapshot})
+
+	go h.writePump(conn, sub)
+	h.readPump(conn, sub, username(r))
+}
+
+// handleSpectate streams the events of a public battle to a spectator, held
+// back by the battle's spectator delay
+func (h *Handler) handleSpectate(w http.ResponseWriter, r *http.Request) {
+	sub, err := h.server.Spectate(username(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeBattleResponse(w, nil, err)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.server.Events().Unsubscribe(sub)
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	go h.writePump(conn, sub)
+	h.readPump(conn, sub, username(r))
+}
+
+// readPump reads an account's commands until the connection closes
+func (h *Handler) readPump(conn *websocket.Conn, sub *Subscriber, owner string) {
+	defer func() {
+		h.server.Events().Unsubscribe(sub)
+		conn.Close()
+	}()
+
+	conn.SetReadLimit(maxCommandSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var cmd Command
+		if err := conn.ReadJSON(&cmd); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("WebSocket read failed for %s: %v", sub.CharacterID, err)
+			}
+			return
+		}
+
+		// Spectators only watch
+		err := ErrSpectatorCannotAct
+		if sub.BattleID == "" {
+			err = h.handleCommand(owner, sub.CharacterID, cmd)
+		}
+		if err != nil {
+			h.server.Events().Send(sub, Event{Type: EventError, Data: ErrorEvent{Message: err.Error()}})
+		}
+	}
+}
+
+// writePump delivers subscribed events and keeps the connection alive
+func (h *Handler) writePump(conn *websocket.Conn, sub *Subscriber) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case event, ok := <-sub.Events:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleCommand applies a client command on behalf of an account's character
+func (h *Handler) handleCommand(owner, characterID string, cmd Command) error {
+	switch cmd.Type {
+	case CommandMove:
+		return h.server.MoveCharacter(characterID, cmd.X, cmd.Y)
+	case CommandBattleAction:
+		_, err := h.server.SubmitBattleAction(owner, cmd.BattleID, cmd.Version, cmd.Ability, cmd.TargetID)
+		return err
+	case CommandMobAttack:
+		_, err := h.server.AttackMob(owner, characterID, cmd.MobID, cmd.Ability)
+		return err
+	default:
+		return fmt.Errorf("unknown command type: %s", cmd.Type)
+	}
+}
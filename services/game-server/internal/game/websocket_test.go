@@ -0,0 +1,149 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+)
+
+func TestPublishNearFiltersByInterest(t *testing.T) {
+	gs := newTestServer()
+	near := &character.Character{ID: "near", Position: common.Coordinates{X: 0, Y: 0}}
+	far := &character.Character{ID: "far", Position: common.Coordinates{X: 100, Y: 100}}
+	gs.players[near.ID] = near
+	gs.players[far.ID] = far
+
+	nearSub := gs.events.Subscribe(near.ID)
+	farSub := gs.events.Subscribe(far.ID)
+
+	gs.publishNear(EventMobMoved, MovedEvent{ID: "mob1"}, common.Coordinates{X: 3, Y: 4})
+
+	if len(nearSub.Events) != 1 {
+		t.Errorf("Expected 1 event for nearby character, got %d", len(nearSub.Events))
+	}
+	if len(farSub.Events) != 0 {
+		t.Errorf("Expected 0 events for distant character, got %d", len(farSub.Events))
+	}
+}
+
+func TestPublishToCombatMembersOnly(t *testing.T) {
+	gs := newTestServer()
+	member := gs.events.Subscribe("member")
+	outsider := gs.events.Subscribe("outsider")
+
+	gs.publishTo(EventTurnChanged, TurnChangedEvent{BattleID: "battle1"}, "member")
+
+	if len(member.Events) != 1 {
+		t.Errorf("Expected 1 event for battle member, got %d", len(member.Events))
+	}
+	if len(outsider.Events) != 0 {
+		t.Errorf("Expected 0 events for outsider, got %d", len(outsider.Events))
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	hub := NewEventHub()
+	sub := hub.Subscribe("player1")
+	hub.Unsubscribe(sub)
+
+	if _, ok := <-sub.Events; ok {
+		t.Error("Expected events channel to be closed")
+	}
+
+	// Publishing after unsubscribe must not panic
+	hub.Publish(Event{Type: EventDamage}, func(string) bool { return true })
+}
+
+func TestWebSocketSnapshotAndCommands(t *testing.T) {
+	gs := newTestServer()
+	char := &character.Character{ID: "player1", Owner: "tester", Name: "Player 1", Position: common.Coordinates{X: 5, Y: 5}}
+	gs.players[char.ID] = char
+
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "username", "tester")))
+		})
+	})
+	NewHandler(gs).RegisterRoutes(r)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/ws?character_id=player1"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	var event struct {
+		Type EventType `json:"type"`
+	}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if event.Type != EventSnapshot {
+		t.Errorf("Expected %s event, got %s", EventSnapshot, event.Type)
+	}
+
+	if err := conn.WriteJSON(Command{Type: "dance"}); err != nil {
+		t.Fatalf("Failed to send command: %v", err)
+	}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read error: %v", err)
+	}
+	if event.Type != EventError {
+		t.Errorf("Expected %s event, got %s", EventError, event.Type)
+	}
+}
+
+func TestWebSocketUnknownCharacter(t *testing.T) {
+	gs := newTestServer()
+	r := mux.NewRouter()
+	NewHandler(gs).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/api/ws?character_id=missing", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "username", "tester"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleCommandChecks(t *testing.T) {
+	gs := newBattleTestServer()
+	battle := startDuel(t, gs)
+	h := NewHandler(gs)
+
+	if err := h.handleCommand("alice", "fast", Command{Type: CommandMove, X: 1, Y: 1}); !errors.Is(err, ErrCharacterInCombat) {
+		t.Errorf("Expected ErrCharacterInCombat moving in a battle, got %v", err)
+	}
+	idle := newTestFighter("idle", 10)
+	idle.Owner = "alice"
+	gs.players[idle.ID] = idle
+	if err := h.handleCommand("alice", idle.ID, Command{Type: CommandMove, X: 5, Y: 5}); !errors.Is(err, ErrMoveTooFar) {
+		t.Errorf("Expected ErrMoveTooFar, got %v", err)
+	}
+
+	action := Command{Type: CommandBattleAction, BattleID: battle.ID, Version: battle.Version + 1, Ability: "Wrench Strike", TargetID: "slow"}
+	if err := h.handleCommand("alice", "fast", action); !errors.Is(err, ErrBattleConflict) {
+		t.Errorf("Expected ErrBattleConflict for a stale version, got %v", err)
+	}
+	action.Version = battle.Version
+	if err := h.handleCommand("alice", "fast", action); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
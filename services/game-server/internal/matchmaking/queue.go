@@ -0,0 +1,191 @@
+package matchmaking
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Search widening: the longer a ticket waits, the further apart in rating and
+// level its opponents and teammates may be
+const (
+	widenInterval       = 15 * time.Second
+	initialRatingWindow = 100.0
+	ratingWindowStep    = 50.0 // added to the rating window every widenInterval
+	maxRatingWindow     = 600.0
+	initialLevelBand    = 2
+	levelBandStep       = 1 // added to the level band every widenInterval
+	maxLevelBand        = 10
+)
+
+// Ticket is a character waiting in a queue
+type Ticket struct {
+	CharacterID string
+	Owner       string
+	Bracket     Bracket
+	Level       int
+	Rating      float64
+	QueuedAt    time.Time
+}
+
+// Window returns how far in rating and level the ticket accepts other
+// characters after waiting until now
+func (t *Ticket) Window(now time.Time) (float64, int) {
+	steps := int(now.Sub(t.QueuedAt) / widenInterval)
+	rating := math.Min(initialRatingWindow+ratingWindowStep*float64(steps), maxRatingWindow)
+	levels := min(initialLevelBand+levelBandStep*steps, maxLevelBand)
+	return rating, levels
+}
+
+// accepts reports whether other is within the ticket's search window. A
+// character is never matched with another of the same account.
+func (t *Ticket) accepts(other *Ticket, now time.Time) bool {
+	rating, levels := t.Window(now)
+	return t.Owner != other.Owner &&
+		math.Abs(t.Rating-other.Rating) <= rating &&
+		abs(t.Level-other.Level) <= levels
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Match is a ranked battle the queue put together: two teams of tickets
+type Match struct {
+	Bracket Bracket
+	Teams   [2][]*Ticket
+}
+
+// Queue holds the tickets of one bracket in the order they joined
+type Queue struct {
+	bracket Bracket
+	tickets []*Ticket
+}
+
+// NewQueue creates an empty queue for a bracket
+func NewQueue(bracket Bracket) *Queue {
+	return &Queue{bracket: bracket}
+}
+
+// Add puts a ticket at the back of the queue
+func (q *Queue) Add(ticket *Ticket) {
+	q.tickets = append(q.tickets, ticket)
+}
+
+// Remove takes a character's ticket out of the queue and reports whether it
+// was queued
+func (q *Queue) Remove(characterID string) bool {
+	for i, t := range q.tickets {
+		if t.CharacterID == characterID {
+			q.tickets = append(q.tickets[:i], q.tickets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns a character's ticket, or nil if it is not queued
+func (q *Queue) Get(characterID string) *Ticket {
+	for _, t := range q.tickets {
+		if t.CharacterID == characterID {
+			return t
+		}
+	}
+	return nil
+}
+
+// Tickets returns the queued tickets, longest waiting first
+func (q *Queue) Tickets() []*Ticket {
+	return append([]*Ticket(nil), q.tickets...)
+}
+
+// Len returns the number of queued tickets
+func (q *Queue) Len() int {
+	return len(q.tickets)
+}
+
+// Match takes every match it can put together out of the queue. Tickets that
+// waited longest are served first; each is grouped with the closest rated
+// tickets that it and every other member of the group accept.
+func (q *Queue) Match(now time.Time) []*Match {
+	size := q.bracket.TeamSize() * 2
+	if size == 0 {
+		return nil
+	}
+
+	var matches []*Match
+	matched := make(map[*Ticket]bool)
+	for _, anchor := range q.tickets {
+		if matched[anchor] {
+			continue
+		}
+		candidates := make([]*Ticket, 0, len(q.tickets))
+		for _, t := range q.tickets {
+			if t != anchor && !matched[t] {
+				candidates = append(candidates, t)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return math.Abs(candidates[i].Rating-anchor.Rating) < math.Abs(candidates[j].Rating-anchor.Rating)
+		})
+
+		group := []*Ticket{anchor}
+		for _, c := range candidates {
+			if len(group) == size {
+				break
+			}
+			if acceptsAll(c, group, now) {
+				group = append(group, c)
+			}
+		}
+		if len(group) < size {
+			continue
+		}
+		for _, t := range group {
+			matched[t] = true
+		}
+		matches = append(matches, &Match{Bracket: q.bracket, Teams: splitTeams(group)})
+	}
+
+	remaining := q.tickets[:0]
+	for _, t := range q.tickets {
+		if !matched[t] {
+			remaining = append(remaining, t)
+		}
+	}
+	q.tickets = remaining
+	return matches
+}
+
+// acceptsAll reports whether a ticket and every member of a group accept each other
+func acceptsAll(t *Ticket, group []*Ticket, now time.Time) bool {
+	for _, member := range group {
+		if !t.accepts(member, now) || !member.accepts(t, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitTeams divides a group into two teams of even strength by handing out
+// its members best rated first in the order 1, 2, 2, 1, 1, 2, ...
+func splitTeams(group []*Ticket) [2][]*Ticket {
+	sorted := append([]*Ticket(nil), group...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Rating > sorted[j].Rating
+	})
+
+	var teams [2][]*Ticket
+	for i, t := range sorted {
+		team := 0
+		if i%4 == 1 || i%4 == 2 {
+			team = 1
+		}
+		teams[team] = append(teams[team], t)
+	}
+	return teams
+}
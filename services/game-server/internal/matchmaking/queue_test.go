@@ -0,0 +1,106 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+)
+
+func newTicket(id string, level int, rating float64, queuedAt time.Time) *Ticket {
+	return &Ticket{CharacterID: id, Owner: id, Level: level, Rating: rating, QueuedAt: queuedAt}
+}
+
+func TestTicketWindow(t *testing.T) {
+	now := time.Now()
+	ticket := newTicket("a", 5, 1500, now)
+
+	rating, levels := ticket.Window(now)
+	if rating != initialRatingWindow || levels != initialLevelBand {
+		t.Errorf("Expected the initial window, got %v and %d", rating, levels)
+	}
+	rating, levels = ticket.Window(now.Add(2 * widenInterval))
+	if rating != initialRatingWindow+2*ratingWindowStep || levels != initialLevelBand+2*levelBandStep {
+		t.Errorf("Expected the window to widen twice, got %v and %d", rating, levels)
+	}
+	rating, levels = ticket.Window(now.Add(time.Hour))
+	if rating != maxRatingWindow || levels != maxLevelBand {
+		t.Errorf("Expected the window to stop widening, got %v and %d", rating, levels)
+	}
+}
+
+func TestQueueMatch1v1(t *testing.T) {
+	now := time.Now()
+	q := NewQueue(Bracket1v1)
+	q.Add(newTicket("a", 5, 1500, now))
+	q.Add(newTicket("far", 5, 1800, now))
+	q.Add(newTicket("b", 6, 1550, now))
+	q.Add(newTicket("low", 1, 1500, now))
+
+	matches := q.Match(now)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(matches))
+	}
+	first, second := matches[0].Teams[0][0].CharacterID, matches[0].Teams[1][0].CharacterID
+	if first != "b" || second != "a" {
+		t.Errorf("Expected b against a, got %s against %s", first, second)
+	}
+	if q.Len() != 2 || q.Get("far") == nil || q.Get("low") == nil {
+		t.Errorf("Expected far and low to keep waiting, got %d tickets", q.Len())
+	}
+
+	// Waiting widens the search until the rating and level gaps are acceptable
+	if matches := q.Match(now.Add(2 * widenInterval)); len(matches) != 0 {
+		t.Errorf("Expected no match before the search widens, got %d", len(matches))
+	}
+	if matches := q.Match(now.Add(4 * widenInterval)); len(matches) != 1 {
+		t.Errorf("Expected far and low to be matched eventually, got %d matches", len(matches))
+	}
+}
+
+func TestQueueMatchSameOwner(t *testing.T) {
+	now := time.Now()
+	q := NewQueue(Bracket1v1)
+	main, alt := newTicket("main", 5, 1500, now), newTicket("alt", 5, 1500, now)
+	alt.Owner = main.Owner
+	q.Add(main)
+	q.Add(alt)
+
+	if matches := q.Match(now); len(matches) != 0 {
+		t.Errorf("Expected characters of one account not to be matched, got %d matches", len(matches))
+	}
+}
+
+func TestQueueMatchTeams(t *testing.T) {
+	now := time.Now()
+	q := NewQueue(Bracket2v2)
+	for i, rating := range []float64{1500, 1540, 1460, 1520} {
+		q.Add(newTicket(string(rune('a'+i)), 5, rating, now))
+	}
+
+	matches := q.Match(now)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(matches))
+	}
+	teams := matches[0].Teams
+	if len(teams[0]) != 2 || len(teams[1]) != 2 {
+		t.Fatalf("Expected two teams of 2, got %d and %d", len(teams[0]), len(teams[1]))
+	}
+	// Best and worst rated play the two in between
+	if teams[0][0].CharacterID != "b" || teams[0][1].CharacterID != "c" {
+		t.Errorf("Expected b and c to team up, got %s and %s", teams[0][0].CharacterID, teams[0][1].CharacterID)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Expected an empty queue, got %d tickets", q.Len())
+	}
+}
+
+func TestQueueRemove(t *testing.T) {
+	q := NewQueue(Bracket1v1)
+	q.Add(newTicket("a", 1, 1500, time.Now()))
+
+	if !q.Remove("a") || q.Remove("a") {
+		t.Errorf("Expected a to be removed once")
+	}
+	if q.Get("a") != nil {
+		t.Errorf("Expected a to be gone")
+	}
+}
@@ -0,0 +1,157 @@
+// Package matchmaking pairs characters queued for ranked battles by rating and
+// level, and rates the results with the Elo system.
+package matchmaking
+
+import (
+	"math"
+	"time"
+)
+
+// Bracket is a ranked format, named after how many characters fight on each side
+type Bracket string
+
+const (
+	Bracket1v1 Bracket = "1v1"
+	Bracket2v2 Bracket = "2v2"
+	Bracket3v3 Bracket = "3v3"
+)
+
+// brackets maps each bracket to its team size
+var brackets = map[Bracket]int{
+	Bracket1v1: 1,
+	Bracket2v2: 2,
+	Bracket3v3: 3,
+}
+
+// Brackets returns every ranked bracket, smallest teams first
+func Brackets() []Bracket {
+	return []Bracket{Bracket1v1, Bracket2v2, Bracket3v3}
+}
+
+// TeamSize returns how many characters fight on each side, or 0 for an
+// unknown bracket
+func (b Bracket) TeamSize() int {
+	return brackets[b]
+}
+
+// Valid reports whether the bracket exists
+func (b Bracket) Valid() bool {
+	return b.TeamSize() > 0
+}
+
+// Battle results, as Elo scores from one side's view
+const (
+	Win  = 1.0
+	Draw = 0.5
+	Loss = 0.0
+)
+
+// Rating tuning
+const (
+	DefaultRating    = 1500.0
+	provisionalGames = 20   // games during which ratings move faster
+	provisionalK     = 40.0 // K-factor of provisional ratings
+	establishedK     = 20.0
+	eloScale         = 400.0 // rating difference at which the favourite is expected to score 10:1
+)
+
+// Rating is a character's rating in one bracket and season
+type Rating struct {
+	Season int
+	Value  float64
+	Games  int
+	Wins   int
+	Losses int
+	Draws  int
+}
+
+// NewRating returns the rating a character starts a season with
+func NewRating(season int) *Rating {
+	return &Rating{Season: season, Value: DefaultRating}
+}
+
+// Expected returns the score a side rated rating is expected to make against
+// a side rated opponent, between 0 and 1
+func Expected(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/eloScale))
+}
+
+// kFactor returns how far a single result moves the rating
+func (r *Rating) kFactor() float64 {
+	if r.Games < provisionalGames {
+		return provisionalK
+	}
+	return establishedK
+}
+
+// update moves the rating by a result of a side rated team against a side
+// rated opponent
+func (r *Rating) update(team, opponent, score float64) {
+	r.Value += r.kFactor() * (score - Expected(team, opponent))
+	r.Games++
+	switch score {
+	case Win:
+		r.Wins++
+	case Loss:
+		r.Losses++
+	default:
+		r.Draws++
+	}
+}
+
+// ResetForSeason softly resets the rating for a new season: it keeps half of
+// its distance from the default rating and starts counting games anew
+func (r *Rating) ResetForSeason(season int) {
+	*r = Rating{Season: season, Value: DefaultRating + (r.Value-DefaultRating)/2}
+}
+
+// TeamRating returns the rating of a side, the average of its members' ratings
+func TeamRating(ratings []*Rating) float64 {
+	if len(ratings) == 0 {
+		return DefaultRating
+	}
+	total := 0.0
+	for _, r := range ratings {
+		total += r.Value
+	}
+	return total / float64(len(ratings))
+}
+
+// RateMatch updates the ratings of both sides of a ranked battle. score is
+// the first side's result: Win, Draw or Loss. Every member of a side gains or
+// loses as if their side's average rating had played the other side's.
+func RateMatch(first, second []*Rating, score float64) {
+	firstRating, secondRating := TeamRating(first), TeamRating(second)
+	for _, r := range first {
+		r.update(firstRating, secondRating, score)
+	}
+	for _, r := range second {
+		r.update(secondRating, firstRating, 1-score)
+	}
+}
+
+// Season is a ranked season. Ratings are softly reset and leaderboards start
+// empty when a new season begins.
+type Season struct {
+	Number    int
+	StartedAt time.Time
+	EndsAt    time.Time
+}
+
+// NewSeason returns a season that starts at start and lasts length
+func NewSeason(number int, start time.Time, length time.Duration) *Season {
+	return &Season{Number: number, StartedAt: start, EndsAt: start.Add(length)}
+}
+
+// Next returns the season following this one, of the same length
+func (s *Season) Next() *Season {
+	return NewSeason(s.Number+1, s.EndsAt, s.EndsAt.Sub(s.StartedAt))
+}
+
+// Standing is a character's place on a leaderboard
+type Standing struct {
+	Rank        int
+	CharacterID string
+	Name        string
+	Rating      float64
+}
@@ -0,0 +1,80 @@
+package matchmaking
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExpected(t *testing.T) {
+	if e := Expected(1500, 1500); e != 0.5 {
+		t.Errorf("Expected even odds between equal ratings, got %v", e)
+	}
+	if e := Expected(1900, 1500); math.Abs(e-10.0/11) > 1e-9 {
+		t.Errorf("Expected 10:1 odds at 400 points, got %v", e)
+	}
+	if e := Expected(1400, 1600) + Expected(1600, 1400); math.Abs(e-1) > 1e-9 {
+		t.Errorf("Expected both sides' odds to add up to 1, got %v", e)
+	}
+}
+
+func TestRateMatch(t *testing.T) {
+	winner, loser := NewRating(1), NewRating(1)
+	RateMatch([]*Rating{winner}, []*Rating{loser}, Win)
+
+	if winner.Value != DefaultRating+provisionalK/2 {
+		t.Errorf("Expected winner at %v, got %v", DefaultRating+provisionalK/2, winner.Value)
+	}
+	if loser.Value != DefaultRating-provisionalK/2 {
+		t.Errorf("Expected loser at %v, got %v", DefaultRating-provisionalK/2, loser.Value)
+	}
+	if winner.Games != 1 || winner.Wins != 1 || loser.Losses != 1 {
+		t.Errorf("Expected the result to be counted, got %+v and %+v", winner, loser)
+	}
+
+	// An upset moves ratings further than an expected result
+	favourite, underdog := &Rating{Value: 1800, Games: provisionalGames}, &Rating{Value: 1400, Games: provisionalGames}
+	RateMatch([]*Rating{underdog}, []*Rating{favourite}, Win)
+	if gain := underdog.Value - 1400; gain <= establishedK/2 {
+		t.Errorf("Expected an upset to gain more than %v, got %v", establishedK/2, gain)
+	}
+
+	// Teammates gain by their team's average, each at their own K-factor
+	a, b := &Rating{Value: 1600, Games: provisionalGames}, NewRating(1)
+	c, d := NewRating(1), NewRating(1)
+	RateMatch([]*Rating{a, b}, []*Rating{c, d}, Draw)
+	if a.Draws != 1 || d.Draws != 1 {
+		t.Errorf("Expected a draw to be counted, got %+v and %+v", a, d)
+	}
+	if a.Value >= 1600 || b.Value >= DefaultRating {
+		t.Errorf("Expected the stronger team to lose rating on a draw, got %v and %v", a.Value, b.Value)
+	}
+	if DefaultRating-b.Value <= 1600-a.Value {
+		t.Errorf("Expected the provisional rating to move further, got %v and %v", b.Value, a.Value)
+	}
+}
+
+func TestResetForSeason(t *testing.T) {
+	r := &Rating{Season: 1, Value: 1700, Games: 30, Wins: 20, Losses: 10}
+	r.ResetForSeason(2)
+
+	if r.Season != 2 || r.Value != 1600 {
+		t.Errorf("Expected season 2 at 1600, got season %d at %v", r.Season, r.Value)
+	}
+	if r.Games != 0 || r.Wins != 0 || r.Losses != 0 {
+		t.Errorf("Expected games to be counted anew, got %+v", r)
+	}
+}
+
+func TestSeasonNext(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	season := NewSeason(1, start, 28*24*time.Hour)
+	next := season.Next()
+
+	if next.Number != 2 || !next.StartedAt.Equal(season.EndsAt) {
+		t.Errorf("Expected season 2 to start when season 1 ends, got %+v", next)
+	}
+	if next.EndsAt.Sub(next.StartedAt) != 28*24*time.Hour {
+		t.Errorf("Expected seasons of equal length, got %v", next.EndsAt.Sub(next.StartedAt))
+	}
+}
@@ -22,6 +22,12 @@ func NewAuthMiddleware(authServiceURL string) *AuthMiddleware {
 func (m *AuthMiddleware) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
+		// Browsers cannot set headers on WebSocket handshakes, so allow the token in the query
+		if authHeader == "" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			if token := r.URL.Query().Get("access_token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
 			http.Error(w, "Authorization header required", http.StatusUnauthorized)
 			return
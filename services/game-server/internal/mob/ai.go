@@ -27,7 +27,17 @@ type AIBehavior struct {
 	PatrolPath         []string
 	CurrentPatrolIndex int
 	LastActionTime     time.Time
-	Cooldowns          map[string]time.Time
+	Cooldowns          map[string]int // Ability name -> turns until it can be used again
+}
+
+// BattleTarget describes an opponent a mob can attack in battle, or an ally,
+// the mob itself included, it can heal or buff
+type BattleTarget struct {
+	ID        string
+	Health    int
+	MaxHealth int
+	Distance  int // in battle grid tiles
+	Threat    int // threat the opponent has drawn from the mob
 }
 
 // NewAIBehavior creates a new AI behavior
@@ -37,7 +47,7 @@ func NewAIBehavior(mobType MobType) *AIBehavior {
 		AggroRange:     10,
 		FleeThreshold:  0.2, // 20% health
 		LastActionTime: time.Now(),
-		Cooldowns:      make(map[string]time.Time),
+		Cooldowns:      make(map[string]int),
 	}
 
 	// Set type-specific behavior
@@ -95,60 +105,114 @@ func (ai *AIBehavior) UpdateAI(m *Mob, nearbyPlayers []*character.Character) {
 	}
 }
 
-// ChooseAction selects the next action for the mob to take
-func (ai *AIBehavior) ChooseAction(m *Mob) *common.Ability {
-	if ai.State == Fleeing {
-		return nil // Mob will try to escape
+// ShouldFlee reports whether the mob is hurt badly enough to run away
+func (ai *AIBehavior) ShouldFlee(m *Mob) bool {
+	return float32(m.Health)/float32(m.MaxHealth) <= ai.FleeThreshold
+}
+
+// ChooseTarget picks the opponent to attack in battle: the one at the top of
+// the mob's threat table, or, between opponents with the same threat, the
+// most wounded and nearest one
+func (ai *AIBehavior) ChooseTarget(targets []BattleTarget) *BattleTarget {
+	var best *BattleTarget
+	var bestScore int
+	for i := range targets {
+		target := &targets[i]
+		missing := 100
+		if target.MaxHealth > 0 {
+			missing = 100 - target.Health*100/target.MaxHealth
+		}
+		score := missing/2 - target.Distance*5
+		if best == nil || target.Threat > best.Threat || (target.Threat == best.Threat && score > bestScore) {
+			best = target
+			bestScore = score
+		}
 	}
+	return best
+}
 
-	if ai.State == Aggressive && ai.Target != nil {
-		// Check cooldowns and choose best ability
-		var bestAbility *common.Ability
-		var bestScore float32
-
-		for i := range m.Abilities {
-			ability := &m.Abilities[i]
-
-			// Skip if on cooldown
-			if lastUse, exists := ai.Cooldowns[ability.Name]; exists {
-				if time.Since(lastUse).Seconds() < float64(ability.Cooldown) {
-					continue
-				}
-			}
-
-			// Calculate ability score based on current situation
-			score := ai.calculateAbilityScore(ability, m)
-			if score > bestScore {
-				bestScore = score
-				bestAbility = ability
-			}
+// ChooseAlly picks who the mob's healing and buffs go to: the most wounded of
+// its allies, the nearest between equally wounded ones
+func (ai *AIBehavior) ChooseAlly(allies []BattleTarget) *BattleTarget {
+	var best *BattleTarget
+	for i := range allies {
+		ally := &allies[i]
+		if best == nil || healthPercent(ally) < healthPercent(best) || (healthPercent(ally) == healthPercent(best) && ally.Distance < best.Distance) {
+			best = ally
 		}
+	}
+	return best
+}
+
+// Supports reports whether an ability is meant for the mob's own side
+func Supports(ability *common.Ability) bool {
+	return ability.Type == "healing" || ability.Type == "buff"
+}
+
+// ChooseAction selects the ability the mob uses in battle: on the target, or,
+// for healing and buffs, on the ally. Abilities that are cooling down, cost
+// more steam power than the mob has, cannot reach whom they are meant for or
+// only trigger as reactions are skipped; nil means the mob has nothing to use.
+func (ai *AIBehavior) ChooseAction(m *Mob, target, ally *BattleTarget) *common.Ability {
+	if ai.State == Fleeing || target == nil {
+		return nil
+	}
 
-		if bestAbility != nil {
-			ai.Cooldowns[bestAbility.Name] = time.Now()
-			return bestAbility
+	var bestAbility *common.Ability
+	var bestScore float32
+	for i := range m.Abilities {
+		ability := &m.Abilities[i]
+		if ai.Cooldowns[ability.Name] > 0 || ability.SteamCost > m.SteamPower || ability.Reaction != "" {
+			continue
+		}
+		recipient := target
+		if Supports(ability) {
+			recipient = ally
+		}
+		if recipient == nil || recipient.Distance > ability.Reach() {
+			continue
+		}
+
+		// Calculate ability score based on current situation
+		score := ai.calculateAbilityScore(ability, m, recipient)
+		if score > bestScore {
+			bestScore = score
+			bestAbility = ability
 		}
 	}
+	return bestAbility
+}
 
-	return nil
+// PreferredRange returns the distance the mob wants to fight from: the
+// longest range of the abilities it can use, or 1 to fight up close
+func (ai *AIBehavior) PreferredRange(m *Mob) int {
+	preferred := 1
+	for _, ability := range m.Abilities {
+		if ai.Cooldowns[ability.Name] == 0 && ability.SteamCost <= m.SteamPower && ability.Reaction == "" {
+			preferred = max(preferred, ability.Reach())
+		}
+	}
+	return preferred
 }
 
-// calculateAbilityScore calculates how good an ability is for the current situation
-func (ai *AIBehavior) calculateAbilityScore(ability *common.Ability, m *Mob) float32 {
+// calculateAbilityScore calculates how good an ability used on a recipient is
+// for the current situation
+func (ai *AIBehavior) calculateAbilityScore(ability *common.Ability, m *Mob, recipient *BattleTarget) float32 {
 	var score float32
 
 	// Base score on ability type
 	switch ability.Type {
-	case "damage":
-		score = float32(ability.Damage)
 	case "healing":
-		// Prioritize healing when health is low
-		healthPercent := float32(m.Health) / float32(m.MaxHealth)
-		score = float32(ability.Damage) * (1.0 - healthPercent)
+		// Prioritize healing when the recipient's health is low
+		score = float32(ability.Healing) * (1.0 - healthPercent(recipient))
+	// Buffs and debuffs are worth less than a solid hit or a badly needed heal
 	case "buff":
-		score = 50 // Base score for buffs
+		score = 25 // Base score for buffs
 	case "debuff":
-		score = 40 // Base score for debuffs
+		score = 20 // Base score for debuffs
+	default:
+		// Damage abilities, whatever their damage type, and untyped ones
+		score = float32(ability.Damage)
 	}
 
 	// Adjust score based on steam power cost
@@ -160,6 +224,14 @@ func (ai *AIBehavior) calculateAbilityScore(ability *common.Ability, m *Mob) flo
 	return score
 }
 
+// healthPercent returns the share of its maximum health a target has left
+func healthPercent(target *BattleTarget) float32 {
+	if target.MaxHealth <= 0 {
+		return 1
+	}
+	return float32(target.Health) / float32(target.MaxHealth)
+}
+
 // SetPatrolPath sets the patrol path for the mob
 func (ai *AIBehavior) SetPatrolPath(path []string) {
 	ai.PatrolPath = path
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
 	"github.com/redfoxius/roleplay/services/game-server/internal/common"
 )
 
@@ -71,7 +72,7 @@ func NewMob(name string, mobType MobType, level int) *Mob {
 			Vitality:     10 * level,
 		},
 		Abilities: make([]common.Ability, 0),
-		LootTable: common.LootTable{},
+		LootTable: GenerateLootTable(mobType, level),
 	}
 
 	// Add type-specific abilities
@@ -200,16 +201,50 @@ func GenerateMobName(mobType MobType) string {
 	return prefix + " " + suffix
 }
 
+// abilityMobTypes maps mob types to the types the ability tables are keyed by
+var abilityMobTypes = map[MobType]character.MobType{
+	Mechanical: character.Mechanical,
+	Biological: character.Biological,
+	Hybrid:     character.Hybrid,
+	Elemental:  character.Elemental,
+	Construct:  character.Construct,
+}
+
 // GetMobAbilities returns the abilities for a mob type and level
 func GetMobAbilities(mobType MobType, level int) []common.Ability {
-	// TODO: Implement mob abilities
-	return []common.Ability{}
+	abilities := []common.Ability{}
+	for _, ability := range character.GetMobAbilities(abilityMobTypes[mobType], level) {
+		abilities = append(abilities, ability.Common())
+	}
+	return abilities
 }
 
-// GenerateLootTable generates a loot table for a mob type and level
+// lootChances is the chance that an item of each rarity tier drops; it is
+// shared between the items of the tier
+var lootChances = map[string]float64{
+	"common":    0.7,
+	"uncommon":  0.4,
+	"rare":      0.2,
+	"epic":      0.05,
+	"legendary": 0.01,
+}
+
+// GenerateLootTable generates a loot table for a mob type and level from the
+// item tiers of its family
 func GenerateLootTable(mobType MobType, level int) common.LootTable {
-	// TODO: Implement loot table generation
-	return common.LootTable{}
+	tiers := character.GenerateLootTable(abilityMobTypes[mobType], level)
+	table := common.LootTable{}
+	for _, items := range [][]character.Item{tiers.Common, tiers.Uncommon, tiers.Rare, tiers.Epic, tiers.Legendary} {
+		for _, item := range items {
+			table.Items = append(table.Items, common.LootItem{
+				ID:       item.ID,
+				Name:     item.Name,
+				Quantity: 1,
+				Chance:   lootChances[item.Rarity] / float64(len(items)),
+			})
+		}
+	}
+	return table
 }
 
 // MoveTo moves the mob to a new location
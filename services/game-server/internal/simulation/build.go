@@ -0,0 +1,157 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// classes are the character classes a build can use
+var classes = []character.Class{
+	character.Engineer,
+	character.Alchemist,
+	character.Aeronaut,
+	character.ClockworkKnight,
+	character.SteamMage,
+}
+
+// mobTypes are the mob types a build can use
+var mobTypes = []mob.MobType{
+	mob.Mechanical,
+	mob.Biological,
+	mob.Hybrid,
+	mob.Elemental,
+	mob.Construct,
+}
+
+// Build is one side of a simulated battle: a character of a class and level
+// wearing equipment, or a mob of a type and level
+type Build struct {
+	Class     character.Class // empty for mobs
+	MobType   mob.MobType     // empty for characters
+	Level     int
+	Equipment []string // equipment IDs, for characters
+}
+
+// ParseBuild parses a build such as "Engineer:5", "SteamMage:3+steam_pistol"
+// or "mob:mechanical:5"
+func ParseBuild(spec string) (Build, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	if len(parts) == 3 && parts[0] == "mob" {
+		level, err := parseLevel(parts[2])
+		if err != nil {
+			return Build{}, fmt.Errorf("invalid build %q: %v", spec, err)
+		}
+		for _, t := range mobTypes {
+			if string(t) == parts[1] {
+				return Build{MobType: t, Level: level}, nil
+			}
+		}
+		return Build{}, fmt.Errorf("invalid build %q: unknown mob type %q", spec, parts[1])
+	}
+	if len(parts) != 2 {
+		return Build{}, fmt.Errorf("invalid build %q: expected class:level or mob:type:level", spec)
+	}
+
+	items := strings.Split(parts[1], "+")
+	level, err := parseLevel(items[0])
+	if err != nil {
+		return Build{}, fmt.Errorf("invalid build %q: %v", spec, err)
+	}
+	for _, class := range classes {
+		if string(class) == parts[0] {
+			return Build{Class: class, Level: level, Equipment: items[1:]}, nil
+		}
+	}
+	return Build{}, fmt.Errorf("invalid build %q: unknown class %q", spec, parts[0])
+}
+
+// ParseBuilds parses a comma separated list of builds
+func ParseBuilds(specs string) ([]Build, error) {
+	var builds []Build
+	for _, spec := range strings.Split(specs, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		build, err := ParseBuild(spec)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, build)
+	}
+	return builds, nil
+}
+
+// parseLevel parses a character or mob level
+func parseLevel(s string) (int, error) {
+	level, err := strconv.Atoi(s)
+	if err != nil || level < 1 {
+		return 0, fmt.Errorf("invalid level %q", s)
+	}
+	return level, nil
+}
+
+// String returns the build in the form ParseBuild reads
+func (b Build) String() string {
+	if b.MobType != "" {
+		return fmt.Sprintf("mob:%s:%d", b.MobType, b.Level)
+	}
+	spec := fmt.Sprintf("%s:%d", b.Class, b.Level)
+	for _, id := range b.Equipment {
+		spec += "+" + id
+	}
+	return spec
+}
+
+// LoadEquipment reads a JSON list of equipment that builds can wear besides
+// the common equipment
+func LoadEquipment(r io.Reader) (map[string]*character.Equipment, error) {
+	var list []*character.Equipment
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to read equipment: %v", err)
+	}
+	equipment := make(map[string]*character.Equipment)
+	for _, eq := range list {
+		if eq.ID == "" || eq.Slot == "" {
+			return nil, fmt.Errorf("equipment %q needs an ID and a slot", eq.Name)
+		}
+		equipment[eq.ID] = eq
+	}
+	return equipment, nil
+}
+
+// combatant creates the character or mob of a build the way the game does
+func (b Build) combatant(id string, equipment map[string]*character.Equipment) (combat.Combatant, error) {
+	if b.MobType != "" {
+		m := mob.NewMob(strings.ToUpper(string(b.MobType[:1]))+string(b.MobType[1:]), b.MobType, b.Level)
+		m.ID = id
+		m.Abilities = mob.GetMobAbilities(b.MobType, b.Level)
+		return combat.NewMobCombatant(m), nil
+	}
+
+	char := character.NewCharacter(string(b.Class), b.Class)
+	char.ID = id
+	char.ApplyClassBonuses()
+	for char.Level < b.Level {
+		char.LevelUp()
+	}
+	for _, itemID := range b.Equipment {
+		eq, exists := equipment[itemID]
+		if !exists {
+			if eq, exists = character.GetEquipment(itemID); !exists {
+				return nil, fmt.Errorf("unknown equipment %q", itemID)
+			}
+		}
+		char.AddToInventory(eq.Item())
+		if err := char.EquipItem(eq.ID); err != nil {
+			return nil, err
+		}
+	}
+	return combat.NewCharacterCombatant(char), nil
+}
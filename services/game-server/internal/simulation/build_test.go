@@ -0,0 +1,63 @@
+package simulation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+func TestParseBuild(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected Build
+	}{
+		{"Engineer:5", Build{Class: character.Engineer, Level: 5, Equipment: []string{}}},
+		{"SteamMage:3+steam_pistol+steam_vest", Build{Class: character.SteamMage, Level: 3, Equipment: []string{"steam_pistol", "steam_vest"}}},
+		{"mob:mechanical:7", Build{MobType: mob.Mechanical, Level: 7}},
+	}
+	for _, tt := range tests {
+		build, err := ParseBuild(tt.spec)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %s: %v", tt.spec, err)
+			continue
+		}
+		if build.String() != tt.expected.String() {
+			t.Errorf("Expected %s, got %s", tt.expected, build)
+		}
+		if build.String() != tt.spec {
+			t.Errorf("Expected %s to print as itself, got %s", tt.spec, build)
+		}
+	}
+
+	for _, spec := range []string{"Bard:5", "Engineer:0", "Engineer", "mob:dragon:5", "mob:mechanical:x"} {
+		if _, err := ParseBuild(spec); err == nil {
+			t.Errorf("Expected %s to be rejected", spec)
+		}
+	}
+}
+
+func TestBuildCombatant(t *testing.T) {
+	build, _ := ParseBuild("ClockworkKnight:3+steam_vest+brass_gauntlets")
+	if _, err := build.combatant("build", nil); err == nil {
+		t.Error("Expected unknown equipment to be rejected")
+	}
+
+	equipment, err := LoadEquipment(strings.NewReader(`[{"ID": "brass_gauntlets", "Name": "Brass Gauntlets", "Slot": "hands", "Stats": {"Strength": 4}}]`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := build.combatant("build", equipment)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p := c.Participant()
+	if p.Level != 3 {
+		t.Errorf("Expected level 3, got %d", p.Level)
+	}
+	// 10 base, 5 class bonus, 2 per level and 4 from the gauntlets
+	if p.Attributes.Strength.Value != 23 {
+		t.Errorf("Expected strength 23, got %d", p.Attributes.Strength.Value)
+	}
+}
@@ -0,0 +1,72 @@
+package simulation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteTable writes the results as an aligned text table
+func WriteTable(w io.Writer, results []*Result) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Build\tOpponent\tBattles\tWin %\tLoss %\tDraw %\tTurns to kill\tRounds\tDamage/steam\tAbility usage")
+	for _, r := range results {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.2f\t%s\n",
+			r.Build, r.Opponent, r.Battles, r.WinRate(), percent(r.Losses, r.Battles), percent(r.Draws, r.Battles),
+			r.TurnsToKill(), r.AverageRounds(), r.DamagePerSteam(), formatUsage(r.AbilityUses, " ", ", "))
+	}
+	return table.Flush()
+}
+
+// WriteCSV writes the results as CSV with a header row
+func WriteCSV(w io.Writer, results []*Result) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"build", "opponent", "battles", "wins", "losses", "draws", "win_rate", "turns_to_kill", "rounds", "damage_per_steam", "ability_usage"})
+	for _, r := range results {
+		out.Write([]string{
+			r.Build.String(),
+			r.Opponent.String(),
+			strconv.Itoa(r.Battles),
+			strconv.Itoa(r.Wins),
+			strconv.Itoa(r.Losses),
+			strconv.Itoa(r.Draws),
+			strconv.FormatFloat(r.WinRate(), 'f', 2, 64),
+			strconv.FormatFloat(r.TurnsToKill(), 'f', 2, 64),
+			strconv.FormatFloat(r.AverageRounds(), 'f', 2, 64),
+			strconv.FormatFloat(r.DamagePerSteam(), 'f', 3, 64),
+			formatUsage(r.AbilityUses, ":", ";"),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// formatUsage lists each ability's share of all ability uses, most used
+// first, e.g. "Steam Blast 75%, Repair 25%"
+func formatUsage(uses map[string]int, sep, join string) string {
+	if len(uses) == 0 {
+		return "-"
+	}
+	total := 0
+	names := make([]string, 0, len(uses))
+	for name, count := range uses {
+		names = append(names, name)
+		total += count
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if uses[names[i]] != uses[names[j]] {
+			return uses[names[i]] > uses[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	shares := make([]string, len(names))
+	for i, name := range names {
+		shares[i] = fmt.Sprintf("%s%s%.0f%%", name, sep, percent(uses[name], total))
+	}
+	return strings.Join(shares, join)
+}
@@ -0,0 +1,271 @@
+// Package simulation fights battles between builds in-process, without the
+// game server or Redis, to measure how classes, levels and equipment compare.
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/redfoxius/roleplay/services/game-server/internal/character"
+	"github.com/redfoxius/roleplay/services/game-server/internal/combat"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
+)
+
+// Participant IDs and teams of the two sides of a simulated battle
+const (
+	buildID    = "build"
+	opponentID = "opponent"
+)
+
+// Options configure a simulation
+type Options struct {
+	Battles   int    // battles per matchup
+	Seed      int64  // seed of the first battle; every next battle adds one
+	Terrain   string // world terrain the battle grid is laid out for
+	MaxRounds int    // rounds after which a battle is a draw
+	Equipment map[string]*character.Equipment
+}
+
+// DefaultOptions returns the options the simulator uses unless told otherwise
+func DefaultOptions() Options {
+	return Options{
+		Battles:   1000,
+		Seed:      1,
+		Terrain:   "plains",
+		MaxRounds: 50,
+	}
+}
+
+// Result summarizes the battles of a build against an opponent, from the
+// build's side
+type Result struct {
+	Build        Build
+	Opponent     Build
+	Battles      int
+	Wins         int
+	Losses       int
+	Draws        int
+	Rounds       int            // rounds fought in all battles
+	WinningTurns int            // turns the build took in the battles it won
+	DamageDealt  int            // damage the build dealt in all battles
+	SteamUsed    int            // steam power the build spent in all battles
+	AbilityUses  map[string]int // how often the build used each ability
+}
+
+// WinRate returns the share of battles the build won, in percent
+func (r *Result) WinRate() float64 {
+	return percent(r.Wins, r.Battles)
+}
+
+// TurnsToKill returns the average number of turns the build needed to defeat
+// the opponent in the battles it won
+func (r *Result) TurnsToKill() float64 {
+	if r.Wins == 0 {
+		return 0
+	}
+	return float64(r.WinningTurns) / float64(r.Wins)
+}
+
+// AverageRounds returns the average length of a battle in rounds
+func (r *Result) AverageRounds() float64 {
+	if r.Battles == 0 {
+		return 0
+	}
+	return float64(r.Rounds) / float64(r.Battles)
+}
+
+// DamagePerSteam returns the damage the build dealt per point of steam power
+func (r *Result) DamagePerSteam() float64 {
+	if r.SteamUsed == 0 {
+		return 0
+	}
+	return float64(r.DamageDealt) / float64(r.SteamUsed)
+}
+
+// percent returns part as a percentage of total
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// Run fights every build against every opponent
+func Run(builds, opponents []Build, opts Options) ([]*Result, error) {
+	var results []*Result
+	for _, build := range builds {
+		for _, opponent := range opponents {
+			result, err := Simulate(build, opponent, opts)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// Simulate fights opts.Battles battles of a build against an opponent. Battle
+// i rolls from seed opts.Seed+i, so the same options give the same results.
+func Simulate(build, opponent Build, opts Options) (*Result, error) {
+	result := &Result{
+		Build:       build,
+		Opponent:    opponent,
+		AbilityUses: make(map[string]int),
+	}
+	for i := 0; i < opts.Battles; i++ {
+		if err := fight(build, opponent, opts.Seed+int64(i), opts, result); err != nil {
+			return nil, fmt.Errorf("%s against %s: %v", build, opponent, err)
+		}
+	}
+	return result, nil
+}
+
+// fight plays out one battle and adds it to the result
+func fight(build, opponent Build, seed int64, opts Options, result *Result) error {
+	battleType := combat.BattleTypePvP
+	if build.MobType != "" || opponent.MobType != "" {
+		battleType = combat.BattleTypePvE
+	}
+	battle := combat.NewBattle(battleType)
+	battle.SetSeed(seed)
+	battle.SetTerrain(opts.Terrain)
+
+	ais := make(map[string]*mob.AIBehavior)
+	for _, side := range []struct {
+		id    string
+		build Build
+	}{{buildID, build}, {opponentID, opponent}} {
+		c, err := side.build.combatant(side.id, opts.Equipment)
+		if err != nil {
+			return err
+		}
+		battle.AddCombatant(c)
+		// Sides are teams, so that even two mobs fight each other
+		battle.AddToTeam(side.id, side.id)
+		if side.build.MobType != "" {
+			ais[side.id] = mob.NewAIBehavior(side.build.MobType)
+		}
+	}
+
+	turns := 0
+	for !battle.IsOver() && battle.Round <= opts.MaxRounds {
+		actor := battle.ActiveParticipant()
+		round, turn := battle.Round, battle.CurrentTurn
+		if actor.ID == buildID {
+			turns++
+		}
+		if ai, isMob := ais[actor.ID]; isMob {
+			// Mobs take their turn the way they do on the game server
+			battle.MobTurn(ai, mobActions{battle: battle})
+		} else {
+			characterTurn(battle, actor)
+		}
+		// Whatever went wrong, the turn has to end
+		if !battle.IsOver() && battle.Round == round && battle.CurrentTurn == turn {
+			battle.SkipTurn()
+		}
+	}
+
+	result.Battles++
+	result.Rounds += min(battle.Round, opts.MaxRounds)
+	switch {
+	case !battle.IsOver() || len(battle.Winners) == 0:
+		result.Draws++
+	case battle.Winners[0] == buildID:
+		result.Wins++
+		result.WinningTurns += turns
+	default:
+		result.Losses++
+	}
+	if stats := battle.Stats[buildID]; stats != nil {
+		result.DamageDealt += stats.TotalDamageDealt
+		result.SteamUsed += stats.TotalSteamPowerUsed
+		for name, uses := range stats.AbilitiesUsed {
+			result.AbilityUses[name] += uses
+		}
+	}
+	return nil
+}
+
+// characterTurn plays a character's turn the way a straightforward player
+// would: heal up when badly hurt, otherwise use the hardest hitting ability
+// that reaches the nearest opponent, closing in first when none does
+func characterTurn(battle *combat.Battle, actor *combat.Participant) {
+	var target *combat.Participant
+	for _, p := range battle.Opponents(actor.ID) {
+		if target == nil || battle.Distance(actor.ID, p.ID) < battle.Distance(actor.ID, target.ID) {
+			target = p
+		}
+	}
+	if target == nil {
+		return
+	}
+
+	if actor.Health*2 < actor.MaxHealth {
+		if heal := bestAbility(actor, 0, func(a *common.Ability) int { return a.Healing }); heal != nil {
+			battle.ExecuteAction(heal, actor.ID)
+			return
+		}
+	}
+
+	damage := func(a *common.Ability) int { return a.Damage }
+	attack := bestAbility(actor, battle.Distance(actor.ID, target.ID), damage)
+	if attack == nil {
+		reach := 1
+		for i := range actor.Abilities {
+			if usable(actor, &actor.Abilities[i]) && actor.Abilities[i].Damage > 0 {
+				reach = max(reach, actor.Abilities[i].Reach())
+			}
+		}
+		battle.Approach(target.ID, reach)
+		attack = bestAbility(actor, battle.Distance(actor.ID, target.ID), damage)
+	}
+	if attack != nil {
+		battle.ExecuteAction(attack, target.ID)
+	}
+}
+
+// bestAbility returns the usable ability that reaches a distance with the
+// highest value, or nil if none has any
+func bestAbility(actor *combat.Participant, distance int, value func(*common.Ability) int) *common.Ability {
+	var best *common.Ability
+	for i := range actor.Abilities {
+		ability := &actor.Abilities[i]
+		if !usable(actor, ability) || value(ability) <= 0 || distance > ability.Reach() {
+			continue
+		}
+		if best == nil || value(ability) > value(best) {
+			best = ability
+		}
+	}
+	return best
+}
+
+// usable reports whether a participant has the steam power for an ability
+// that is not cooling down and is used on a turn rather than as a reaction
+func usable(actor *combat.Participant, ability *common.Ability) bool {
+	return actor.Cooldowns[ability.Name] == 0 && actor.SteamPower >= ability.SteamCost && ability.Reaction == ""
+}
+
+// mobActions lets a mob's AI act on a simulated battle directly
+type mobActions struct {
+	battle *combat.Battle
+}
+
+// Move performs a move for the mob
+func (a mobActions) Move(move func() error) error {
+	return move()
+}
+
+// Act uses an ability of the mob
+func (a mobActions) Act(ability *common.Ability, targetID string) error {
+	_, err := a.battle.ExecuteAction(ability, targetID)
+	return err
+}
+
+// Flee lets the mob try to flee
+func (a mobActions) Flee() error {
+	_, err := a.battle.Flee()
+	return err
+}
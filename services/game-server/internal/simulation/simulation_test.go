@@ -0,0 +1,83 @@
+package simulation
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Battles = 20
+	build, _ := ParseBuild("Engineer:5")
+	opponent, _ := ParseBuild("mob:mechanical:1")
+
+	result, err := Simulate(build, opponent, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Battles != 20 || result.Wins+result.Losses+result.Draws != 20 {
+		t.Errorf("Expected 20 battles to be counted, got %+v", result)
+	}
+	if result.WinRate() < 90 {
+		t.Errorf("Expected a level 5 engineer to beat a level 1 mob, got a %.1f%% win rate", result.WinRate())
+	}
+	if result.TurnsToKill() <= 0 || result.DamagePerSteam() <= 0 || result.AbilityUses["Steam Blast"] == 0 {
+		t.Errorf("Expected turns to kill, damage per steam and ability use, got %+v", result)
+	}
+
+	again, _ := Simulate(build, opponent, opts)
+	if !reflect.DeepEqual(result, again) {
+		t.Errorf("Expected the same seed to give the same results, got %+v and %+v", result, again)
+	}
+}
+
+func TestMirrorMatch(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Battles = 5
+	builds, _ := ParseBuilds("mob:biological:3")
+
+	results, err := Run(builds, builds, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Battles != 5 {
+		t.Fatalf("Expected one matchup of 5 battles, got %+v", results)
+	}
+	if results[0].Rounds == 0 {
+		t.Error("Expected mobs to fight each other")
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	results := []*Result{{
+		Build:        Build{Class: "Engineer", Level: 5},
+		Opponent:     Build{MobType: "mechanical", Level: 5},
+		Battles:      4,
+		Wins:         3,
+		Losses:       1,
+		Rounds:       20,
+		WinningTurns: 12,
+		DamageDealt:  300,
+		SteamUsed:    150,
+		AbilityUses:  map[string]int{"Steam Blast": 3, "Repair": 1},
+	}}
+
+	var csv bytes.Buffer
+	if err := WriteCSV(&csv, results); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "Engineer:5,mob:mechanical:5,4,3,1,0,75.00,4.00,5.00,2.000,Steam Blast:75%;Repair:25%"
+	if lines := strings.Split(strings.TrimSpace(csv.String()), "\n"); len(lines) != 2 || lines[1] != expected {
+		t.Errorf("Expected CSV row %q, got:\n%s", expected, csv.String())
+	}
+
+	var table bytes.Buffer
+	if err := WriteTable(&table, results); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(table.String(), "Steam Blast 75%, Repair 25%") {
+		t.Errorf("Expected ability usage in the table, got:\n%s", table.String())
+	}
+}
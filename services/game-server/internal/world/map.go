@@ -5,7 +5,7 @@ import (
 	"math/rand"
 	"time"
 
-	"roleplay/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
 )
 
 // Location represents a point of interest in the world
@@ -3,7 +3,7 @@ package world
 import (
 	"testing"
 
-	"roleplay/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
 )
 
 func TestWorldMapGeneration(t *testing.T) {
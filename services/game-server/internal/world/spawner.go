@@ -5,8 +5,8 @@ import (
 	"math/rand"
 	"time"
 
-	"roleplay/internal/common"
-	"roleplay/internal/mob"
+	"github.com/redfoxius/roleplay/services/game-server/internal/common"
+	"github.com/redfoxius/roleplay/services/game-server/internal/mob"
 )
 
 // WorldSpawner manages mob spawning in the world
@@ -69,7 +69,11 @@ func (ws *WorldSpawner) InitializeSpawnPoints(wm *WorldMap) {
 
 // UpdateSpawner updates the spawner state
 func (ws *WorldSpawner) UpdateSpawner() {
-	now := time.Now()
+	ws.UpdateSpawnerAt(time.Now())
+}
+
+// UpdateSpawnerAt updates the spawner state as of the given time
+func (ws *WorldSpawner) UpdateSpawnerAt(now time.Time) {
 	for _, sp := range ws.spawnPoints {
 		// Check if it's time to spawn new mobs
 		if now.Sub(sp.LastSpawn).Seconds() >= float64(sp.RespawnTime) {
@@ -122,6 +126,7 @@ func (ws *WorldSpawner) SpawnMob(sp *SpawnPoint) (*mob.Mob, error) {
 	// Set mob location
 	newMob.Location.X = spawnX
 	newMob.Location.Y = spawnY
+	newMob.MoveTo(spawnX, spawnY)
 
 	return newMob, nil
 }